@@ -0,0 +1,237 @@
+// keyctl управляет кольцом ключей подписи JWT (манифест JWT_KEYRING_FILE).
+//
+// Ротация ключа без разлогина пользователей:
+//
+//	keyctl generate -alg ES256   # новый ключ публикуется в JWKS, но ещё не подписывает
+//	                             # перезапустить инстансы, чтобы все знали новый ключ
+//	keyctl promote -kid <kid>    # новый ключ подписывает, старый остаётся для проверки
+//	                             # перезапустить инстансы
+//	keyctl retire                # когда истекут подписанные ими токены (JWT_LIFETIME,
+//	                             # но не меньше суток — столько живёт ссылка подтверждения почты),
+//	                             # удалить старые ключи
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"jwt_auth_project/internal/config"
+	"jwt_auth_project/internal/logger"
+)
+
+func main() {
+	_ = godotenv.Load()
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	manifestPath := fs.String("manifest", os.Getenv("JWT_KEYRING_FILE"), "path to keyring manifest")
+	alg := fs.String("alg", config.AlgES256, "signing algorithm: HS256, RS256, ES256 or EdDSA")
+	kid := fs.String("kid", "", "key id")
+	force := fs.Bool("force", false, "retire previous keys even if their tokens may still be valid")
+	_ = fs.Parse(os.Args[2:])
+
+	if *manifestPath == "" {
+		logger.Fatal("manifest path is required: set -manifest or JWT_KEYRING_FILE")
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "init":
+		err = initRing(*manifestPath, *alg, *kid)
+	case "generate":
+		err = generate(*manifestPath, *alg, *kid)
+	case "promote":
+		err = promote(*manifestPath, *kid)
+	case "retire":
+		err = retire(*manifestPath, *force)
+	case "list":
+		err = list(*manifestPath)
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		logger.Fatal("keyctl failed", "command", os.Args[1], "error", err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: keyctl <init|generate|promote|retire|list> [-manifest path] [-alg alg] [-kid kid] [-force]")
+}
+
+// initRing создаёт манифест с единственным текущим ключом
+func initRing(path, alg, kid string) error {
+	if _, err := os.Stat(path); err == nil {
+		return errors.New("manifest already exists")
+	}
+	m := &config.KeyRingManifest{}
+	key, err := newKey(path, alg, kid)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	key.State = config.KeyStateCurrent
+	key.PromotedAt = &now
+	m.Keys = append(m.Keys, key)
+	if err := config.WriteKeyRingManifest(path, m); err != nil {
+		return err
+	}
+	fmt.Printf("created keyring with current key %s (%s)\n", key.ID, key.Algorithm)
+	return nil
+}
+
+// generate добавляет новый ключ в состоянии pending
+func generate(path, alg, kid string) error {
+	m, err := config.ReadKeyRingManifest(path)
+	if err != nil {
+		return err
+	}
+	for _, k := range m.Keys {
+		if k.ID == kid {
+			return fmt.Errorf("key %q already exists", kid)
+		}
+	}
+	key, err := newKey(path, alg, kid)
+	if err != nil {
+		return err
+	}
+	m.Keys = append(m.Keys, key)
+	if err := config.WriteKeyRingManifest(path, m); err != nil {
+		return err
+	}
+	fmt.Printf("generated pending key %s (%s); restart instances before promoting it\n", key.ID, key.Algorithm)
+	return nil
+}
+
+// promote делает ключ kid текущим, прежний текущий ключ переходит в previous
+func promote(path, kid string) error {
+	if kid == "" {
+		return errors.New("-kid is required")
+	}
+	m, err := config.ReadKeyRingManifest(path)
+	if err != nil {
+		return err
+	}
+	idx := -1
+	for i, k := range m.Keys {
+		if k.ID == kid {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return fmt.Errorf("key %q not found", kid)
+	}
+	if m.Keys[idx].State == config.KeyStateCurrent {
+		return fmt.Errorf("key %q is already current", kid)
+	}
+
+	now := time.Now().UTC()
+	for i := range m.Keys {
+		if m.Keys[i].State == config.KeyStateCurrent {
+			m.Keys[i].State = config.KeyStatePrevious
+			m.Keys[i].DemotedAt = &now
+		}
+	}
+	m.Keys[idx].State = config.KeyStateCurrent
+	m.Keys[idx].PromotedAt = &now
+	m.Keys[idx].DemotedAt = nil
+
+	if err := config.WriteKeyRingManifest(path, m); err != nil {
+		return err
+	}
+	fmt.Printf("promoted key %s; restart instances to start signing with it\n", kid)
+	return nil
+}
+
+// retire удаляет предыдущие ключи, все токены которых уже истекли
+func retire(path string, force bool) error {
+	maxLifetime, err := config.SignedTokenMaxLifetime()
+	if err != nil {
+		return err
+	}
+	m, err := config.ReadKeyRingManifest(path)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	kept := m.Keys[:0]
+	var retired []config.ManifestKey
+	for _, k := range m.Keys {
+		if k.Retirable(now, maxLifetime) || (force && k.State == config.KeyStatePrevious) {
+			retired = append(retired, k)
+			continue
+		}
+		kept = append(kept, k)
+	}
+	m.Keys = kept
+	if err := config.WriteKeyRingManifest(path, m); err != nil {
+		return err
+	}
+
+	for _, k := range retired {
+		if err := os.Remove(keyPath(path, k.File)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		fmt.Printf("retired key %s\n", k.ID)
+	}
+	if len(retired) == 0 {
+		fmt.Println("nothing to retire")
+	}
+	return nil
+}
+
+func list(path string) error {
+	m, err := config.ReadKeyRingManifest(path)
+	if err != nil {
+		return err
+	}
+	for _, k := range m.Keys {
+		fmt.Printf("%-24s %-6s %-9s created %s\n", k.ID, k.Algorithm, k.State, k.CreatedAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// newKey генерирует файл ключа рядом с манифестом и возвращает запись для манифеста
+func newKey(manifestPath, alg, kid string) (config.ManifestKey, error) {
+	if kid == "" {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return config.ManifestKey{}, err
+		}
+		kid = hex.EncodeToString(buf)
+	}
+	file := kid + ".pem"
+	if alg == config.AlgHS256 {
+		file = kid + ".secret"
+	}
+	if err := config.GenerateKeyFile(alg, keyPath(manifestPath, file)); err != nil {
+		return config.ManifestKey{}, err
+	}
+	return config.ManifestKey{
+		ID:        kid,
+		Algorithm: alg,
+		File:      file,
+		State:     config.KeyStatePending,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+func keyPath(manifestPath, file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(filepath.Dir(manifestPath), file)
+}
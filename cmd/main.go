@@ -14,8 +14,13 @@ import (
 	"jwt_auth_project/internal/delivery"
 	middleware "jwt_auth_project/internal/delivery/middleware"
 	"jwt_auth_project/internal/logger"
+	"jwt_auth_project/internal/mailer"
+	"jwt_auth_project/internal/oidc"
+	"jwt_auth_project/internal/password"
 	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/throttle"
 	"jwt_auth_project/internal/usecase"
+	"jwt_auth_project/internal/webauthn"
 )
 
 func main() {
@@ -37,10 +42,72 @@ func main() {
 	defer pool.Close()
 
 	userRepo := repo.NewUserRepo(pool)
-	userUC := usecase.NewUserUsecase(userRepo, conf.JWT.Secret, conf.JWT.Lifetime)
+	refreshRepo := repo.NewRefreshTokenRepo(pool)
+	sessionRepo := repo.NewCachedSessionRepo(repo.NewSessionRepo(pool), conf.JWT.RevocationCacheTTL)
+	revocationRepo := repo.NewCachedRevocationRepo(repo.NewRevocationRepo(pool), conf.JWT.RevocationCacheTTL)
+	roleRepo := repo.NewRoleRepo(pool)
+	roleUC := usecase.NewRoleUsecase(roleRepo, revocationRepo)
+	oneTimeRepo := repo.NewOneTimeTokenRepo(pool)
+	mfaRepo := repo.NewMFARepo(pool)
+	webauthnRepo := repo.NewWebAuthnRepo(pool)
+	oauthRepo := repo.NewOAuthRepo(pool)
+	identityRepo := repo.NewIdentityRepo(pool)
+	rp := webauthn.NewRelyingParty(conf.WebAuthn.RPID, conf.WebAuthn.RPName, conf.WebAuthn.Origins)
+
+	policy, err := password.NewPolicy(conf.Password)
+	if err != nil {
+		logger.Fatal("init password policy failed", err)
+	}
+	if policy.Breached != nil {
+		slog.Info("breached password list loaded", "hashes", policy.Breached.Len())
+	}
+
+	var attempts throttle.Store = throttle.NewMemoryStore()
+	if conf.Throttle.Store == config.ThrottleStorePostgres {
+		attempts = repo.NewThrottleRepo(pool)
+	}
+
+	mail, err := mailer.New(conf.Mail)
+	if err != nil {
+		logger.Fatal("init mailer failed", err)
+	}
+
+	// Callback провайдера — адрес этого API; он же регистрируется у провайдера как redirect URI
+	providers := make(map[string]*oidc.Provider, len(conf.Social.Providers))
+	for _, p := range conf.Social.Providers {
+		providers[p.Name] = oidc.NewProvider(p, conf.OIDC.Issuer+"/login/social/"+p.Name+"/callback")
+	}
+
+	userUC := usecase.NewUserUsecase(
+		userRepo,
+		refreshRepo,
+		sessionRepo,
+		revocationRepo,
+		roleRepo,
+		oneTimeRepo,
+		mfaRepo,
+		webauthnRepo,
+		oauthRepo,
+		identityRepo,
+		providers,
+		rp,
+		attempts,
+		password.NewHasher(conf.Password),
+		policy,
+		mail,
+		conf.JWT,
+		conf.BaseURL,
+		conf.OIDC.Issuer,
+	)
+
+	if !conf.JWT.Keys.Current.Asymmetric() {
+		slog.Warn("OpenID Connect disabled: id tokens need an asymmetric JWT_ALGORITHM", "algorithm", conf.JWT.Keys.Current.Algorithm)
+	}
+
+	apiKeyUC := usecase.NewAPIKeyUsecase(repo.NewAPIKeyRepo(pool), userRepo, roleUC)
 
 	adsRepo := repo.NewAdsRepo(pool)
-	adsUC := usecase.NewAdsUsecase(adsRepo, s3Client, s3Cfg.Bucket, 5<<20) // макс 5MiB, например
+	adsUC := usecase.NewAdsUsecase(adsRepo, userRepo, s3Client, s3Cfg.Bucket, 5<<20) // макс 5MiB, например
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -49,13 +116,21 @@ func main() {
 	}
 
 	router := mux.NewRouter()
+	router.Use(middleware.ClientInfo(conf.Throttle.TrustProxyHeaders))
+	router.Use(middleware.CSRF(conf.CSRF, conf.JWT.TokenSources))
+	guard := middleware.NewGuard(userUC, roleUC, apiKeyUC, conf.JWT.TokenSources)
 
-	userHandler := delivery.NewHandler(userUC)
-	userHandler.RegisterRoutes(router)
+	userHandler := delivery.NewHandler(userUC, conf.BaseURL)
+	userHandler.RegisterRoutes(router, guard)
+
+	apiKeyHandler := delivery.NewAPIKeyHandler(apiKeyUC)
+	apiKeyHandler.RegisterRoutes(router, guard)
 
 	adsHandler := delivery.NewAdsHandler(adsUC)
-	router.Use(middleware.AuthMiddleware(userUC))
-	adsHandler.RegisterRoutes(router)
+	adsHandler.RegisterRoutes(router, guard)
+
+	adminHandler := delivery.NewAdminHandler(roleUC)
+	adminHandler.RegisterRoutes(router, guard)
 
 	slog.Info("listening on", "port", conf.Port)
 	if err := http.ListenAndServe(conf.Port, router); err != nil {
@@ -0,0 +1,117 @@
+// rolectl выдаёт и снимает роли пользователей напрямую через базу.
+//
+// Нужен прежде всего для первого администратора: через /admin роли раздаёт только тот,
+// у кого уже есть право users:manage.
+//
+//	rolectl grant -email admin@example.com -role admin
+//	rolectl revoke -email admin@example.com -role admin
+//	rolectl list -email admin@example.com
+//
+// Смена ролей увеличивает поколение токенов пользователя, как и через /admin
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"jwt_auth_project/internal/config"
+	"jwt_auth_project/internal/db"
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/logger"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/usecase"
+)
+
+func main() {
+	_ = godotenv.Load()
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	email := fs.String("email", "", "user email")
+	role := fs.String("role", domain.RoleAdmin, "role name")
+	_ = fs.Parse(os.Args[2:])
+
+	if *email == "" {
+		logger.Fatal("-email is required")
+	}
+
+	pgCfg, err := config.GetPostgresConfig()
+	if err != nil {
+		logger.Fatal("load postgres config failed", "error", err)
+	}
+	pool, err := db.InitPostgres(pgCfg, slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	if err != nil {
+		logger.Fatal("init postgres failed", "error", err)
+	}
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	user, err := repo.NewUserRepo(pool).GetUserByEmail(ctx, strings.TrimSpace(*email))
+	if err != nil {
+		logger.Fatal("find user failed", "email", *email, "error", err)
+	}
+	roleUC := usecase.NewRoleUsecase(repo.NewRoleRepo(pool), repo.NewRevocationRepo(pool))
+
+	switch os.Args[1] {
+	case "grant":
+		err = changeRoles(ctx, roleUC, user, func(roles []string) []string {
+			return append(roles, *role)
+		})
+	case "revoke":
+		err = changeRoles(ctx, roleUC, user, func(roles []string) []string {
+			return slices.DeleteFunc(roles, func(r string) bool { return r == *role })
+		})
+	case "list":
+		err = list(ctx, roleUC, user)
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		logger.Fatal("rolectl failed", "command", os.Args[1], "error", err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: rolectl <grant|revoke|list> -email email [-role role]")
+}
+
+// changeRoles применяет change к текущим ролям пользователя и сохраняет результат
+func changeRoles(ctx context.Context, roleUC usecase.RoleUseCase, user *domain.User, change func([]string) []string) error {
+	roles, err := roleUC.GetUserRoles(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	roles = change(roles)
+	if len(roles) == 0 {
+		return errors.New("user must keep at least one role")
+	}
+	if err := roleUC.SetUserRoles(ctx, user.ID, domain.SetUserRolesPayload{Roles: roles}); err != nil {
+		return err
+	}
+	return list(ctx, roleUC, user)
+}
+
+func list(ctx context.Context, roleUC usecase.RoleUseCase, user *domain.User) error {
+	roles, err := roleUC.GetUserRoles(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s (%s): %s\n", user.Email, user.ID, strings.Join(roles, ", "))
+	return nil
+}
@@ -16,7 +16,16 @@ type Config struct {
 	Port           string
 	PostgresConfig PostgresConfig
 	//RedisConfig    sessionRepository.RedisConfig
-	JWT JWTConfig
+	JWT  JWTConfig
+	Mail MailConfig
+	// BaseURL — адрес фронтенда, на который ведут ссылки из писем
+	BaseURL  string
+	WebAuthn WebAuthnConfig
+	OIDC     OIDCConfig
+	Social   SocialConfig
+	CSRF     CSRFConfig
+	Throttle ThrottleConfig
+	Password PasswordConfig
 }
 
 func LoadConfig() (*Config, error) {
@@ -48,5 +57,53 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("load jwt config: %w", err)
 	}
 
+	cfg.Mail, err = LoadMail()
+	if err != nil {
+		return nil, fmt.Errorf("load mail config: %w", err)
+	}
+
+	cfg.BaseURL = os.Getenv("APP_BASE_URL")
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "http://localhost" + cfg.Port
+	}
+
+	cfg.WebAuthn, err = LoadWebAuthn(cfg.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("load webauthn config: %w", err)
+	}
+
+	cfg.OIDC, err = LoadOIDC(cfg.Port)
+	if err != nil {
+		return nil, fmt.Errorf("load oidc config: %w", err)
+	}
+	// По умолчанию access-токены выпускает и принимает сам сервис под своим публичным адресом —
+	// адресом API из OIDC_ISSUER, а не фронтенда из APP_BASE_URL
+	if cfg.JWT.Issuer == "" {
+		cfg.JWT.Issuer = cfg.OIDC.Issuer
+	}
+	if cfg.JWT.Audience == "" {
+		cfg.JWT.Audience = cfg.JWT.Issuer
+	}
+
+	cfg.Social, err = LoadSocial()
+	if err != nil {
+		return nil, fmt.Errorf("load social login config: %w", err)
+	}
+
+	cfg.CSRF, err = LoadCSRF(cfg.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("load csrf config: %w", err)
+	}
+
+	cfg.Throttle, err = LoadThrottle()
+	if err != nil {
+		return nil, fmt.Errorf("load throttle config: %w", err)
+	}
+
+	cfg.Password, err = LoadPassword()
+	if err != nil {
+		return nil, fmt.Errorf("load password config: %w", err)
+	}
+
 	return cfg, nil
 }
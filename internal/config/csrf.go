@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+type CSRFConfig struct {
+	// AllowedOrigins — origin'ы фронтендов (scheme://host[:port]), с которых принимаются
+	// изменяющие запросы, аутентифицированные cookie
+	AllowedOrigins []string
+}
+
+// LoadCSRF читает CSRF_ALLOWED_ORIGINS — список origin'ов через запятую.
+// По умолчанию разрешён только origin baseURL
+func LoadCSRF(baseURL string) (CSRFConfig, error) {
+	raw := os.Getenv("CSRF_ALLOWED_ORIGINS")
+	if raw == "" {
+		raw = baseURL
+	}
+
+	var cfg CSRFConfig
+	for _, value := range strings.Split(raw, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		parsed, err := url.Parse(value)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return CSRFConfig{}, fmt.Errorf("invalid csrf origin %q: must be an absolute url", value)
+		}
+		if strings.Trim(parsed.Path, "/") != "" || parsed.RawQuery != "" || parsed.Fragment != "" {
+			return CSRFConfig{}, fmt.Errorf("invalid csrf origin %q: must not contain path, query or fragment", value)
+		}
+		cfg.AllowedOrigins = append(cfg.AllowedOrigins, strings.ToLower(parsed.Scheme+"://"+parsed.Host))
+	}
+	if len(cfg.AllowedOrigins) == 0 {
+		return CSRFConfig{}, fmt.Errorf("CSRF_ALLOWED_ORIGINS must contain at least one origin")
+	}
+	return cfg, nil
+}
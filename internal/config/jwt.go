@@ -4,32 +4,186 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// Источники access-токена в запросе
+const (
+	TokenSourceCookie = "cookie" // cookie "jwt"
+	TokenSourceHeader = "header" // Authorization: Bearer <token>
+)
+
 type JWTConfig struct {
-	Secret   string
-	Lifetime time.Duration
+	Keys            KeyRing
+	Lifetime        time.Duration
+	RefreshLifetime time.Duration
+	// RevocationCacheTTL — сколько инстанс доверяет закешированным ответам хранилища отзывов
+	RevocationCacheTTL time.Duration
+	// TokenSources — откуда AuthMiddleware берёт access-токен, в порядке приоритета
+	TokenSources []string
+	// Issuer (iss) и Audience (aud) выпускаемых токенов. Токен с другими значениями не принимается,
+	// даже если подписан тем же ключом, — так токен одного окружения не пройдёт в другом
+	Issuer   string
+	Audience string
+	// Leeway — допустимое расхождение часов при проверке exp, nbf и iat
+	Leeway time.Duration
 }
 
 func LoadJWT() (JWTConfig, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return JWTConfig{}, fmt.Errorf("JWT_SECRET is required")
+	lifetime, err := AccessTokenLifetime()
+	if err != nil {
+		return JWTConfig{}, err
 	}
 
-	// Время жизни в секундах, по умолчанию 3600s
-	raw := os.Getenv("JWT_LIFETIME")
-	if raw == "" {
-		raw = "3600"
+	maxLifetime, err := SignedTokenMaxLifetime()
+	if err != nil {
+		return JWTConfig{}, err
 	}
-	secs, err := strconv.Atoi(raw)
+	keys, err := loadKeyRing(maxLifetime)
+	if err != nil {
+		return JWTConfig{}, err
+	}
+
+	// Время жизни refresh-токена в секундах, по умолчанию 30 дней
+	refreshLifetime, err := secondsFromEnv("JWT_REFRESH_LIFETIME", 30*24*3600)
 	if err != nil {
-		return JWTConfig{}, fmt.Errorf("invalid JWT_LIFETIME: %w", err)
+		return JWTConfig{}, err
 	}
 
+	revocationCacheTTL, err := secondsFromEnv("JWT_REVOCATION_CACHE_TTL", 30)
+	if err != nil {
+		return JWTConfig{}, err
+	}
+
+	tokenSources, err := loadTokenSources()
+	if err != nil {
+		return JWTConfig{}, err
+	}
+
+	leeway, err := secondsFromEnv("JWT_LEEWAY", 30)
+	if err != nil {
+		return JWTConfig{}, err
+	}
+	if leeway < 0 || leeway > maxLeeway {
+		return JWTConfig{}, fmt.Errorf("JWT_LEEWAY must be between 0 and %d seconds", int(maxLeeway.Seconds()))
+	}
+
+	// Пустые JWT_ISSUER и JWT_AUDIENCE заполняются в LoadConfig публичным адресом API (OIDC_ISSUER)
 	return JWTConfig{
-		Secret:   secret,
-		Lifetime: time.Duration(secs) * time.Second,
+		Keys:               keys,
+		Lifetime:           lifetime,
+		RefreshLifetime:    refreshLifetime,
+		RevocationCacheTTL: revocationCacheTTL,
+		TokenSources:       tokenSources,
+		Issuer:             os.Getenv("JWT_ISSUER"),
+		Audience:           os.Getenv("JWT_AUDIENCE"),
+		Leeway:             leeway,
 	}, nil
 }
+
+// maxLeeway ограничивает JWT_LEEWAY: большой запас продлевает жизнь истёкшим токенам
+const maxLeeway = 5 * time.Minute
+
+// AccessTokenLifetime читает время жизни access-токена в секундах, по умолчанию 900s.
+// Токен короткоживущий, продлевается через /token/refresh
+func AccessTokenLifetime() (time.Duration, error) {
+	return secondsFromEnv("JWT_LIFETIME", 900)
+}
+
+// PurposeTokenMaxLifetime — наибольший срок жизни одноразовых JWT (ссылка подтверждения почты).
+// Они подписываются тем же кольцом ключей, что и access-токены
+const PurposeTokenMaxLifetime = 24 * time.Hour
+
+// SignedTokenMaxLifetime возвращает наибольший срок жизни токенов, подписанных кольцом ключей:
+// раньше этого срока выведенный из подписи ключ удалять нельзя
+func SignedTokenMaxLifetime() (time.Duration, error) {
+	lifetime, err := AccessTokenLifetime()
+	if err != nil {
+		return 0, err
+	}
+	return max(lifetime, PurposeTokenMaxLifetime), nil
+}
+
+// loadKeyRing загружает кольцо ключей из манифеста JWT_KEYRING_FILE (см. cmd/keyctl),
+// а если он не задан — собирает кольцо из одного ключа, описанного переменными окружения
+func loadKeyRing(maxLifetime time.Duration) (KeyRing, error) {
+	if path := os.Getenv("JWT_KEYRING_FILE"); path != "" {
+		ring, err := LoadKeyRing(path, maxLifetime)
+		if err != nil {
+			return KeyRing{}, fmt.Errorf("load keyring: %w", err)
+		}
+		return ring, nil
+	}
+
+	key, err := loadSigningKey()
+	if err != nil {
+		return KeyRing{}, err
+	}
+	return KeyRing{Current: key}, nil
+}
+
+// loadSigningKey выбирает ключ подписи по JWT_ALGORITHM (HS256 по умолчанию).
+// Для HS256 нужен общий JWT_SECRET, для RS256/ES256/EdDSA — закрытый ключ в PEM из JWT_PRIVATE_KEY_FILE
+// OpenID Connect работает только с асимметричным ключом: ID-токены проверяются клиентами по JWKS
+func loadSigningKey() (SigningKey, error) {
+	alg := os.Getenv("JWT_ALGORITHM")
+	if alg == "" {
+		alg = AlgHS256
+	}
+	kid := os.Getenv("JWT_KEY_ID")
+
+	if alg == AlgHS256 {
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			return SigningKey{}, fmt.Errorf("JWT_SECRET is required")
+		}
+		if kid == "" {
+			kid = "default"
+		}
+		return NewSecretKey(kid, secret), nil
+	}
+
+	path := os.Getenv("JWT_PRIVATE_KEY_FILE")
+	if path == "" {
+		return SigningKey{}, fmt.Errorf("JWT_PRIVATE_KEY_FILE is required for %s", alg)
+	}
+	key, err := LoadPrivateKeyPEM(kid, alg, path)
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("load signing key: %w", err)
+	}
+	return key, nil
+}
+
+// loadTokenSources читает AUTH_TOKEN_SOURCES — список источников через запятую,
+// по умолчанию "cookie,header"
+func loadTokenSources() ([]string, error) {
+	raw := os.Getenv("AUTH_TOKEN_SOURCES")
+	if raw == "" {
+		return []string{TokenSourceCookie, TokenSourceHeader}, nil
+	}
+	var sources []string
+	for _, s := range strings.Split(raw, ",") {
+		s = strings.TrimSpace(s)
+		switch s {
+		case TokenSourceCookie, TokenSourceHeader:
+			sources = append(sources, s)
+		default:
+			return nil, fmt.Errorf("invalid AUTH_TOKEN_SOURCES entry %q", s)
+		}
+	}
+	return sources, nil
+}
+
+// secondsFromEnv читает длительность в секундах из переменной окружения
+func secondsFromEnv(name string, def int) (time.Duration, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return time.Duration(def) * time.Second, nil
+	}
+	secs, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return time.Duration(secs) * time.Second, nil
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// KeyRing — набор ключей JWT: текущий ключ подписи и ключи, которыми ещё можно проверять токены.
+// Verification содержит ключи, выведенные из подписи (ещё живы выпущенные ими токены),
+// и заранее опубликованные ключи, которые станут текущими при следующей ротации
+type KeyRing struct {
+	Current      SigningKey
+	Verification []SigningKey
+}
+
+// Lookup ищет ключ проверки подписи по kid
+func (r KeyRing) Lookup(kid string) (SigningKey, bool) {
+	if r.Current.ID == kid {
+		return r.Current, true
+	}
+	for _, k := range r.Verification {
+		if k.ID == kid {
+			return k, true
+		}
+	}
+	return SigningKey{}, false
+}
+
+// All возвращает все ключи кольца, начиная с текущего
+func (r KeyRing) All() []SigningKey {
+	return append([]SigningKey{r.Current}, r.Verification...)
+}
+
+// Состояния ключа в манифесте
+const (
+	KeyStatePending  = "pending"  // опубликован для проверки, но ещё не подписывает
+	KeyStateCurrent  = "current"  // подписывает новые токены
+	KeyStatePrevious = "previous" // только проверка, до истечения выпущенных им токенов
+)
+
+// KeyRingManifest — JSON-файл с описанием кольца ключей. Пути к файлам ключей
+// указываются относительно каталога манифеста
+type KeyRingManifest struct {
+	Keys []ManifestKey `json:"keys"`
+}
+
+type ManifestKey struct {
+	ID         string     `json:"kid"`
+	Algorithm  string     `json:"alg"`
+	File       string     `json:"file"`
+	State      string     `json:"state"`
+	CreatedAt  time.Time  `json:"created_at"`
+	PromotedAt *time.Time `json:"promoted_at,omitempty"`
+	DemotedAt  *time.Time `json:"demoted_at,omitempty"`
+}
+
+// Retirable сообщает, можно ли удалить ключ: он выведен из подписи раньше, чем maxLifetime назад
+func (k ManifestKey) Retirable(now time.Time, maxLifetime time.Duration) bool {
+	return k.State == KeyStatePrevious && k.DemotedAt != nil && now.Sub(*k.DemotedAt) >= maxLifetime
+}
+
+func ReadKeyRingManifest(path string) (*KeyRingManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read keyring manifest: %w", err)
+	}
+	m := new(KeyRingManifest)
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parse keyring manifest: %w", err)
+	}
+	return m, nil
+}
+
+// WriteKeyRingManifest атомарно перезаписывает манифест
+func WriteKeyRingManifest(path string, m *KeyRingManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadKeyRing читает манифест и загружает ключи. Ключи в состоянии previous,
+// выведенные из подписи больше maxLifetime назад, пропускаются — их токены уже истекли
+func LoadKeyRing(path string, maxLifetime time.Duration) (KeyRing, error) {
+	m, err := ReadKeyRingManifest(path)
+	if err != nil {
+		return KeyRing{}, err
+	}
+	dir := filepath.Dir(path)
+	now := time.Now()
+
+	var ring KeyRing
+	hasCurrent := false
+	for _, mk := range m.Keys {
+		if mk.Retirable(now, maxLifetime) {
+			continue
+		}
+		key, err := loadManifestKey(dir, mk)
+		if err != nil {
+			return KeyRing{}, fmt.Errorf("key %q: %w", mk.ID, err)
+		}
+		switch mk.State {
+		case KeyStateCurrent:
+			if hasCurrent {
+				return KeyRing{}, errors.New("keyring has more than one current key")
+			}
+			ring.Current = key
+			hasCurrent = true
+		case KeyStatePending, KeyStatePrevious:
+			ring.Verification = append(ring.Verification, key)
+		default:
+			return KeyRing{}, fmt.Errorf("key %q: unknown state %q", mk.ID, mk.State)
+		}
+	}
+	if !hasCurrent {
+		return KeyRing{}, errors.New("keyring has no current key")
+	}
+	return ring, nil
+}
+
+func loadManifestKey(dir string, mk ManifestKey) (SigningKey, error) {
+	path := mk.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if mk.Algorithm == AlgHS256 {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return SigningKey{}, fmt.Errorf("read secret file: %w", err)
+		}
+		secret := strings.TrimSpace(string(data))
+		if secret == "" {
+			return SigningKey{}, errors.New("empty secret")
+		}
+		return NewSecretKey(mk.ID, secret), nil
+	}
+	return LoadPrivateKeyPEM(mk.ID, mk.Algorithm, path)
+}
+
+// GenerateKeyFile создаёт новый ключ для алгоритма alg и записывает его в path:
+// закрытый ключ в PKCS#8 PEM или, для HS256, случайный секрет в base64
+func GenerateKeyFile(alg, path string) error {
+	var data []byte
+	if alg == AlgHS256 {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+		data = []byte(base64.RawStdEncoding.EncodeToString(buf) + "\n")
+	} else {
+		var key crypto.Signer
+		var err error
+		switch alg {
+		case AlgRS256:
+			key, err = rsa.GenerateKey(rand.Reader, 2048)
+		case AlgES256:
+			key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		case AlgEdDSA:
+			_, key, err = ed25519.GenerateKey(rand.Reader)
+		default:
+			return fmt.Errorf("unsupported signing algorithm %q", alg)
+		}
+		if err != nil {
+			return err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return err
+		}
+		data = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	}
+	return os.WriteFile(path, data, 0o600)
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	MailDriverSMTP = "smtp"
+	MailDriverLog  = "log"
+)
+
+type MailConfig struct {
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+	// LogFile — куда LogMailer дописывает письма, если задан
+	LogFile string
+}
+
+// LoadMail читает настройки почты. По умолчанию письма только пишутся в лог
+func LoadMail() (MailConfig, error) {
+	cfg := MailConfig{
+		Driver:       os.Getenv("MAIL_DRIVER"),
+		From:         os.Getenv("MAIL_FROM"),
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     os.Getenv("SMTP_PORT"),
+		SMTPUser:     os.Getenv("SMTP_USER"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		LogFile:      os.Getenv("MAIL_LOG_FILE"),
+	}
+	if cfg.Driver == "" {
+		cfg.Driver = MailDriverLog
+	}
+	if cfg.From == "" {
+		cfg.From = "no-reply@localhost"
+	}
+	if cfg.SMTPPort == "" {
+		cfg.SMTPPort = "587"
+	}
+	if cfg.Driver == MailDriverSMTP && cfg.SMTPHost == "" {
+		return MailConfig{}, fmt.Errorf("SMTP_HOST is required for smtp mail driver")
+	}
+	return cfg, nil
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+type OIDCConfig struct {
+	// Issuer — публичный адрес сервиса, по которому клиенты находят /.well-known/openid-configuration.
+	// Попадает в iss выпускаемых ID-токенов
+	Issuer string
+}
+
+// LoadOIDC читает настройки OpenID Connect. Issuer — публичный адрес API, а не фронтенда, поэтому
+// при фронтенде на своём адресе (APP_BASE_URL) OIDC_ISSUER обязателен. Если не задано ни то, ни другое,
+// issuer выводится из адреса, который слушает сервис: так работает локальный запуск
+func LoadOIDC(listenAddr string) (OIDCConfig, error) {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		if os.Getenv("APP_BASE_URL") != "" {
+			return OIDCConfig{}, fmt.Errorf("OIDC_ISSUER is required when APP_BASE_URL is set: it must be the public url of the API")
+		}
+		issuer = "http://localhost" + listenAddr
+	}
+	issuer = strings.TrimRight(issuer, "/")
+
+	parsed, err := url.Parse(issuer)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return OIDCConfig{}, fmt.Errorf("OIDC_ISSUER must be an absolute url")
+	}
+	if parsed.RawQuery != "" || parsed.Fragment != "" {
+		return OIDCConfig{}, fmt.Errorf("OIDC_ISSUER must not contain query or fragment")
+	}
+	return OIDCConfig{Issuer: issuer}, nil
+}
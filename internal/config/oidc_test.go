@@ -0,0 +1,38 @@
+package config
+
+import "testing"
+
+func TestLoadOIDC(t *testing.T) {
+	tests := []struct {
+		name    string
+		issuer  string
+		baseURL string
+		want    string
+		wantErr bool
+	}{
+		{name: "explicit issuer", issuer: "https://api.example.com/", baseURL: "https://app.example.com", want: "https://api.example.com"},
+		{name: "local run derives issuer from listen address", want: "http://localhost:8080"},
+		{name: "frontend url is not the issuer", baseURL: "https://app.example.com", wantErr: true},
+		{name: "relative issuer", issuer: "/api", wantErr: true},
+		{name: "issuer with query", issuer: "https://api.example.com?x=1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OIDC_ISSUER", tt.issuer)
+			t.Setenv("APP_BASE_URL", tt.baseURL)
+			cfg, err := LoadOIDC(":8080")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("issuer = %q, want error", cfg.Issuer)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadOIDC: %v", err)
+			}
+			if cfg.Issuer != tt.want {
+				t.Fatalf("issuer = %q, want %q", cfg.Issuer, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// PasswordConfig — параметры Argon2id для новых хешей паролей и политика паролей.
+// Параметры Argon2id записываются в сам хеш, поэтому их можно менять: старые хеши проверяются
+// своими параметрами и пересчитываются при следующем успешном логине
+type PasswordConfig struct {
+	// Memory — объём памяти в KiB
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+
+	MinLength      int
+	MaxLength      int
+	MinCharClasses int
+	// BreachedListFile — файл с SHA-1 утёкших паролей, проверка выключена, если не задан
+	BreachedListFile string
+}
+
+func LoadPassword() (PasswordConfig, error) {
+	memory, err := uintFromEnv("ARGON2_MEMORY_KIB", 64*1024, 32)
+	if err != nil {
+		return PasswordConfig{}, err
+	}
+	iterations, err := uintFromEnv("ARGON2_TIME", 1, 32)
+	if err != nil {
+		return PasswordConfig{}, err
+	}
+	threads, err := uintFromEnv("ARGON2_THREADS", 4, 8)
+	if err != nil {
+		return PasswordConfig{}, err
+	}
+
+	minLength, err := uintFromEnv("PASSWORD_MIN_LENGTH", 8, 16)
+	if err != nil {
+		return PasswordConfig{}, err
+	}
+	maxLength, err := uintFromEnv("PASSWORD_MAX_LENGTH", 128, 16)
+	if err != nil {
+		return PasswordConfig{}, err
+	}
+	minClasses, err := uintFromEnv("PASSWORD_MIN_CHAR_CLASSES", 3, 8)
+	if err != nil {
+		return PasswordConfig{}, err
+	}
+
+	cfg := PasswordConfig{
+		Memory:           uint32(memory),
+		Time:             uint32(iterations),
+		Threads:          uint8(threads),
+		MinLength:        int(minLength),
+		MaxLength:        int(maxLength),
+		MinCharClasses:   int(minClasses),
+		BreachedListFile: os.Getenv("PASSWORD_BREACHED_LIST_FILE"),
+	}
+	if cfg.Time < 1 || cfg.Threads < 1 {
+		return PasswordConfig{}, fmt.Errorf("ARGON2_TIME and ARGON2_THREADS must be positive")
+	}
+	if cfg.Memory < 8*uint32(cfg.Threads) {
+		return PasswordConfig{}, fmt.Errorf("ARGON2_MEMORY_KIB must be at least 8*ARGON2_THREADS")
+	}
+	if cfg.MaxLength < cfg.MinLength {
+		return PasswordConfig{}, fmt.Errorf("PASSWORD_MAX_LENGTH must not be less than PASSWORD_MIN_LENGTH")
+	}
+	if cfg.MinCharClasses > 4 {
+		return PasswordConfig{}, fmt.Errorf("PASSWORD_MIN_CHAR_CLASSES must be between 0 and 4")
+	}
+	return cfg, nil
+}
+
+func uintFromEnv(name string, def uint64, bits int) (uint64, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseUint(raw, 10, bits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// SigningKey — ключ подписи JWT. Для HS256 заполнен Secret, для асимметричных алгоритмов — Private
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	Secret    []byte
+	Private   crypto.Signer
+}
+
+// Method возвращает метод подписи jwt, соответствующий алгоритму ключа
+func (k SigningKey) Method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// SignKey возвращает ключ в том виде, который ожидает jwt при подписи
+func (k SigningKey) SignKey() any {
+	if k.Algorithm == AlgHS256 {
+		return k.Secret
+	}
+	return k.Private
+}
+
+// VerifyKey возвращает ключ в том виде, который ожидает jwt при проверке подписи
+func (k SigningKey) VerifyKey() any {
+	if k.Algorithm == AlgHS256 {
+		return k.Secret
+	}
+	return k.Private.Public()
+}
+
+// Asymmetric сообщает, можно ли опубликовать открытую часть ключа в JWKS
+func (k SigningKey) Asymmetric() bool {
+	return k.Algorithm != AlgHS256
+}
+
+// NewSecretKey создаёт HS256-ключ из общего секрета
+func NewSecretKey(id, secret string) SigningKey {
+	return SigningKey{ID: id, Algorithm: AlgHS256, Secret: []byte(secret)}
+}
+
+// LoadPrivateKeyPEM читает закрытый ключ из PEM-файла и проверяет, что он подходит под алгоритм.
+// Если id пустой, в качестве kid используется отпечаток открытого ключа
+func LoadPrivateKeyPEM(id, alg, path string) (SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("read key file: %w", err)
+	}
+
+	var signer crypto.Signer
+	switch alg {
+	case AlgRS256:
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return SigningKey{}, fmt.Errorf("parse RSA key: %w", err)
+		}
+		if key.N.BitLen() < 2048 {
+			return SigningKey{}, fmt.Errorf("RSA key must be at least 2048 bits")
+		}
+		signer = key
+	case AlgES256:
+		key, err := jwt.ParseECPrivateKeyFromPEM(data)
+		if err != nil {
+			return SigningKey{}, fmt.Errorf("parse EC key: %w", err)
+		}
+		if key.Curve != elliptic.P256() {
+			return SigningKey{}, fmt.Errorf("ES256 requires a P-256 key")
+		}
+		signer = key
+	case AlgEdDSA:
+		key, err := jwt.ParseEdPrivateKeyFromPEM(data)
+		if err != nil {
+			return SigningKey{}, fmt.Errorf("parse Ed25519 key: %w", err)
+		}
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return SigningKey{}, fmt.Errorf("EdDSA requires an Ed25519 key")
+		}
+		signer = edKey
+	default:
+		return SigningKey{}, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+
+	if id == "" {
+		id, err = keyThumbprint(signer.Public())
+		if err != nil {
+			return SigningKey{}, err
+		}
+	}
+
+	return SigningKey{ID: id, Algorithm: alg, Private: signer}, nil
+}
+
+// keyThumbprint строит короткий идентификатор ключа из SHA-256 его открытой части
+func keyThumbprint(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("marshal public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:12]), nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// providerNamePattern — имя провайдера попадает в URL и в имена переменных окружения
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// SocialProviderConfig — внешний OpenID Connect провайдер для входа "через X"
+type SocialProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+type SocialConfig struct {
+	Providers []SocialProviderConfig
+}
+
+// LoadSocial читает список провайдеров из SOCIAL_PROVIDERS (через запятую), а настройки каждого —
+// из SOCIAL_<NAME>_ISSUER, SOCIAL_<NAME>_CLIENT_ID, SOCIAL_<NAME>_CLIENT_SECRET и SOCIAL_<NAME>_SCOPES
+func LoadSocial() (SocialConfig, error) {
+	var cfg SocialConfig
+	for _, name := range strings.Split(os.Getenv("SOCIAL_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !providerNamePattern.MatchString(name) {
+			return SocialConfig{}, fmt.Errorf("invalid social provider name %q", name)
+		}
+
+		prefix := "SOCIAL_" + strings.ToUpper(name) + "_"
+		provider := SocialProviderConfig{
+			Name:         name,
+			Issuer:       strings.TrimRight(os.Getenv(prefix+"ISSUER"), "/"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			return SocialConfig{}, fmt.Errorf("%sISSUER and %sCLIENT_ID are required", prefix, prefix)
+		}
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"openid", "email", "profile"}
+		}
+		cfg.Providers = append(cfg.Providers, provider)
+	}
+	return cfg, nil
+}
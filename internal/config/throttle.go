@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const (
+	ThrottleStoreMemory   = "memory"
+	ThrottleStorePostgres = "postgres"
+)
+
+type ThrottleConfig struct {
+	// Store — где хранить счётчики неудачных попыток. При нескольких инстансах нужен postgres
+	Store string
+	// TrustProxyHeaders — брать адрес клиента из X-Forwarded-For (только за своим прокси)
+	TrustProxyHeaders bool
+}
+
+func LoadThrottle() (ThrottleConfig, error) {
+	cfg := ThrottleConfig{Store: os.Getenv("THROTTLE_STORE")}
+	if cfg.Store == "" {
+		cfg.Store = ThrottleStoreMemory
+	}
+	if cfg.Store != ThrottleStoreMemory && cfg.Store != ThrottleStorePostgres {
+		return ThrottleConfig{}, fmt.Errorf("unknown THROTTLE_STORE %q", cfg.Store)
+	}
+
+	if v := os.Getenv("TRUST_PROXY_HEADERS"); v != "" {
+		trust, err := strconv.ParseBool(v)
+		if err != nil {
+			return ThrottleConfig{}, fmt.Errorf("invalid TRUST_PROXY_HEADERS: %w", err)
+		}
+		cfg.TrustProxyHeaders = trust
+	}
+	return cfg, nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+type WebAuthnConfig struct {
+	// RPID — домен, к которому привязываются passkey. Менять его нельзя: старые ключи перестанут работать
+	RPID   string
+	RPName string
+	// Origins — адреса страниц, с которых разрешены церемонии
+	Origins []string
+}
+
+// LoadWebAuthn читает настройки passkey. По умолчанию RP ID и origin берутся из baseURL
+func LoadWebAuthn(baseURL string) (WebAuthnConfig, error) {
+	cfg := WebAuthnConfig{
+		RPID:   os.Getenv("WEBAUTHN_RP_ID"),
+		RPName: os.Getenv("WEBAUTHN_RP_NAME"),
+	}
+
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return WebAuthnConfig{}, fmt.Errorf("parse base url: %w", err)
+	}
+	if cfg.RPID == "" {
+		cfg.RPID = parsed.Hostname()
+	}
+	if cfg.RPID == "" {
+		return WebAuthnConfig{}, fmt.Errorf("WEBAUTHN_RP_ID is required")
+	}
+	if cfg.RPName == "" {
+		cfg.RPName = cfg.RPID
+	}
+
+	for _, origin := range strings.Split(os.Getenv("WEBAUTHN_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			cfg.Origins = append(cfg.Origins, origin)
+		}
+	}
+	if len(cfg.Origins) == 0 {
+		cfg.Origins = []string{parsed.Scheme + "://" + parsed.Host}
+	}
+	return cfg, nil
+}
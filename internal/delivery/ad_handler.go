@@ -13,6 +13,7 @@ import (
 	"github.com/gorilla/mux"
 	"log/slog"
 
+	"jwt_auth_project/internal/delivery/middleware"
 	"jwt_auth_project/internal/domain"
 	"jwt_auth_project/internal/repo"
 	"jwt_auth_project/internal/usecase"
@@ -29,18 +30,29 @@ func NewAdsHandler(adsUC usecase.AdsUseCase) *AdsHandler {
 	return &AdsHandler{adsUC: adsUC}
 }
 
-// RegisterRoutes регистрирует маршруты для работы с объявлениями
-func (h *AdsHandler) RegisterRoutes(r *mux.Router) {
+// RegisterRoutes регистрирует маршруты для работы с объявлениями.
+// Просмотр доступен анонимно, изменения — пользователям с правом ads:write
+func (h *AdsHandler) RegisterRoutes(r *mux.Router, guard *middleware.Guard) {
 	sub := r.PathPrefix("/ads").Subrouter()
-	sub.HandleFunc("", h.handleListAds).Methods(http.MethodGet)
-	sub.HandleFunc("", h.handleCreateAd).Methods(http.MethodPost)
-	sub.HandleFunc("/{id}", h.handleGetAd).Methods(http.MethodGet)
-	sub.HandleFunc("/{id}", h.handleUpdateAd).Methods(http.MethodPut)
-	sub.HandleFunc("/{id}", h.handleDeleteAd).Methods(http.MethodDelete)
+
+	public := guard.Subrouter(sub, middleware.Public)
+	public.HandleFunc("", h.handleListAds).Methods(http.MethodGet)
+	public.HandleFunc("/{id}", h.handleGetAd).Methods(http.MethodGet)
+
+	private := guard.Subrouter(sub, middleware.PermissionRestricted(domain.PermAdsWrite))
+	private.HandleFunc("", h.handleCreateAd).Methods(http.MethodPost)
+	private.HandleFunc("/{id}", h.handleUpdateAd).Methods(http.MethodPut)
+	private.HandleFunc("/{id}", h.handleDeleteAd).Methods(http.MethodDelete)
 }
 
-// handleCreateAd создаёт новое объявление через multipart/form-data
+// handleCreateAd создаёт новое объявление через multipart/form-data. Автор — текущий пользователь
 func (h *AdsHandler) handleCreateAd(w http.ResponseWriter, r *http.Request) {
+	actor, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+
 	// Ограничение размера тела до 10MB
 	r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
 	if err := r.ParseMultipartForm(10 << 20); err != nil {
@@ -50,18 +62,10 @@ func (h *AdsHandler) handleCreateAd(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Чтение и валидация полей
-	authorIDStr := r.FormValue("author_id")
 	title := strings.TrimSpace(r.FormValue("title"))
 	description := strings.TrimSpace(r.FormValue("description"))
 	priceStr := r.FormValue("price")
 
-	authorID, err := uuid.Parse(authorIDStr)
-	if err != nil {
-		slog.Error("create ad: invalid author_id", "error", err)
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid author_id"))
-		return
-	}
-
 	price, err := strconv.ParseFloat(priceStr, 64)
 	if err != nil {
 		slog.Error("create ad: invalid price", "error", err)
@@ -88,7 +92,6 @@ func (h *AdsHandler) handleCreateAd(w http.ResponseWriter, r *http.Request) {
 
 	// Формирование payload
 	payload := domain.CreateAdPayload{
-		AuthorID:    authorID,
 		Title:       title,
 		Description: description,
 		Price:       price,
@@ -99,10 +102,14 @@ func (h *AdsHandler) handleCreateAd(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Вызов бизнес-логики
-	ad, err := h.adsUC.CreateAd(r.Context(), payload)
+	ad, err := h.adsUC.CreateAd(r.Context(), actor, payload)
 	if err != nil {
 		slog.Error("create ad: usecase error", "error", err)
-		utils.WriteError(w, http.StatusBadRequest, err)
+		if errors.Is(err, usecase.ErrEmailNotVerified) {
+			utils.WriteError(w, http.StatusForbidden, err)
+		} else {
+			utils.WriteError(w, http.StatusBadRequest, err)
+		}
 		return
 	}
 
@@ -161,6 +168,12 @@ func (h *AdsHandler) handleListAds(w http.ResponseWriter, r *http.Request) {
 
 // handleUpdateAd обновляет объявление и опционально заменяет картинку
 func (h *AdsHandler) handleUpdateAd(w http.ResponseWriter, r *http.Request) {
+	actor, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+
 	idStr := mux.Vars(r)["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -218,11 +231,13 @@ func (h *AdsHandler) handleUpdateAd(w http.ResponseWriter, r *http.Request) {
 		payload.ContentType = header.Header.Get("Content-Type")
 	}
 
-	ad, err := h.adsUC.UpdateAd(r.Context(), payload)
+	ad, err := h.adsUC.UpdateAd(r.Context(), actor, payload)
 	if err != nil {
 		slog.Error("update ad: usecase error", "error", err)
 		if errors.Is(err, repo.ErrAdNotFound) {
 			utils.WriteError(w, http.StatusNotFound, err)
+		} else if errors.Is(err, usecase.ErrForbidden) {
+			utils.WriteError(w, http.StatusForbidden, err)
 		} else {
 			utils.WriteError(w, http.StatusBadRequest, err)
 		}
@@ -235,6 +250,12 @@ func (h *AdsHandler) handleUpdateAd(w http.ResponseWriter, r *http.Request) {
 
 // handleDeleteAd удаляет объявление по UUID
 func (h *AdsHandler) handleDeleteAd(w http.ResponseWriter, r *http.Request) {
+	actor, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+
 	idStr := mux.Vars(r)["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -243,11 +264,13 @@ func (h *AdsHandler) handleDeleteAd(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.adsUC.DeleteAd(r.Context(), id)
+	err = h.adsUC.DeleteAd(r.Context(), actor, id)
 	if err != nil {
 		slog.Error("delete ad: usecase error", "error", err)
 		if errors.Is(err, repo.ErrAdNotFound) {
 			utils.WriteError(w, http.StatusNotFound, err)
+		} else if errors.Is(err, usecase.ErrForbidden) {
+			utils.WriteError(w, http.StatusForbidden, err)
 		} else {
 			utils.WriteError(w, http.StatusInternalServerError, err)
 		}
@@ -0,0 +1,103 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"jwt_auth_project/internal/delivery/middleware"
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/usecase"
+	"jwt_auth_project/internal/utils"
+)
+
+// AdminHandler обрабатывает административные запросы: управление ролями пользователей
+type AdminHandler struct {
+	roleUC usecase.RoleUseCase
+}
+
+func NewAdminHandler(roleUC usecase.RoleUseCase) *AdminHandler {
+	return &AdminHandler{roleUC: roleUC}
+}
+
+// RegisterRoutes регистрирует маршруты /admin, доступные только с правом users:manage. Пользователь
+// за запросом не нужен, поэтому пускаются и сервисы с токеном client_credentials
+func (h *AdminHandler) RegisterRoutes(r *mux.Router, guard *middleware.Guard) {
+	sub := guard.Subrouter(r.PathPrefix("/admin").Subrouter(), middleware.ServicePermissionRestricted(domain.PermUsersManage))
+	sub.HandleFunc("/roles", h.handleListRoles).Methods(http.MethodGet)
+	sub.HandleFunc("/users/{id}/roles", h.handleGetUserRoles).Methods(http.MethodGet)
+	sub.HandleFunc("/users/{id}/roles", h.handleSetUserRoles).Methods(http.MethodPut)
+}
+
+// handleListRoles возвращает все роли с их правами
+func (h *AdminHandler) handleListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.roleUC.ListRoles(r.Context())
+	if err != nil {
+		slog.Error("list roles: usecase error", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+	if err := utils.WriteJSON(w, http.StatusOK, roles); err != nil {
+		slog.Error("list roles: write response failed", "error", err)
+	}
+}
+
+// handleGetUserRoles возвращает роли пользователя
+func (h *AdminHandler) handleGetUserRoles(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	roles, err := h.roleUC.GetUserRoles(r.Context(), id)
+	if err != nil {
+		slog.Error("get user roles: usecase error", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+	if err := utils.WriteJSON(w, http.StatusOK, map[string]any{"roles": roles}); err != nil {
+		slog.Error("get user roles: write response failed", "error", err)
+	}
+}
+
+// handleSetUserRoles заменяет роли пользователя
+func (h *AdminHandler) handleSetUserRoles(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	var payload domain.SetUserRolesPayload
+	if err := utils.ParceJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.roleUC.SetUserRoles(r.Context(), id, payload); err != nil {
+		var validationErrs validator.ValidationErrors
+		switch {
+		case errors.As(err, &validationErrs), errors.Is(err, repo.ErrUnknownRole):
+			utils.WriteError(w, http.StatusBadRequest, err)
+		case errors.Is(err, repo.ErrUserNotFound):
+			utils.WriteError(w, http.StatusNotFound, err)
+		default:
+			slog.Error("set user roles: usecase error", "user_id", id, "error", err)
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		}
+		return
+	}
+
+	actor, _ := utils.PrincipalFromContext(r.Context())
+	slog.Info("user roles changed", "user_id", id, "roles", payload.Roles, "by", actor.UserID, "client_id", actor.ClientID)
+	if err := utils.WriteJSON(w, http.StatusOK, map[string]any{"roles": payload.Roles}); err != nil {
+		slog.Error("set user roles: write response failed", "error", err)
+	}
+}
@@ -0,0 +1,116 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"jwt_auth_project/internal/delivery/middleware"
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/usecase"
+	"jwt_auth_project/internal/utils"
+)
+
+// APIKeyHandler обрабатывает управление персональными API-ключами
+type APIKeyHandler struct {
+	apiKeyUC usecase.APIKeyUseCase
+}
+
+func NewAPIKeyHandler(apiKeyUC usecase.APIKeyUseCase) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyUC: apiKeyUC}
+}
+
+// RegisterRoutes регистрирует маршруты /me/api-keys. Управлять ключами можно только
+// из сессии пользователя, сами ключи сюда доступа не дают
+func (h *APIKeyHandler) RegisterRoutes(r *mux.Router, guard *middleware.Guard) {
+	sub := guard.Subrouter(r.PathPrefix("/me/api-keys").Subrouter(), middleware.Authenticated)
+	sub.HandleFunc("", h.handleListAPIKeys).Methods(http.MethodGet)
+	sub.HandleFunc("", h.handleCreateAPIKey).Methods(http.MethodPost)
+	sub.HandleFunc("/{id}", h.handleRevokeAPIKey).Methods(http.MethodDelete)
+}
+
+// handleListAPIKeys возвращает действующие и истёкшие, но не отозванные ключи пользователя
+func (h *APIKeyHandler) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+
+	keys, err := h.apiKeyUC.ListAPIKeys(r.Context(), principal.UserID)
+	if err != nil {
+		slog.Error("list api keys: usecase failed", "user_id", principal.UserID, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, keys); err != nil {
+		slog.Error("list api keys: write response failed", "error", err)
+	}
+}
+
+// handleCreateAPIKey выпускает ключ. Полное значение ключа есть только в этом ответе
+func (h *APIKeyHandler) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+
+	var payload domain.CreateAPIKeyPayload
+	if err := utils.ParceJSON(r, &payload); err != nil {
+		slog.Error("create api key: invalid JSON", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	key, err := h.apiKeyUC.CreateAPIKey(r.Context(), principal, payload)
+	if err != nil {
+		slog.Error("create api key: usecase failed", "user_id", principal.UserID, "error", err)
+		if errors.Is(err, usecase.ErrScopeNotAllowed) {
+			utils.WriteError(w, http.StatusForbidden, err)
+			return
+		}
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	slog.Info("api key created", "user_id", principal.UserID, "key_id", key.ID, "scopes", key.Scopes)
+
+	if err := utils.WriteJSON(w, http.StatusCreated, key); err != nil {
+		slog.Error("create api key: write response failed", "error", err)
+	}
+}
+
+// handleRevokeAPIKey отзывает ключ, после чего он сразу перестаёт приниматься
+func (h *APIKeyHandler) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	if err := h.apiKeyUC.RevokeAPIKey(r.Context(), principal.UserID, id); err != nil {
+		if errors.Is(err, repo.ErrAPIKeyNotFound) {
+			utils.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+		slog.Error("revoke api key: usecase failed", "user_id", principal.UserID, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+
+	slog.Info("api key revoked", "user_id", principal.UserID, "key_id", id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
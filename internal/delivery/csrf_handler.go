@@ -0,0 +1,59 @@
+package delivery
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/delivery/middleware"
+	"jwt_auth_project/internal/utils"
+)
+
+// csrfCookieLifetime — срок CSRF-cookie, выданной вне входа, например после перезагрузки страницы
+const csrfCookieLifetime = 24 * time.Hour
+
+// handleCSRFToken возвращает текущий CSRF-токен, выдавая новый, если его нет.
+// Нужен фронтенду, который потерял токен из ответа на вход, например после перезагрузки страницы:
+// с другого поддомена cookie __Host- не прочитать
+func (h *Handler) handleCSRFToken(w http.ResponseWriter, r *http.Request) {
+	token := setCSRFCookie(w, r, time.Now().Add(csrfCookieLifetime))
+	if err := utils.WriteJSON(w, http.StatusOK, map[string]string{"csrf_token": token}); err != nil {
+		slog.Error("csrf: write response failed", "error", err)
+	}
+}
+
+// setCSRFCookie выставляет cookie с CSRF-токеном и дублирует токен в заголовке ответа.
+// Уже выданный токен сохраняется, чтобы обновление токенов в одной вкладке не ломало
+// запросы, которые другая вкладка отправляет со старым значением
+func setCSRFCookie(w http.ResponseWriter, r *http.Request, expires time.Time) string {
+	token := uuid.NewString()
+	if cookie, err := r.Cookie(middleware.CSRFCookieName); err == nil && cookie.Value != "" {
+		token = cookie.Value
+	}
+
+	// Не HttpOnly: фронтенд на том же origin читает токен прямо из cookie
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.CSRFCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.Header().Set(middleware.CSRFHeader, token)
+	return token
+}
+
+func clearCSRFCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.CSRFCookieName,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
@@ -0,0 +1,65 @@
+package delivery
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/usecase"
+	"jwt_auth_project/internal/utils"
+)
+
+// handleRequestMagicLink отправляет ссылку для входа без пароля. Ответ одинаков
+// для существующих и несуществующих адресов
+func (h *Handler) handleRequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	var payload domain.MagicLinkPayload
+	if err := utils.ParceJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.userUseCase.RequestMagicLink(r.Context(), payload); err != nil {
+		if writeThrottled(w, err) {
+			return
+		}
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	resp := map[string]string{"message": "if the account exists, a sign-in link has been sent"}
+	if err := utils.WriteJSON(w, http.StatusAccepted, resp); err != nil {
+		slog.Error("magic link: write response failed", "error", err)
+	}
+}
+
+// handleMagicLinkCallback — адрес ссылки из письма, который открывается в браузере. Гасит токен,
+// выставляет те же cookie, что и /login, и возвращает на фронтенд. Ошибка передаётся странице
+// входа в error
+func (h *Handler) handleMagicLinkCallback(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		h.redirectToApp(w, r, "/login", url.Values{"error": {"invalid_link"}})
+		return
+	}
+
+	result, err := h.userUseCase.LoginMagicLink(r.Context(), token)
+	if err != nil {
+		slog.Error("magic link callback: usecase failed", "error", err)
+		code := "server_error"
+		if errors.Is(err, usecase.ErrInvalidToken) || errors.Is(err, repo.ErrOneTimeTokenInvalid) {
+			code = "invalid_link"
+		}
+		h.redirectToApp(w, r, "/login", url.Values{"error": {code}})
+		return
+	}
+
+	if result.MFAToken != "" {
+		slog.Info("magic link: second factor required")
+	} else {
+		slog.Info("user logged in via magic link")
+	}
+	h.redirectLoginResult(w, r, result)
+}
@@ -0,0 +1,98 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/usecase"
+)
+
+const testBaseURL = "https://app.example.com"
+
+// linkLoginUseCase отвечает на вход по ссылкам заранее заданным результатом
+type linkLoginUseCase struct {
+	usecase.UserUseCase
+	result *domain.LoginResult
+	err    error
+}
+
+func (uc linkLoginUseCase) LoginMagicLink(context.Context, string) (*domain.LoginResult, error) {
+	return uc.result, uc.err
+}
+
+type linkLoginCase struct {
+	name         string
+	query        string
+	result       *domain.LoginResult
+	err          error
+	wantLocation string
+	wantCookies  bool
+}
+
+// linkLoginCases — общие случаи входа по ссылке, открытой в браузере
+var linkLoginCases = []linkLoginCase{
+	{
+		name:         "tokens",
+		query:        "?token=t",
+		result:       &domain.LoginResult{Tokens: &domain.TokenPair{AccessToken: "access", RefreshToken: "refresh", AccessExpiresAt: time.Now().Add(time.Hour), RefreshExpiresAt: time.Now().Add(time.Hour)}},
+		wantLocation: testBaseURL,
+		wantCookies:  true,
+	},
+	{
+		name:         "second factor required",
+		query:        "?token=t",
+		result:       &domain.LoginResult{MFAToken: "mfa-token"},
+		wantLocation: testBaseURL + "/login#mfa_token=mfa-token",
+	},
+	{
+		name:         "invalid link",
+		query:        "?token=t",
+		err:          repo.ErrOneTimeTokenInvalid,
+		wantLocation: testBaseURL + "/login#error=invalid_link",
+	},
+	{
+		name:         "server error",
+		query:        "?token=t",
+		err:          errors.New("db down"),
+		wantLocation: testBaseURL + "/login#error=server_error",
+	},
+}
+
+func TestMagicLinkCallbackRedirectsToApp(t *testing.T) {
+	cases := append([]linkLoginCase{{name: "no token", wantLocation: testBaseURL + "/login#error=invalid_link"}}, linkLoginCases...)
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(linkLoginUseCase{result: tt.result, err: tt.err}, testBaseURL+"/")
+			w := httptest.NewRecorder()
+			h.handleMagicLinkCallback(w, httptest.NewRequest(http.MethodGet, "/login/magic-link/callback"+tt.query, nil))
+			checkLinkLoginRedirect(t, w, tt.wantLocation, tt.wantCookies)
+		})
+	}
+}
+
+func checkLinkLoginRedirect(t *testing.T, w *httptest.ResponseRecorder, wantLocation string, wantCookies bool) {
+	t.Helper()
+	if w.Code != http.StatusFound {
+		t.Fatalf("status = %d, want 302: %s", w.Code, w.Body.String())
+	}
+	if location := w.Header().Get("Location"); location != wantLocation {
+		t.Fatalf("Location = %q, want %q", location, wantLocation)
+	}
+	cookies := map[string]bool{}
+	for _, c := range w.Result().Cookies() {
+		if c.MaxAge >= 0 && c.Value != "" {
+			cookies[c.Name] = true
+		}
+	}
+	for _, name := range []string{accessCookieName, refreshCookieName} {
+		if cookies[name] != wantCookies {
+			t.Fatalf("cookie %s set = %v, want %v", name, cookies[name], wantCookies)
+		}
+	}
+}
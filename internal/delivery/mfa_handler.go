@@ -0,0 +1,125 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/usecase"
+	"jwt_auth_project/internal/utils"
+)
+
+// handleLoginMFA завершает логин: обменивает mfa_pending токен и код на пару токенов
+func (h *Handler) handleLoginMFA(w http.ResponseWriter, r *http.Request) {
+	var payload domain.LoginMFAPayload
+	if err := utils.ParceJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	pair, err := h.userUseCase.LoginMFA(r.Context(), payload)
+	if err != nil {
+		slog.Error("login mfa: usecase failed", "error", err)
+		writeMFAError(w, err)
+		return
+	}
+
+	slog.Info("user logged in with second factor")
+
+	if err := writeTokens(w, r, pair, false); err != nil {
+		slog.Error("login mfa: write response failed", "error", err)
+	}
+}
+
+// handleEnrollTOTP выдаёт секрет и otpauth:// ссылку для приложения-аутентификатора
+func (h *Handler) handleEnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+
+	enrollment, err := h.userUseCase.EnrollTOTP(r.Context(), principal.UserID)
+	if err != nil {
+		slog.Error("enroll totp: usecase failed", "user_id", principal.UserID, "error", err)
+		writeMFAError(w, err)
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, enrollment); err != nil {
+		slog.Error("enroll totp: write response failed", "error", err)
+	}
+}
+
+// handleConfirmTOTP включает 2FA и единственный раз отдаёт коды восстановления
+func (h *Handler) handleConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+	var payload domain.TOTPCodePayload
+	if err := utils.ParceJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	codes, err := h.userUseCase.ConfirmTOTP(r.Context(), principal.UserID, payload)
+	if err != nil {
+		slog.Error("confirm totp: usecase failed", "user_id", principal.UserID, "error", err)
+		writeMFAError(w, err)
+		return
+	}
+
+	slog.Info("two-factor authentication enabled", "user_id", principal.UserID)
+
+	if err := utils.WriteJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes}); err != nil {
+		slog.Error("confirm totp: write response failed", "error", err)
+	}
+}
+
+// handleDisableTOTP выключает 2FA по действующему коду или коду восстановления
+func (h *Handler) handleDisableTOTP(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+	var payload domain.DisableMFAPayload
+	if err := utils.ParceJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.userUseCase.DisableTOTP(r.Context(), principal.UserID, payload); err != nil {
+		slog.Error("disable totp: usecase failed", "user_id", principal.UserID, "error", err)
+		writeMFAError(w, err)
+		return
+	}
+
+	slog.Info("two-factor authentication disabled", "user_id", principal.UserID)
+
+	if err := utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "two-factor authentication disabled"}); err != nil {
+		slog.Error("disable totp: write response failed", "error", err)
+	}
+}
+
+// writeMFAError переводит ошибки 2FA в HTTP-статусы
+func writeMFAError(w http.ResponseWriter, err error) {
+	if writeThrottled(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, usecase.ErrInvalidMFACode),
+		errors.Is(err, usecase.ErrInvalidToken),
+		errors.Is(err, repo.ErrOneTimeTokenInvalid):
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid or expired code"))
+	case errors.Is(err, usecase.ErrMFAAlreadyEnabled), errors.Is(err, usecase.ErrMFANotEnabled):
+		utils.WriteError(w, http.StatusConflict, err)
+	default:
+		utils.WriteError(w, http.StatusBadRequest, err)
+	}
+}
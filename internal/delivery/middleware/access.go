@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gorilla/mux"
+
+	"jwt_auth_project/internal/usecase"
+	"jwt_auth_project/internal/utils"
+)
+
+// Access — уровень доступа группы маршрутов
+type Access struct {
+	authenticated bool
+	delegated     bool
+	services      bool
+	roles         []string
+	permissions   []string
+}
+
+var (
+	// Public — маршрут доступен всем; если запрос несёт валидный токен, пользователь попадает в контекст
+	Public = Access{}
+	// Authenticated — нужен валидный токен
+	Authenticated = Access{authenticated: true}
+)
+
+// RoleRestricted — нужен валидный токен и хотя бы одна из ролей
+func RoleRestricted(roles ...string) Access {
+	return Access{authenticated: true, roles: roles}
+}
+
+// PermissionRestricted — нужен валидный токен и все перечисленные права.
+// Подойдут и API-ключ или токен OAuth-клиента, scopes которых включают эти права, но только
+// если за ними стоит пользователь: токены client_credentials сюда не допускаются
+func PermissionRestricted(permissions ...string) Access {
+	return Access{authenticated: true, delegated: true, permissions: permissions}
+}
+
+// ServicePermissionRestricted — как PermissionRestricted, но пускает и токены client_credentials.
+// Только для маршрутов, которым не нужен пользователь, совершающий действие
+func ServicePermissionRestricted(permissions ...string) Access {
+	return Access{authenticated: true, delegated: true, services: true, permissions: permissions}
+}
+
+// Guard навешивает проверки доступа на подроутеры, чтобы каждый обработчик
+// объявлял уровень доступа своих маршрутов при регистрации
+type Guard struct {
+	userUC   usecase.UserUseCase
+	roleUC   usecase.RoleUseCase
+	apiKeyUC usecase.APIKeyUseCase
+	sources  []string
+}
+
+func NewGuard(userUC usecase.UserUseCase, roleUC usecase.RoleUseCase, apiKeyUC usecase.APIKeyUseCase, sources []string) *Guard {
+	return &Guard{userUC: userUC, roleUC: roleUC, apiKeyUC: apiKeyUC, sources: sources}
+}
+
+// Subrouter создаёт на r группу маршрутов с уровнем доступа access
+func (g *Guard) Subrouter(r *mux.Router, access Access) *mux.Router {
+	sub := r.NewRoute().Subrouter()
+	sub.Use(g.Middleware(access)...)
+	return sub
+}
+
+// Middleware возвращает цепочку middleware, реализующую уровень доступа
+func (g *Guard) Middleware(access Access) []mux.MiddlewareFunc {
+	if !access.authenticated {
+		return []mux.MiddlewareFunc{OptionalAuthMiddleware(g.userUC, g.roleUC, g.sources)}
+	}
+	chain := []mux.MiddlewareFunc{AuthMiddleware(g.userUC, g.roleUC, g.apiKeyUC, g.sources)}
+	// API-ключи и OAuth-клиенты допускаются только туда, где доступ задан правами:
+	// управлять аккаунтом, сессиями и самими ключами от чужого имени нельзя
+	if !access.delegated {
+		chain = append(chain, RequireFirstParty())
+	} else if !access.services {
+		chain = append(chain, RequireUser())
+	}
+	if len(access.roles) > 0 {
+		chain = append(chain, RequireRole(access.roles...))
+	}
+	for _, permission := range access.permissions {
+		chain = append(chain, RequirePermission(permission))
+	}
+	return chain
+}
+
+// RequireFirstParty пропускает только запросы самого пользователя, а не API-ключа или OAuth-клиента.
+// Должен стоять после AuthMiddleware
+func RequireFirstParty() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := utils.PrincipalFromContext(r.Context())
+			if !ok {
+				utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+				return
+			}
+			if !principal.FirstParty() {
+				utils.WriteError(w, http.StatusForbidden, fmt.Errorf("this endpoint requires a user session"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireUser не пропускает OAuth-клиентов, действующих от своего имени по client_credentials.
+// Должен стоять после AuthMiddleware
+func RequireUser() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := utils.PrincipalFromContext(r.Context())
+			if !ok {
+				utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+				return
+			}
+			if principal.Service() {
+				utils.WriteError(w, http.StatusForbidden, fmt.Errorf("this endpoint requires a user"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole пропускает запрос, только если у пользователя есть одна из ролей.
+// Должен стоять после AuthMiddleware
+func RequireRole(roles ...string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := utils.PrincipalFromContext(r.Context())
+			if !ok {
+				utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+				return
+			}
+			if slices.ContainsFunc(roles, principal.HasRole) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			utils.WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+		})
+	}
+}
+
+// RequirePermission пропускает запрос, только если роли пользователя дают право permission,
+// например RequirePermission("ads:moderate"). Должен стоять после AuthMiddleware
+func RequirePermission(permission string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := utils.PrincipalFromContext(r.Context())
+			if !ok {
+				utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+				return
+			}
+			if !principal.Can(permission) {
+				utils.WriteError(w, http.StatusForbidden, fmt.Errorf("missing permission %s", permission))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
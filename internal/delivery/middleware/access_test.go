@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"jwt_auth_project/internal/config"
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/usecase"
+	"jwt_auth_project/internal/utils"
+)
+
+// clientTokenUseCase принимает любой токен как токен client_credentials клиента clientID
+type clientTokenUseCase struct {
+	usecase.UserUseCase
+	clientID    string
+	permissions []string
+	err         error
+}
+
+func (uc clientTokenUseCase) ValidateToken(context.Context, string) (*domain.Claims, error) {
+	claims := &domain.Claims{ClientID: uc.clientID, Scope: "users:manage ads:write"}
+	claims.Subject = uc.clientID
+	return claims, nil
+}
+
+func (uc clientTokenUseCase) ClientPermissions(context.Context, *domain.Claims) ([]string, error) {
+	return uc.permissions, uc.err
+}
+
+type noRoles struct{ usecase.RoleUseCase }
+
+func (noRoles) Permissions(context.Context, []string) ([]string, error) { return nil, nil }
+
+func TestGuardClientCredentialsToken(t *testing.T) {
+	// client_id из 32 hex-символов разбирается и как UUID
+	const clientID = "0123456789abcdef0123456789abcdef"
+	sources := []string{config.TokenSourceHeader}
+
+	tests := []struct {
+		name        string
+		access      Access
+		permissions []string
+		err         error
+		want        int
+	}{
+		{name: "service route with permission", access: ServicePermissionRestricted(domain.PermUsersManage), permissions: []string{domain.PermUsersManage}, want: http.StatusOK},
+		{name: "service route without permission", access: ServicePermissionRestricted(domain.PermUsersManage), want: http.StatusForbidden},
+		{name: "route acting as a user", access: PermissionRestricted(domain.PermAdsWrite), permissions: []string{domain.PermAdsWrite}, want: http.StatusForbidden},
+		{name: "account route", access: Authenticated, permissions: []string{domain.PermUsersManage}, want: http.StatusForbidden},
+		{name: "deleted client", access: ServicePermissionRestricted(domain.PermUsersManage), err: usecase.ErrInvalidToken, want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := clientTokenUseCase{clientID: clientID, permissions: tt.permissions, err: tt.err}
+			guard := NewGuard(uc, noRoles{}, nil, sources)
+
+			var principal domain.Principal
+			router := mux.NewRouter()
+			guard.Subrouter(router, tt.access).HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				principal, _ = utils.PrincipalFromContext(r.Context())
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Authorization", "Bearer token")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if w.Code == http.StatusOK && (!principal.Service() || principal.ClientID != clientID) {
+				t.Fatalf("principal = %+v, want a service principal without user", principal)
+			}
+		})
+	}
+}
@@ -2,39 +2,165 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 
 	"github.com/gorilla/mux"
 	"log/slog"
 
+	"jwt_auth_project/internal/config"
+	"jwt_auth_project/internal/domain"
 	"jwt_auth_project/internal/usecase"
 	"jwt_auth_project/internal/utils"
 )
 
-// AuthMiddleware проверяет JWT в cookie и добавляет userID в контекст
-func AuthMiddleware(userUC usecase.UserUseCase) mux.MiddlewareFunc {
+// APIKeyHeader — заголовок, в котором передаётся API-ключ
+const APIKeyHeader = "X-API-Key"
+
+var errMissingToken = errors.New("missing token")
+
+// AuthMiddleware проверяет JWT из cookie или заголовка Authorization и добавляет userID в контекст.
+// sources задаёт, откуда брать токен и в каком порядке. Вместо JWT принимается
+// и API-ключ из заголовка X-API-Key
+func AuthMiddleware(userUC usecase.UserUseCase, roleUC usecase.RoleUseCase, apiKeyUC usecase.APIKeyUseCase, sources []string) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Извлекаем JWT из cookie
-			cookie, err := r.Cookie("jwt")
+			var (
+				ctx context.Context
+				err error
+			)
+			if key := r.Header.Get(APIKeyHeader); key != "" {
+				ctx, err = authenticateAPIKey(r, apiKeyUC, key)
+			} else {
+				ctx, err = authenticate(r, userUC, roleUC, sources)
+			}
 			if err != nil {
-				slog.Error("auth: missing or invalid cookie", "error", err)
-				utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+				slog.Error("auth: authentication failed", "error", err)
+				if errors.Is(err, errMissingToken) {
+					utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+				} else if errors.Is(err, usecase.ErrInvalidAPIKey) {
+					utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid api key"))
+				} else if errors.Is(err, usecase.ErrInvalidToken) || errors.Is(err, usecase.ErrTokenRevoked) {
+					// Причина нужна клиенту: истёкший токен обновляют через refresh, остальные — нет
+					utils.WriteError(w, http.StatusUnauthorized, err)
+				} else {
+					utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid token"))
+				}
 				return
 			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
 
-			// Валидируем токен и получаем userID
-			userID, err := userUC.ValidateToken(cookie.Value)
+// OptionalAuthMiddleware добавляет пользователя в контекст, если запрос несёт валидный токен,
+// и пропускает запрос анонимно в остальных случаях
+func OptionalAuthMiddleware(userUC usecase.UserUseCase, roleUC usecase.RoleUseCase, sources []string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, err := authenticate(r, userUC, roleUC, sources)
 			if err != nil {
-				slog.Error("auth: token validation failed", "error", err)
-				utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid token"))
+				if !errors.Is(err, errMissingToken) {
+					slog.Warn("auth: ignoring invalid token on public route", "error", err)
+				}
+				next.ServeHTTP(w, r)
 				return
 			}
-
-			// Сохраняем userID в контексте запроса
-			ctx := context.WithValue(r.Context(), utils.ContextKeyUserID, userID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// authenticate извлекает и проверяет токен, возвращает контекст с userID, claims и principal
+func authenticate(r *http.Request, userUC usecase.UserUseCase, roleUC usecase.RoleUseCase, sources []string) (context.Context, error) {
+	// Извлекаем JWT из первого источника, где он есть
+	token := TokenFromRequest(r, sources)
+	if token == "" {
+		return nil, errMissingToken
+	}
+
+	// Валидируем токен (включая проверку отзыва) и получаем claims
+	claims, err := userUC.ValidateToken(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	// Subject токена client_credentials — client_id, а не пользователь, хотя 32 hex-символа
+	// и разбираются как UUID
+	if claims.ClientToken() {
+		permissions, err := userUC.ClientPermissions(r.Context(), claims)
+		if err != nil {
+			return nil, err
+		}
+		principal := domain.Principal{ClientID: claims.ClientID, Permissions: permissions}
+		ctx := context.WithValue(r.Context(), utils.ContextKeyClaims, claims)
+		ctx = context.WithValue(ctx, utils.ContextKeyPrincipal, principal)
+		return ctx, nil
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := roleUC.Permissions(r.Context(), claims.Roles)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, _ := claims.Session()
+	principal := domain.Principal{
+		UserID:      userID,
+		SessionID:   sessionID,
+		Roles:       claims.Roles,
+		Permissions: permissions,
+	}
+	// OAuth-клиент действует только в пределах выданных ему scopes и не получает ролей
+	if claims.ClientID != "" {
+		principal.ClientID = claims.ClientID
+		principal.Roles = nil
+		principal.Permissions = slices.DeleteFunc(permissions, func(p string) bool {
+			return !slices.Contains(claims.Scopes(), p)
+		})
+	}
+
+	// Сохраняем userID, claims и principal в контексте запроса
+	ctx := context.WithValue(r.Context(), utils.ContextKeyUserID, userID)
+	ctx = context.WithValue(ctx, utils.ContextKeyClaims, claims)
+	ctx = context.WithValue(ctx, utils.ContextKeyPrincipal, principal)
+	return ctx, nil
+}
+
+// authenticateAPIKey проверяет API-ключ и возвращает контекст с userID и principal.
+// Claims в контекст не попадают: ключ — не JWT
+func authenticateAPIKey(r *http.Request, apiKeyUC usecase.APIKeyUseCase, key string) (context.Context, error) {
+	principal, err := apiKeyUC.Authenticate(r.Context(), key)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.WithValue(r.Context(), utils.ContextKeyUserID, principal.UserID)
+	ctx = context.WithValue(ctx, utils.ContextKeyPrincipal, *principal)
+	return ctx, nil
+}
+
+// TokenFromRequest возвращает access-токен из первого по порядку источника, в котором он есть
+func TokenFromRequest(r *http.Request, sources []string) string {
+	token, _ := tokenFromRequest(r, sources)
+	return token
+}
+
+// tokenFromRequest возвращает access-токен и источник, из которого он взят
+func tokenFromRequest(r *http.Request, sources []string) (string, string) {
+	for _, source := range sources {
+		switch source {
+		case config.TokenSourceCookie:
+			if cookie, err := r.Cookie("jwt"); err == nil && cookie.Value != "" {
+				return cookie.Value, source
+			}
+		case config.TokenSourceHeader:
+			if token := utils.BearerToken(r); token != "" {
+				return token, source
+			}
+		}
+	}
+	return "", ""
+}
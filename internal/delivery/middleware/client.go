@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"jwt_auth_project/internal/utils"
+)
+
+// maxUserAgentLength — длиннее User-Agent не бывает у настоящих браузеров, обрезаем мусор
+const maxUserAgentLength = 512
+
+// ClientInfo кладёт в контекст адрес и User-Agent клиента. X-Forwarded-For учитывается только при trustProxy:
+// без доверенного прокси перед сервисом заголовок подделывается кем угодно
+func ClientInfo(trustProxy bool) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ua := r.UserAgent()
+			if len(ua) > maxUserAgentLength {
+				ua = ua[:maxUserAgentLength]
+			}
+			ctx := context.WithValue(r.Context(), utils.ContextKeyClientIP, clientIP(r, trustProxy))
+			ctx = context.WithValue(ctx, utils.ContextKeyUserAgent, ua)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		// Последний адрес дописал наш прокси — это тот, кто к нему подключился.
+		// Более ранние значения прислал сам клиент, им верить нельзя
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			parts := strings.Split(xff, ",")
+			if ip := strings.TrimSpace(parts[len(parts)-1]); net.ParseIP(ip) != nil {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"jwt_auth_project/internal/config"
+	"jwt_auth_project/internal/utils"
+)
+
+const (
+	// CSRFCookieName — cookie с CSRF-токеном. Префикс __Host- запрещает выставлять её
+	// с соседних поддоменов, поэтому подбросить свой токен оттуда нельзя
+	CSRFCookieName = "__Host-csrf_token"
+	// CSRFHeader — заголовок, в котором клиент повторяет CSRF-токен
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// CSRF защищает изменяющие запросы, аутентифицированные cookie: Origin (или Referer) должен быть
+// среди разрешённых, а заголовок X-CSRF-Token — совпадать с cookie, выданной при входе.
+// SameSite не спасает от соседних поддоменов — для браузера это тот же сайт.
+// Запрос не проверяется, если аутентифицирован заголовком X-API-Key или Authorization: их браузер
+// не добавит сам, а чужая страница не отправит без CORS. Но только когда заголовок действительно
+// заменяет cookie — sources задаёт тот же порядок источников, что и у AuthMiddleware
+func CSRF(cfg config.CSRFConfig, sources []string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !needsCSRFCheck(r, sources) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if origin := requestOrigin(r); origin != "" && !slices.Contains(cfg.AllowedOrigins, origin) {
+				slog.Warn("csrf: origin not allowed", "origin", origin, "path", r.URL.Path)
+				utils.WriteError(w, http.StatusForbidden, fmt.Errorf("origin not allowed"))
+				return
+			}
+
+			cookie, err := r.Cookie(CSRFCookieName)
+			header := r.Header.Get(CSRFHeader)
+			if err != nil || cookie.Value == "" || header == "" ||
+				subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) != 1 {
+				slog.Warn("csrf: token missing or mismatched", "path", r.URL.Path)
+				utils.WriteError(w, http.StatusForbidden, fmt.Errorf("invalid csrf token"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// needsCSRFCheck сообщает, что запрос изменяющий и браузер мог аутентифицировать его cookie
+func needsCSRFCheck(r *http.Request, sources []string) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	}
+	// refresh_token /token/refresh и /logout читают прямо из cookie, какие бы заголовки ни пришли
+	if hasCookie(r, "refresh_token") {
+		return true
+	}
+	return hasCookie(r, "jwt") && !headerCredential(r, sources)
+}
+
+// headerCredential сообщает, что запрос аутентифицируется заголовком, а не cookie "jwt":
+// API-ключом, который AuthMiddleware проверяет первым, или токеном из Authorization,
+// если по порядку sources он идёт раньше cookie
+func headerCredential(r *http.Request, sources []string) bool {
+	if r.Header.Get(APIKeyHeader) != "" {
+		return true
+	}
+	_, source := tokenFromRequest(r, sources)
+	return source == config.TokenSourceHeader
+}
+
+func hasCookie(r *http.Request, name string) bool {
+	cookie, err := r.Cookie(name)
+	return err == nil && cookie.Value != ""
+}
+
+// requestOrigin возвращает origin запроса из Origin, а если его нет — из Referer.
+// Пустая строка — браузер не сообщил ни того, ни другого. Origin "null" (sandbox-iframe,
+// переходы с file://) возвращается как есть и ни с одним разрешённым origin не совпадёт
+func requestOrigin(r *http.Request) string {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		return strings.ToLower(origin)
+	}
+	referer, err := url.Parse(r.Header.Get("Referer"))
+	if err != nil || referer.Host == "" {
+		return ""
+	}
+	return strings.ToLower(referer.Scheme + "://" + referer.Host)
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"jwt_auth_project/internal/config"
+)
+
+func TestCSRF(t *testing.T) {
+	const origin = "https://app.example.com"
+	cookieFirst := []string{config.TokenSourceCookie, config.TokenSourceHeader}
+	headerFirst := []string{config.TokenSourceHeader, config.TokenSourceCookie}
+
+	tests := []struct {
+		name     string
+		method   string
+		sources  []string
+		cookies  []string
+		header   map[string]string
+		withCSRF bool
+		want     int
+	}{
+		{name: "safe method", method: http.MethodGet, sources: cookieFirst, cookies: []string{"jwt"}, want: http.StatusOK},
+		{name: "no auth cookies", sources: cookieFirst, want: http.StatusOK},
+		{name: "cookie without csrf token", sources: cookieFirst, cookies: []string{"jwt"}, want: http.StatusForbidden},
+		{name: "cookie with csrf token", sources: cookieFirst, cookies: []string{"jwt"}, withCSRF: true, want: http.StatusOK},
+		{
+			name: "authorization ignored in favour of cookie", sources: cookieFirst, cookies: []string{"jwt"},
+			header: map[string]string{"Authorization": "Bearer token"}, want: http.StatusForbidden,
+		},
+		{
+			name: "authorization is not a token source", sources: []string{config.TokenSourceCookie}, cookies: []string{"jwt"},
+			header: map[string]string{"Authorization": "Bearer token"}, want: http.StatusForbidden,
+		},
+		{
+			name: "authorization used before cookie", sources: headerFirst, cookies: []string{"jwt"},
+			header: map[string]string{"Authorization": "Bearer token"}, want: http.StatusOK,
+		},
+		{
+			name: "non-bearer authorization", sources: headerFirst, cookies: []string{"jwt"},
+			header: map[string]string{"Authorization": "Basic dXNlcjpwYXNz"}, want: http.StatusForbidden,
+		},
+		{
+			name: "api key", sources: cookieFirst, cookies: []string{"jwt"},
+			header: map[string]string{APIKeyHeader: "key"}, want: http.StatusOK,
+		},
+		{
+			name: "refresh cookie is read regardless of headers", sources: headerFirst, cookies: []string{"jwt", "refresh_token"},
+			header: map[string]string{"Authorization": "Bearer token", APIKeyHeader: "key"}, want: http.StatusForbidden,
+		},
+		{name: "refresh cookie with csrf token", sources: headerFirst, cookies: []string{"refresh_token"}, withCSRF: true, want: http.StatusOK},
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			r := httptest.NewRequest(method, "/me/password", nil)
+			r.Header.Set("Origin", origin)
+			for _, name := range tt.cookies {
+				r.AddCookie(&http.Cookie{Name: name, Value: "value"})
+			}
+			for name, value := range tt.header {
+				r.Header.Set(name, value)
+			}
+			if tt.withCSRF {
+				r.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: "csrf"})
+				r.Header.Set(CSRFHeader, "csrf")
+			}
+
+			w := httptest.NewRecorder()
+			CSRF(config.CSRFConfig{AllowedOrigins: []string{origin}}, tt.sources)(ok).ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/usecase"
+	"jwt_auth_project/internal/utils"
+)
+
+// handleAuthorize — точка входа authorization code flow. Всегда отвечает редиректом: к клиенту с кодом
+// или ошибкой, на экран согласия или на страницу входа. Если клиент или redirect_uri неизвестны,
+// редиректить некуда — отвечаем 400
+func (h *Handler) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := domain.AuthorizeRequest{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		Nonce:               query.Get("nonce"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	}
+
+	// Войти от имени пользователя в другое приложение может только сам пользователь
+	var userID uuid.UUID
+	if principal, ok := utils.PrincipalFromContext(r.Context()); ok && principal.FirstParty() {
+		userID = principal.UserID
+	}
+
+	redirect, err := h.userUseCase.Authorize(r.Context(), userID, req)
+	if err != nil {
+		slog.Warn("oauth authorize: request rejected", "client_id", req.ClientID, "error", err)
+		var oauthErr *usecase.OAuthError
+		if errors.As(err, &oauthErr) {
+			if oauthErr.RedirectTo != "" {
+				http.Redirect(w, r, oauthErr.RedirectTo, http.StatusFound)
+				return
+			}
+			writeOAuthError(w, http.StatusBadRequest, oauthErr)
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+
+	http.Redirect(w, r, redirect.RedirectTo, http.StatusFound)
+}
+
+// handleGetConsent возвращает экрану согласия, какое приложение и что запрашивает
+func (h *Handler) handleGetConsent(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	consent, err := h.userUseCase.GetConsentRequest(r.Context(), principal.UserID, id)
+	if err != nil {
+		writeConsentError(w, err)
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, consent); err != nil {
+		slog.Error("get consent: write response failed", "error", err)
+	}
+}
+
+// handleDecideConsent принимает решение пользователя и возвращает адрес, на который фронтенд
+// должен отправить браузер
+func (h *Handler) handleDecideConsent(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+	var payload domain.OAuthConsentPayload
+	if err := utils.ParceJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	redirect, err := h.userUseCase.DecideConsent(r.Context(), principal.UserID, id, payload)
+	if err != nil {
+		writeConsentError(w, err)
+		return
+	}
+
+	slog.Info("oauth consent decided", "user_id", principal.UserID, "request_id", id, "approved", payload.Approve)
+
+	if err := utils.WriteJSON(w, http.StatusOK, redirect); err != nil {
+		slog.Error("decide consent: write response failed", "error", err)
+	}
+}
+
+// handleToken — token endpoint (RFC 6749, раздел 3.2). Клиент аутентифицируется через
+// HTTP Basic или параметрами client_id/client_secret в форме
+func (h *Handler) handleToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, &usecase.OAuthError{Code: usecase.OAuthInvalidRequest, Description: "invalid form"})
+		return
+	}
+	clientID, clientSecret, basic := clientCredentials(r)
+	req := domain.OAuthTokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+		RefreshToken: r.PostForm.Get("refresh_token"),
+		Scope:        r.PostForm.Get("scope"),
+	}
+
+	resp, err := h.userUseCase.Token(r.Context(), req)
+	if err != nil {
+		slog.Warn("oauth token: request rejected", "client_id", req.ClientID, "grant_type", req.GrantType, "error", err)
+		writeClientRequestError(w, err, basic)
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, resp); err != nil {
+		slog.Error("oauth token: write response failed", "error", err)
+	}
+}
+
+// handleIntrospect — introspection endpoint (RFC 7662). Чужие токены видят только клиенты с правом
+// introspect (API-шлюз и другие ресурсные серверы), остальные — лишь выданные им самим
+func (h *Handler) handleIntrospect(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	req, basic, ok := parseTokenQuery(w, r)
+	if !ok {
+		return
+	}
+
+	info, err := h.userUseCase.Introspect(r.Context(), req)
+	if err != nil {
+		slog.Warn("oauth introspect: request rejected", "client_id", req.ClientID, "error", err)
+		writeClientRequestError(w, err, basic)
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, info); err != nil {
+		slog.Error("oauth introspect: write response failed", "error", err)
+	}
+}
+
+// handleRevoke — revocation endpoint (RFC 7009). Отвечает 200 и на неизвестный токен,
+// чтобы по ответу нельзя было судить, существовал ли он
+func (h *Handler) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	req, basic, ok := parseTokenQuery(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.userUseCase.Revoke(r.Context(), req); err != nil {
+		slog.Warn("oauth revoke: request rejected", "client_id", req.ClientID, "error", err)
+		writeClientRequestError(w, err, basic)
+		return
+	}
+
+	slog.Info("oauth token revoked", "client_id", req.ClientID)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// parseTokenQuery разбирает форму /oauth/introspect и /oauth/revoke. При ошибке ответ уже записан
+func parseTokenQuery(w http.ResponseWriter, r *http.Request) (domain.OAuthTokenQuery, bool, bool) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, &usecase.OAuthError{Code: usecase.OAuthInvalidRequest, Description: "invalid form"})
+		return domain.OAuthTokenQuery{}, false, false
+	}
+	clientID, clientSecret, basic := clientCredentials(r)
+	return domain.OAuthTokenQuery{
+		Token:         r.PostForm.Get("token"),
+		TokenTypeHint: r.PostForm.Get("token_type_hint"),
+		ClientID:      clientID,
+		ClientSecret:  clientSecret,
+	}, basic, true
+}
+
+// clientCredentials возвращает client_id и секрет из HTTP Basic или, если его нет, из формы.
+// basic сообщает, что клиент использовал Basic. Форма должна быть уже разобрана
+func clientCredentials(r *http.Request) (clientID, clientSecret string, basic bool) {
+	id, secret, basic := r.BasicAuth()
+	if !basic {
+		return r.PostForm.Get("client_id"), r.PostForm.Get("client_secret"), false
+	}
+	// В Basic-авторизации client_id и секрет закодированы как form-urlencoded (RFC 6749, 2.3.1)
+	clientID, _ = url.QueryUnescape(id)
+	clientSecret, _ = url.QueryUnescape(secret)
+	return clientID, clientSecret, true
+}
+
+// writeClientRequestError отвечает на ошибку запроса клиента к token, introspection или revocation
+// endpoint. Неудачная аутентификация клиента — 401 (RFC 6749, раздел 5.2)
+func writeClientRequestError(w http.ResponseWriter, err error, basic bool) {
+	var oauthErr *usecase.OAuthError
+	if !errors.As(err, &oauthErr) {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+	status := http.StatusBadRequest
+	if oauthErr.Code == usecase.OAuthInvalidClient {
+		status = http.StatusUnauthorized
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+	}
+	writeOAuthError(w, status, oauthErr)
+}
+
+// handleRegisterOAuthClient регистрирует клиента. Секрет есть только в этом ответе
+func (h *Handler) handleRegisterOAuthClient(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+	var payload domain.RegisterOAuthClientPayload
+	if err := utils.ParceJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	client, err := h.userUseCase.RegisterOAuthClient(r.Context(), principal.UserID, payload)
+	if err != nil {
+		slog.Error("register oauth client: usecase failed", "error", err)
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	slog.Info("oauth client registered", "client_id", client.ID, "by", principal.UserID)
+
+	if err := utils.WriteJSON(w, http.StatusCreated, client); err != nil {
+		slog.Error("register oauth client: write response failed", "error", err)
+	}
+}
+
+func (h *Handler) handleListOAuthClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := h.userUseCase.ListOAuthClients(r.Context())
+	if err != nil {
+		slog.Error("list oauth clients: usecase failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+	if err := utils.WriteJSON(w, http.StatusOK, clients); err != nil {
+		slog.Error("list oauth clients: write response failed", "error", err)
+	}
+}
+
+// handleDeleteOAuthClient удаляет клиента вместе с выданными ему refresh-токенами
+func (h *Handler) handleDeleteOAuthClient(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := h.userUseCase.DeleteOAuthClient(r.Context(), id); err != nil {
+		if errors.Is(err, repo.ErrOAuthClientNotFound) {
+			utils.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+		slog.Error("delete oauth client: usecase failed", "client_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+
+	slog.Info("oauth client deleted", "client_id", id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeOAuthError отвечает ошибкой в формате RFC 6749: {"error": ..., "error_description": ...}
+func writeOAuthError(w http.ResponseWriter, status int, err *usecase.OAuthError) {
+	resp := map[string]string{"error": err.Code, "error_description": err.Description}
+	if err := utils.WriteJSON(w, status, resp); err != nil {
+		slog.Error("write oauth error failed", "error", err)
+	}
+}
+
+func writeConsentError(w http.ResponseWriter, err error) {
+	if errors.Is(err, repo.ErrAuthorizationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	slog.Error("oauth consent: usecase failed", "error", err)
+	utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+}
@@ -0,0 +1,361 @@
+package delivery_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"jwt_auth_project/internal/config"
+	"jwt_auth_project/internal/delivery"
+	"jwt_auth_project/internal/delivery/middleware"
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/password"
+	"jwt_auth_project/internal/repo/repotest"
+	"jwt_auth_project/internal/throttle"
+	"jwt_auth_project/internal/usecase"
+	"jwt_auth_project/internal/webauthn"
+)
+
+const (
+	e2eBaseURL     = "https://app.example.com"
+	e2eRedirectURI = "https://rp.example.com/callback"
+	e2eEmail       = "alice@example.com"
+	e2ePassword    = "correct horse battery"
+)
+
+// oidcServer — API целиком, запущенный на httptest-сервере; issuer совпадает с адресом сервера
+type oidcServer struct {
+	*httptest.Server
+	uc     usecase.UserUseCase
+	client *domain.RegisteredOAuthClient
+	user   domain.User
+}
+
+func newOIDCServer(t *testing.T) *oidcServer {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(nil)
+	issuer := "http://" + srv.Listener.Addr().String()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwtCfg := config.JWTConfig{
+		Keys:            config.KeyRing{Current: config.SigningKey{ID: "e2e", Algorithm: config.AlgES256, Private: priv}},
+		Lifetime:        15 * time.Minute,
+		RefreshLifetime: time.Hour,
+		Issuer:          issuer,
+		Audience:        issuer,
+		Leeway:          30 * time.Second,
+		TokenSources:    []string{config.TokenSourceCookie, config.TokenSourceHeader},
+	}
+	passwordCfg := config.PasswordConfig{Memory: 1024, Time: 1, Threads: 1, MinLength: 8, MaxLength: 128, MinCharClasses: 1}
+	policy, err := password.NewPolicy(passwordCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hasher := password.NewHasher(passwordCfg)
+
+	users := repotest.NewUsers()
+	revocations := repotest.NewRevocations(users)
+	roles := repotest.NewRoles()
+	uc := usecase.NewUserUsecase(
+		users, repotest.NewRefreshTokens(), repotest.NewSessions(), revocations, roles,
+		repotest.NewOneTimeTokens(), repotest.MFA{}, repotest.NewWebAuthn(), repotest.NewOAuth(), nil, nil,
+		webauthn.NewRelyingParty("app.example.com", "Test", []string{e2eBaseURL}),
+		throttle.NewMemoryStore(), hasher, policy, &repotest.Mailer{},
+		jwtCfg, e2eBaseURL, issuer,
+	)
+	roleUC := usecase.NewRoleUsecase(roles, revocations)
+
+	hashed, err := hasher.Hash(e2ePassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified := time.Now().UTC()
+	user := domain.User{ID: uuid.New(), Username: "alice", Email: e2eEmail, Password: hashed, EmailVerifiedAt: &verified, CreatedAt: verified}
+	if err := users.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	client, err := uc.RegisterOAuthClient(context.Background(), uuid.New(), domain.RegisterOAuthClientPayload{
+		Name:         "Relying party",
+		RedirectURIs: []string{e2eRedirectURI},
+		GrantTypes:   []string{domain.GrantAuthorizationCode, domain.GrantRefreshToken},
+		Scopes:       []string{domain.ScopeOpenID, domain.ScopeProfile, domain.ScopeEmail},
+	})
+	if err != nil {
+		t.Fatalf("RegisterOAuthClient: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.Use(middleware.ClientInfo(false))
+	router.Use(middleware.CSRF(config.CSRFConfig{AllowedOrigins: []string{e2eBaseURL}}, jwtCfg.TokenSources))
+	guard := middleware.NewGuard(uc, roleUC, nil, jwtCfg.TokenSources)
+	delivery.NewHandler(uc, e2eBaseURL).RegisterRoutes(router, guard)
+
+	srv.Config.Handler = router
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return &oidcServer{Server: srv, uc: uc, client: client, user: user}
+}
+
+// relyingParty — заглушка клиента OIDC: знает только свои учётные данные и адрес issuer,
+// всё остальное узнаёт из discovery
+type relyingParty struct {
+	t         *testing.T
+	http      *http.Client
+	issuer    string
+	clientID  string
+	secret    string
+	discovery domain.OpenIDConfiguration
+}
+
+func newRelyingParty(t *testing.T, srv *oidcServer) *relyingParty {
+	rp := &relyingParty{
+		t:        t,
+		issuer:   srv.URL,
+		clientID: srv.client.ID,
+		secret:   srv.client.Secret,
+		// Редиректы разбирает сам тест: конечная точка — адрес клиента, а не сервер
+		http: &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }},
+	}
+	rp.getJSON(srv.URL+"/.well-known/openid-configuration", "", &rp.discovery)
+	return rp
+}
+
+func (rp *relyingParty) do(req *http.Request, wantStatus int, out any) *http.Response {
+	rp.t.Helper()
+	resp, err := rp.http.Do(req)
+	if err != nil {
+		rp.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		var body bytes.Buffer
+		body.ReadFrom(resp.Body)
+		rp.t.Fatalf("%s %s: status %d, want %d: %s", req.Method, req.URL.Path, resp.StatusCode, wantStatus, body.String())
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			rp.t.Fatalf("%s %s: decode response: %v", req.Method, req.URL.Path, err)
+		}
+	}
+	return resp
+}
+
+func (rp *relyingParty) getJSON(endpoint, bearer string, out any) {
+	rp.t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, endpoint, nil)
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	rp.do(req, http.StatusOK, out)
+}
+
+// login входит пользователем на сервер и возвращает его access-токен
+func (rp *relyingParty) login() string {
+	rp.t.Helper()
+	body, _ := json.Marshal(domain.LoginUserPayload{Email: e2eEmail, Password: e2ePassword})
+	req, _ := http.NewRequest(http.MethodPost, rp.issuer+"/login?token_delivery=body", bytes.NewReader(body))
+	var pair domain.TokenPair
+	rp.do(req, http.StatusOK, &pair)
+	return pair.AccessToken
+}
+
+// authorize проводит пользователя через /oauth/authorize и экран согласия и возвращает код
+func (rp *relyingParty) authorize(userToken, scope, state, nonce, challenge string) string {
+	rp.t.Helper()
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {rp.clientID},
+		"redirect_uri":          {e2eRedirectURI},
+		"scope":                 {scope},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+	req, _ := http.NewRequest(http.MethodGet, rp.discovery.AuthorizationEndpoint+"?"+query.Encode(), nil)
+	req.Header.Set("Authorization", "Bearer "+userToken)
+	location, err := rp.do(req, http.StatusFound, nil).Location()
+	if err != nil {
+		rp.t.Fatal(err)
+	}
+
+	if strings.HasPrefix(location.String(), e2eBaseURL+"/oauth/consent") {
+		id := location.Query().Get("id")
+		var consent map[string]any
+		rp.getJSON(rp.issuer+"/oauth/consent/"+id, userToken, &consent)
+
+		body, _ := json.Marshal(domain.OAuthConsentPayload{Approve: true})
+		req, _ := http.NewRequest(http.MethodPost, rp.issuer+"/oauth/consent/"+id, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+userToken)
+		var redirect domain.OAuthRedirect
+		rp.do(req, http.StatusOK, &redirect)
+		if location, err = url.Parse(redirect.RedirectTo); err != nil {
+			rp.t.Fatal(err)
+		}
+	}
+
+	if got := location.Scheme + "://" + location.Host + location.Path; got != e2eRedirectURI {
+		rp.t.Fatalf("redirected to %s, want %s", location, e2eRedirectURI)
+	}
+	if got := location.Query().Get("state"); got != state {
+		rp.t.Fatalf("state = %q, want %q", got, state)
+	}
+	code := location.Query().Get("code")
+	if code == "" {
+		rp.t.Fatalf("no code in redirect %s", location)
+	}
+	return code
+}
+
+// exchange меняет код на токены, аутентифицируясь через HTTP Basic
+func (rp *relyingParty) exchange(code, verifier string) domain.OAuthTokenResponse {
+	rp.t.Helper()
+	form := url.Values{
+		"grant_type":    {domain.GrantAuthorizationCode},
+		"code":          {code},
+		"redirect_uri":  {e2eRedirectURI},
+		"code_verifier": {verifier},
+	}
+	req, _ := http.NewRequest(http.MethodPost, rp.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(rp.clientID), url.QueryEscape(rp.secret))
+	var resp domain.OAuthTokenResponse
+	rp.do(req, http.StatusOK, &resp)
+	return resp
+}
+
+// verifyIDToken проверяет подпись ID-токена по JWKS сервера и стандартные claims
+func (rp *relyingParty) verifyIDToken(raw, nonce string) *domain.IDTokenClaims {
+	rp.t.Helper()
+	var set domain.JWKSet
+	rp.getJSON(rp.discovery.JWKSURI, "", &set)
+
+	claims := new(domain.IDTokenClaims)
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (any, error) {
+		for _, key := range set.Keys {
+			if key.Kid == token.Header["kid"] && key.Kty == "EC" && key.Crv == "P-256" {
+				return ecPublicKey(key)
+			}
+		}
+		return nil, fmt.Errorf("unknown kid %v", token.Header["kid"])
+	},
+		jwt.WithValidMethods(rp.discovery.IDTokenSigningAlgValuesSupported),
+		jwt.WithIssuer(rp.issuer),
+		jwt.WithAudience(rp.clientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		rp.t.Fatalf("id_token: %v", err)
+	}
+	if claims.AuthorizedParty != rp.clientID {
+		rp.t.Fatalf("azp = %q, want %q", claims.AuthorizedParty, rp.clientID)
+	}
+	if claims.Nonce != nonce {
+		rp.t.Fatalf("nonce = %q, want %q", claims.Nonce, nonce)
+	}
+	return claims
+}
+
+func ecPublicKey(key domain.JWK) (*ecdsa.PublicKey, error) {
+	x, err := base64.RawURLEncoding.DecodeString(key.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := base64.RawURLEncoding.DecodeString(key.Y)
+	if err != nil {
+		return nil, err
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+}
+
+func pkcePair() (verifier, challenge string) {
+	verifier = base64.RawURLEncoding.EncodeToString([]byte(uuid.NewString()))
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestOIDCAuthorizationCodeFlow(t *testing.T) {
+	srv := newOIDCServer(t)
+	rp := newRelyingParty(t, srv)
+	if rp.discovery.Issuer != srv.URL {
+		t.Fatalf("discovery issuer = %q, want %q", rp.discovery.Issuer, srv.URL)
+	}
+	userToken := rp.login()
+
+	tests := []struct {
+		scope        string
+		wantEmail    bool
+		wantUsername bool
+	}{
+		{scope: "openid"},
+		{scope: "openid email", wantEmail: true},
+		{scope: "openid profile email", wantEmail: true, wantUsername: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			rp.t = t
+			verifier, challenge := pkcePair()
+			nonce := uuid.NewString()
+			code := rp.authorize(userToken, tt.scope, "state-"+nonce, nonce, challenge)
+			tokens := rp.exchange(code, verifier)
+			if tokens.IDToken == "" {
+				t.Fatal("no id_token in token response")
+			}
+
+			claims := rp.verifyIDToken(tokens.IDToken, nonce)
+			if claims.Subject != srv.user.ID.String() {
+				t.Fatalf("sub = %q, want %s", claims.Subject, srv.user.ID)
+			}
+			if tt.wantEmail {
+				if claims.Email != e2eEmail || claims.EmailVerified == nil || !*claims.EmailVerified {
+					t.Fatalf("id_token email = %q verified = %v", claims.Email, claims.EmailVerified)
+				}
+			} else if claims.Email != "" || claims.EmailVerified != nil {
+				t.Fatalf("id_token carries email without the email scope")
+			}
+
+			var info domain.UserInfo
+			rp.getJSON(rp.discovery.UserInfoEndpoint, tokens.AccessToken, &info)
+			if info.Subject != claims.Subject {
+				t.Fatalf("userinfo sub = %q, want %q", info.Subject, claims.Subject)
+			}
+			if got := info.Email != ""; got != tt.wantEmail {
+				t.Fatalf("userinfo email = %q with scope %q", info.Email, tt.scope)
+			}
+			if got := info.EmailVerified != nil && *info.EmailVerified; got != tt.wantEmail {
+				t.Fatalf("userinfo email_verified = %v with scope %q", info.EmailVerified, tt.scope)
+			}
+			if got := info.PreferredUsername != ""; got != tt.wantUsername {
+				t.Fatalf("userinfo preferred_username = %q with scope %q", info.PreferredUsername, tt.scope)
+			}
+
+			// ID-токен предназначен клиенту и не годится как access-токен этого API
+			if _, err := srv.uc.ValidateToken(context.Background(), tokens.IDToken); err == nil {
+				t.Fatal("ValidateToken accepted an id_token")
+			}
+			req, _ := http.NewRequest(http.MethodGet, rp.discovery.UserInfoEndpoint, nil)
+			req.Header.Set("Authorization", "Bearer "+tokens.IDToken)
+			rp.do(req, http.StatusUnauthorized, nil)
+		})
+	}
+}
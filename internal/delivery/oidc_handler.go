@@ -0,0 +1,53 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"jwt_auth_project/internal/usecase"
+	"jwt_auth_project/internal/utils"
+)
+
+// handleOpenIDConfiguration отдаёт discovery-документ OpenID Connect
+func (h *Handler) handleOpenIDConfiguration(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := utils.WriteJSON(w, http.StatusOK, h.userUseCase.OpenIDConfiguration()); err != nil {
+		slog.Error("openid configuration: write response failed", "error", err)
+	}
+}
+
+// handleUserInfo — UserInfo endpoint OpenID Connect. Принимает только access-токен
+// из заголовка Authorization, выданный клиенту со scope openid
+func (h *Handler) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	token := utils.BearerToken(r)
+	if token == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+
+	info, err := h.userUseCase.UserInfo(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInsufficientScope) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+			utils.WriteError(w, http.StatusForbidden, err)
+			return
+		}
+		slog.Warn("userinfo: token rejected", "error", err)
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		if errors.Is(err, usecase.ErrInvalidToken) || errors.Is(err, usecase.ErrTokenRevoked) {
+			utils.WriteError(w, http.StatusUnauthorized, err)
+		} else {
+			utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid token"))
+		}
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, info); err != nil {
+		slog.Error("userinfo: write response failed", "error", err)
+	}
+}
@@ -0,0 +1,156 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/usecase"
+	"jwt_auth_project/internal/utils"
+)
+
+// handleBeginPasskeyRegistration выдаёт параметры регистрации нового passkey
+func (h *Handler) handleBeginPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+
+	options, err := h.userUseCase.BeginPasskeyRegistration(r.Context(), principal.UserID)
+	if err != nil {
+		slog.Error("passkey register begin: usecase failed", "user_id", principal.UserID, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, options); err != nil {
+		slog.Error("passkey register begin: write response failed", "error", err)
+	}
+}
+
+// handleFinishPasskeyRegistration проверяет ответ аутентификатора и сохраняет passkey
+func (h *Handler) handleFinishPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+	var payload domain.WebAuthnRegisterPayload
+	if err := utils.ParceJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cred, err := h.userUseCase.FinishPasskeyRegistration(r.Context(), principal.UserID, payload)
+	if err != nil {
+		slog.Error("passkey register finish: usecase failed", "user_id", principal.UserID, "error", err)
+		writePasskeyError(w, err)
+		return
+	}
+
+	slog.Info("passkey registered", "user_id", principal.UserID, "credential", cred.ID)
+
+	if err := utils.WriteJSON(w, http.StatusCreated, cred); err != nil {
+		slog.Error("passkey register finish: write response failed", "error", err)
+	}
+}
+
+// handleBeginPasskeyLogin выдаёт параметры входа по passkey
+func (h *Handler) handleBeginPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	options, err := h.userUseCase.BeginPasskeyLogin(r.Context())
+	if err != nil {
+		slog.Error("passkey login begin: usecase failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, options); err != nil {
+		slog.Error("passkey login begin: write response failed", "error", err)
+	}
+}
+
+// handleFinishPasskeyLogin проверяет подпись и выдаёт те же токены, что и обычный логин
+func (h *Handler) handleFinishPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	var payload domain.WebAuthnLoginPayload
+	if err := utils.ParceJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	pair, err := h.userUseCase.FinishPasskeyLogin(r.Context(), payload)
+	if err != nil {
+		slog.Error("passkey login finish: usecase failed", "error", err)
+		writePasskeyError(w, err)
+		return
+	}
+
+	slog.Info("user logged in with passkey")
+
+	if err := writeTokens(w, r, pair, false); err != nil {
+		slog.Error("passkey login finish: write response failed", "error", err)
+	}
+}
+
+func (h *Handler) handleListPasskeys(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+
+	creds, err := h.userUseCase.ListPasskeys(r.Context(), principal.UserID)
+	if err != nil {
+		slog.Error("list passkeys: usecase failed", "user_id", principal.UserID, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, creds); err != nil {
+		slog.Error("list passkeys: write response failed", "error", err)
+	}
+}
+
+func (h *Handler) handleDeletePasskey(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	if err := h.userUseCase.DeletePasskey(r.Context(), principal.UserID, id); err != nil {
+		if errors.Is(err, repo.ErrCredentialNotFound) {
+			utils.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+		slog.Error("delete passkey: usecase failed", "user_id", principal.UserID, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writePasskeyError переводит ошибки церемоний WebAuthn в HTTP-статусы.
+// Подробности проверки остаются в логе, клиенту уходит общее сообщение
+func writePasskeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrPasskeyRejected), errors.Is(err, repo.ErrChallengeInvalid):
+		utils.WriteError(w, http.StatusUnauthorized, usecase.ErrPasskeyRejected)
+	case errors.Is(err, repo.ErrCredentialExists):
+		utils.WriteError(w, http.StatusConflict, err)
+	default:
+		utils.WriteError(w, http.StatusBadRequest, err)
+	}
+}
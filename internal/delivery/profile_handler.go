@@ -0,0 +1,174 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/usecase"
+	"jwt_auth_project/internal/utils"
+)
+
+// handleGetMe возвращает профиль текущего пользователя
+func (h *Handler) handleGetMe(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+
+	user, err := h.userUseCase.GetUserByID(r.Context(), principal.UserID)
+	if err != nil {
+		slog.Error("get me: usecase failed", "user_id", principal.UserID, "error", err)
+		if errors.Is(err, repo.ErrUserNotFound) {
+			utils.WriteError(w, http.StatusNotFound, err)
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		}
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, user); err != nil {
+		slog.Error("get me: write response failed", "error", err)
+	}
+}
+
+// handleUpdateMe частично обновляет профиль. Смена почты подтверждается текущим паролем
+// и вступает в силу после перехода по ссылке, отправленной на новый адрес
+func (h *Handler) handleUpdateMe(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+	var payload domain.UpdateProfilePayload
+	if err := utils.ParceJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := h.userUseCase.UpdateProfile(r.Context(), principal.UserID, payload)
+	if err != nil {
+		slog.Error("update me: usecase failed", "user_id", principal.UserID, "error", err)
+		if errors.Is(err, repo.ErrUserExists) {
+			utils.WriteError(w, http.StatusConflict, err)
+		} else {
+			writeConfirmPasswordError(w, err)
+		}
+		return
+	}
+
+	slog.Info("profile updated", "user_id", principal.UserID)
+
+	if err := utils.WriteJSON(w, http.StatusOK, user); err != nil {
+		slog.Error("update me: write response failed", "error", err)
+	}
+}
+
+// handleConfirmEmailChange переносит на аккаунт новую почту по токену из письма
+func (h *Handler) handleConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var payload domain.VerifyEmailPayload
+	if err := utils.ParceJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.userUseCase.ConfirmEmailChange(r.Context(), payload.Token); err != nil {
+		slog.Error("confirm email change: usecase failed", "error", err)
+		switch {
+		case errors.Is(err, usecase.ErrInvalidToken), errors.Is(err, repo.ErrOneTimeTokenInvalid):
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid or expired token"))
+		case errors.Is(err, repo.ErrUserExists):
+			utils.WriteError(w, http.StatusConflict, err)
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		}
+		return
+	}
+
+	slog.Info("email changed")
+
+	if err := utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "email changed"}); err != nil {
+		slog.Error("confirm email change: write response failed", "error", err)
+	}
+}
+
+// handleChangePassword меняет пароль и выдаёт новую пару токенов: остальные сессии завершаются
+func (h *Handler) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+	var payload domain.ChangePasswordPayload
+	if err := utils.ParceJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	pair, err := h.userUseCase.ChangePassword(r.Context(), principal.UserID, payload)
+	if err != nil {
+		slog.Error("change password: usecase failed", "user_id", principal.UserID, "error", err)
+		writeConfirmPasswordError(w, err)
+		return
+	}
+
+	slog.Info("password changed", "user_id", principal.UserID)
+
+	if err := writeTokens(w, r, pair, false); err != nil {
+		slog.Error("change password: write response failed", "error", err)
+	}
+}
+
+// handleDeleteMe удаляет аккаунт текущего пользователя
+func (h *Handler) handleDeleteMe(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+	var payload domain.DeleteAccountPayload
+	if err := utils.ParceJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.userUseCase.DeleteAccount(r.Context(), principal.UserID, payload); err != nil {
+		slog.Error("delete me: usecase failed", "user_id", principal.UserID, "error", err)
+		writeConfirmPasswordError(w, err)
+		return
+	}
+
+	clearTokenCookies(w)
+
+	slog.Info("account deleted", "user_id", principal.UserID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeConfirmPasswordError переводит ошибки операций, подтверждаемых паролем, в HTTP-статусы.
+// Вызывающий уже записал ошибку в лог; всё, что не является ошибкой запроса, отдаётся как 500
+// без подробностей
+func writeConfirmPasswordError(w http.ResponseWriter, err error) {
+	if writeThrottled(w, err) || writePolicyViolations(w, err) {
+		return
+	}
+	var validationErrs validator.ValidationErrors
+	switch {
+	case errors.Is(err, usecase.ErrInvalidCredentials):
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("current password is incorrect"))
+	case errors.As(err, &validationErrs), errors.Is(err, usecase.ErrCurrentPasswordRequired):
+		utils.WriteError(w, http.StatusBadRequest, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+	}
+}
@@ -0,0 +1,43 @@
+package delivery
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/usecase"
+	"jwt_auth_project/internal/utils"
+)
+
+func TestWriteConfirmPasswordError(t *testing.T) {
+	validationErr := utils.Validate.Struct(domain.DeleteAccountPayload{})
+	if validationErr == nil {
+		t.Fatal("empty payload passed validation")
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "validation", err: validationErr, want: http.StatusBadRequest},
+		{name: "current password required", err: usecase.ErrCurrentPasswordRequired, want: http.StatusBadRequest},
+		{name: "wrong password", err: usecase.ErrInvalidCredentials, want: http.StatusForbidden},
+		{name: "storage failure", err: errors.New("pq: connection refused"), want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writeConfirmPasswordError(w, tt.err)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if w.Code == http.StatusInternalServerError && strings.Contains(w.Body.String(), "connection refused") {
+				t.Fatalf("internal error leaked: %s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/utils"
+)
+
+// handleListSessions возвращает устройства, на которых пользователь сейчас залогинен
+func (h *Handler) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+
+	sessions, err := h.userUseCase.ListSessions(r.Context(), principal.UserID, principal.SessionID)
+	if err != nil {
+		slog.Error("list sessions: usecase failed", "user_id", principal.UserID, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, sessions); err != nil {
+		slog.Error("list sessions: write response failed", "error", err)
+	}
+}
+
+// handleRevokeSession завершает сессию на выбранном устройстве. Завершение текущей сессии
+// равносильно выходу, поэтому cookie тоже удаляются
+func (h *Handler) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	if err := h.userUseCase.RevokeSession(r.Context(), principal.UserID, id); err != nil {
+		if errors.Is(err, repo.ErrSessionNotFound) {
+			utils.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+		slog.Error("revoke session: usecase failed", "user_id", principal.UserID, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+
+	if id == principal.SessionID {
+		clearTokenCookies(w)
+	}
+
+	slog.Info("session revoked", "user_id", principal.UserID, "session_id", id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,204 @@
+package delivery
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"jwt_auth_project/internal/oidc"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/usecase"
+	"jwt_auth_project/internal/utils"
+)
+
+const (
+	// socialStateCookieName — cookie, связывающая возврат с провайдера с браузером, начавшим вход
+	socialStateCookieName = "social_state"
+	socialStatePath       = "/login/social"
+)
+
+// handleBeginSocialLogin отправляет браузер на страницу входа провайдера
+func (h *Handler) handleBeginSocialLogin(w http.ResponseWriter, r *http.Request) {
+	h.beginSocialLogin(w, r, nil)
+}
+
+// handleBeginSocialLink начинает привязку провайдера к текущему пользователю
+func (h *Handler) handleBeginSocialLink(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+	h.beginSocialLogin(w, r, &principal.UserID)
+}
+
+func (h *Handler) beginSocialLogin(w http.ResponseWriter, r *http.Request, linkUserID *uuid.UUID) {
+	provider := mux.Vars(r)["provider"]
+
+	start, err := h.userUseCase.BeginSocialLogin(r.Context(), provider, linkUserID)
+	if err != nil {
+		if errors.Is(err, usecase.ErrUnknownProvider) {
+			utils.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+		slog.Error("social login: begin failed", "provider", provider, "error", err)
+		if errors.Is(err, oidc.ErrProvider) {
+			utils.WriteError(w, http.StatusBadGateway, fmt.Errorf("provider is unavailable"))
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+
+	// Возврат с провайдера — переход с другого сайта, поэтому Lax, а не Strict
+	http.SetCookie(w, &http.Cookie{
+		Name:     socialStateCookieName,
+		Value:    start.State,
+		Path:     socialStatePath,
+		Expires:  time.Now().Add(10 * time.Minute),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, start.RedirectTo, http.StatusFound)
+}
+
+// handleSocialCallback — адрес возврата с провайдера. State из query должен совпасть с cookie,
+// иначе чужой код мог бы войти в браузер жертвы под аккаунтом злоумышленника. Итог входа или ошибка
+// передаются фронтенду редиректом
+func (h *Handler) handleSocialCallback(w http.ResponseWriter, r *http.Request) {
+	provider := mux.Vars(r)["provider"]
+	query := r.URL.Query()
+
+	cookie, err := r.Cookie(socialStateCookieName)
+	http.SetCookie(w, &http.Cookie{
+		Name:     socialStateCookieName,
+		Value:    "",
+		Path:     socialStatePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	if providerErr := query.Get("error"); providerErr != "" {
+		slog.Info("social login: provider returned error", "provider", provider, "error", providerErr)
+		h.redirectToApp(w, r, "/login", url.Values{"error": {"provider_declined"}})
+		return
+	}
+	state, code := query.Get("state"), query.Get("code")
+	if err != nil || state == "" || code == "" ||
+		subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		h.redirectToApp(w, r, "/login", url.Values{"error": {"invalid_state"}})
+		return
+	}
+
+	result, err := h.userUseCase.SocialCallback(r.Context(), provider, code, state)
+	if err != nil {
+		slog.Error("social login: callback failed", "provider", provider, "error", err)
+		var code string
+		switch {
+		case errors.Is(err, usecase.ErrUnknownProvider):
+			code = "unknown_provider"
+		case errors.Is(err, repo.ErrSocialStateInvalid):
+			code = "invalid_state"
+		case errors.Is(err, usecase.ErrSocialEmailConflict), errors.Is(err, repo.ErrIdentityExists):
+			code = "email_conflict"
+		case errors.Is(err, usecase.ErrSocialEmailRequired):
+			code = "email_required"
+		case errors.Is(err, oidc.ErrInvalidIDToken), errors.Is(err, oidc.ErrProvider):
+			code = "provider_error"
+		default:
+			code = "server_error"
+		}
+		h.redirectToApp(w, r, "/login", url.Values{"error": {code}})
+		return
+	}
+
+	switch {
+	case result.Linked:
+		h.redirectToApp(w, r, "", url.Values{"linked": {provider}})
+	case result.LinkConfirmationRequired:
+		slog.Info("social login: link confirmation sent", "provider", provider)
+		h.redirectToApp(w, r, "/login", url.Values{"link_confirmation_required": {"true"}})
+	default:
+		slog.Info("user logged in via social login", "provider", provider)
+		h.redirectLoginResult(w, r, &result.LoginResult)
+	}
+}
+
+// handleConfirmSocialLink — адрес ссылки из письма о привязке. Подтверждает привязку, выполняет вход
+// и возвращает на фронтенд, как handleMagicLinkCallback
+func (h *Handler) handleConfirmSocialLink(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		h.redirectToApp(w, r, "/login", url.Values{"error": {"invalid_link"}})
+		return
+	}
+
+	result, err := h.userUseCase.ConfirmSocialLink(r.Context(), token)
+	if err != nil {
+		slog.Error("social link confirm: usecase failed", "error", err)
+		code := "server_error"
+		if errors.Is(err, usecase.ErrInvalidToken) || errors.Is(err, repo.ErrOneTimeTokenInvalid) {
+			code = "invalid_link"
+		}
+		h.redirectToApp(w, r, "/login", url.Values{"error": {code}})
+		return
+	}
+	h.redirectLoginResult(w, r, result)
+}
+
+// handleListIdentities возвращает привязанные аккаунты провайдеров
+func (h *Handler) handleListIdentities(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+
+	identities, err := h.userUseCase.ListIdentities(r.Context(), principal.UserID)
+	if err != nil {
+		slog.Error("list identities: usecase failed", "user_id", principal.UserID, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, identities); err != nil {
+		slog.Error("list identities: write response failed", "error", err)
+	}
+}
+
+// handleUnlinkIdentity отвязывает аккаунт провайдера
+func (h *Handler) handleUnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid id"))
+		return
+	}
+
+	if err := h.userUseCase.UnlinkIdentity(r.Context(), principal.UserID, id); err != nil {
+		if errors.Is(err, repo.ErrIdentityNotFound) {
+			utils.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+		slog.Error("unlink identity: usecase failed", "user_id", principal.UserID, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+
+	slog.Info("social identity unlinked", "user_id", principal.UserID, "identity_id", id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
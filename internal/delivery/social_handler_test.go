@@ -0,0 +1,65 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/usecase"
+)
+
+func (uc linkLoginUseCase) ConfirmSocialLink(context.Context, string) (*domain.LoginResult, error) {
+	return uc.result, uc.err
+}
+
+func (uc linkLoginUseCase) SocialCallback(context.Context, string, string, string) (*domain.SocialLoginResult, error) {
+	if uc.err != nil {
+		return nil, uc.err
+	}
+	return &domain.SocialLoginResult{LoginResult: *uc.result}, nil
+}
+
+func TestConfirmSocialLinkRedirectsToApp(t *testing.T) {
+	cases := append([]linkLoginCase{{name: "no token", wantLocation: testBaseURL + "/login#error=invalid_link"}}, linkLoginCases...)
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(linkLoginUseCase{result: tt.result, err: tt.err}, testBaseURL)
+			w := httptest.NewRecorder()
+			h.handleConfirmSocialLink(w, httptest.NewRequest(http.MethodGet, "/login/social/link/confirm"+tt.query, nil))
+			checkLinkLoginRedirect(t, w, tt.wantLocation, tt.wantCookies)
+		})
+	}
+}
+
+func TestSocialCallbackRedirectsToApp(t *testing.T) {
+	const query = "?code=c&state=t"
+	tokens, mfa := linkLoginCases[0], linkLoginCases[1]
+	tokens.query, mfa.query = query, query
+	cases := []linkLoginCase{
+		tokens,
+		mfa,
+		{name: "state mismatch", query: "?code=c&state=other", wantLocation: testBaseURL + "/login#error=invalid_state"},
+		{name: "provider declined", query: "?error=access_denied&state=t", wantLocation: testBaseURL + "/login#error=provider_declined"},
+		{name: "state expired", query: query, err: repo.ErrSocialStateInvalid, wantLocation: testBaseURL + "/login#error=invalid_state"},
+		{name: "email conflict", query: query, err: usecase.ErrSocialEmailConflict, wantLocation: testBaseURL + "/login#error=email_conflict"},
+		{name: "server error", query: query, err: errors.New("db down"), wantLocation: testBaseURL + "/login#error=server_error"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(linkLoginUseCase{result: tt.result, err: tt.err}, testBaseURL)
+			r := httptest.NewRequest(http.MethodGet, "/login/social/mock/callback"+tt.query, nil)
+			r.AddCookie(&http.Cookie{Name: socialStateCookieName, Value: "t"})
+			r = mux.SetURLVars(r, map[string]string{"provider": "mock"})
+
+			w := httptest.NewRecorder()
+			h.handleSocialCallback(w, r)
+			checkLinkLoginRedirect(t, w, tt.wantLocation, tt.wantCookies)
+		})
+	}
+}
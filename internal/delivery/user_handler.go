@@ -1,30 +1,91 @@
 package delivery
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 
+	"jwt_auth_project/internal/delivery/middleware"
 	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/password"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/throttle"
 	"jwt_auth_project/internal/usecase"
 	"jwt_auth_project/internal/utils"
 )
 
 type Handler struct {
 	userUseCase usecase.UserUseCase
+	// baseURL — адрес фронтенда, куда возвращается браузер после входа по ссылке
+	baseURL string
 }
 
-func NewHandler(userUseCase usecase.UserUseCase) *Handler {
-	return &Handler{userUseCase: userUseCase}
+func NewHandler(userUseCase usecase.UserUseCase, baseURL string) *Handler {
+	return &Handler{userUseCase: userUseCase, baseURL: strings.TrimRight(baseURL, "/")}
 }
 
-func (h *Handler) RegisterRoutes(router *mux.Router) {
-	router.HandleFunc("/register", h.handleRegister).Methods(http.MethodPost)
-	router.HandleFunc("/login", h.handleLogin).Methods(http.MethodPost)
-	router.HandleFunc("/logout", h.handleLogout).Methods(http.MethodPost)
+func (h *Handler) RegisterRoutes(router *mux.Router, guard *middleware.Guard) {
+	public := guard.Subrouter(router, middleware.Public)
+	public.HandleFunc("/register", h.handleRegister).Methods(http.MethodPost)
+	public.HandleFunc("/login", h.handleLogin).Methods(http.MethodPost)
+	public.HandleFunc("/login/mfa", h.handleLoginMFA).Methods(http.MethodPost)
+	public.HandleFunc("/login/magic-link", h.handleRequestMagicLink).Methods(http.MethodPost)
+	public.HandleFunc("/login/magic-link/callback", h.handleMagicLinkCallback).Methods(http.MethodGet)
+	public.HandleFunc("/login/social/link/confirm", h.handleConfirmSocialLink).Methods(http.MethodGet)
+	public.HandleFunc("/login/social/{provider}", h.handleBeginSocialLogin).Methods(http.MethodGet)
+	public.HandleFunc("/login/social/{provider}/callback", h.handleSocialCallback).Methods(http.MethodGet)
+	public.HandleFunc("/webauthn/login/begin", h.handleBeginPasskeyLogin).Methods(http.MethodPost)
+	public.HandleFunc("/webauthn/login/finish", h.handleFinishPasskeyLogin).Methods(http.MethodPost)
+	public.HandleFunc("/logout", h.handleLogout).Methods(http.MethodPost)
+	public.HandleFunc("/token/refresh", h.handleRefresh).Methods(http.MethodPost)
+	public.HandleFunc("/csrf", h.handleCSRFToken).Methods(http.MethodGet)
+	public.HandleFunc("/.well-known/jwks.json", h.handleJWKS).Methods(http.MethodGet)
+	public.HandleFunc("/.well-known/openid-configuration", h.handleOpenIDConfiguration).Methods(http.MethodGet)
+	public.HandleFunc("/userinfo", h.handleUserInfo).Methods(http.MethodGet, http.MethodPost)
+	public.HandleFunc("/verify-email", h.handleVerifyEmail).Methods(http.MethodPost)
+	public.HandleFunc("/verify-email/change", h.handleConfirmEmailChange).Methods(http.MethodPost)
+	public.HandleFunc("/password/forgot", h.handleForgotPassword).Methods(http.MethodPost)
+	public.HandleFunc("/password/reset", h.handleResetPassword).Methods(http.MethodPost)
+	public.HandleFunc("/oauth/authorize", h.handleAuthorize).Methods(http.MethodGet)
+	public.HandleFunc("/oauth/token", h.handleToken).Methods(http.MethodPost)
+	public.HandleFunc("/oauth/introspect", h.handleIntrospect).Methods(http.MethodPost)
+	public.HandleFunc("/oauth/revoke", h.handleRevoke).Methods(http.MethodPost)
+
+	private := guard.Subrouter(router, middleware.Authenticated)
+	private.HandleFunc("/me", h.handleGetMe).Methods(http.MethodGet)
+	private.HandleFunc("/me", h.handleUpdateMe).Methods(http.MethodPatch)
+	private.HandleFunc("/me", h.handleDeleteMe).Methods(http.MethodDelete)
+	private.HandleFunc("/me/password", h.handleChangePassword).Methods(http.MethodPost)
+	private.HandleFunc("/me/sessions", h.handleListSessions).Methods(http.MethodGet)
+	private.HandleFunc("/me/sessions/{id}", h.handleRevokeSession).Methods(http.MethodDelete)
+	private.HandleFunc("/me/identities", h.handleListIdentities).Methods(http.MethodGet)
+	private.HandleFunc("/me/identities/{id}", h.handleUnlinkIdentity).Methods(http.MethodDelete)
+	private.HandleFunc("/me/identities/{provider}/link", h.handleBeginSocialLink).Methods(http.MethodGet)
+	private.HandleFunc("/logout/all", h.handleLogoutAll).Methods(http.MethodPost)
+	private.HandleFunc("/verify-email/resend", h.handleResendVerification).Methods(http.MethodPost)
+	private.HandleFunc("/mfa/totp", h.handleEnrollTOTP).Methods(http.MethodPost)
+	private.HandleFunc("/mfa/totp/confirm", h.handleConfirmTOTP).Methods(http.MethodPost)
+	private.HandleFunc("/mfa/totp", h.handleDisableTOTP).Methods(http.MethodDelete)
+	private.HandleFunc("/webauthn/register/begin", h.handleBeginPasskeyRegistration).Methods(http.MethodPost)
+	private.HandleFunc("/webauthn/register/finish", h.handleFinishPasskeyRegistration).Methods(http.MethodPost)
+	private.HandleFunc("/webauthn/credentials", h.handleListPasskeys).Methods(http.MethodGet)
+	private.HandleFunc("/webauthn/credentials/{id}", h.handleDeletePasskey).Methods(http.MethodDelete)
+	private.HandleFunc("/oauth/consent/{id}", h.handleGetConsent).Methods(http.MethodGet)
+	private.HandleFunc("/oauth/consent/{id}", h.handleDecideConsent).Methods(http.MethodPost)
+
+	clients := guard.Subrouter(router.PathPrefix("/oauth/clients").Subrouter(), middleware.PermissionRestricted(domain.PermClientsManage))
+	clients.HandleFunc("", h.handleListOAuthClients).Methods(http.MethodGet)
+	clients.HandleFunc("", h.handleRegisterOAuthClient).Methods(http.MethodPost)
+	clients.HandleFunc("/{id}", h.handleDeleteOAuthClient).Methods(http.MethodDelete)
 }
 
 func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
@@ -35,28 +96,28 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, token, err := h.userUseCase.Register(r.Context(), payload)
+	user, pair, err := h.userUseCase.Register(r.Context(), payload)
 	if err != nil {
 		slog.Error("register: usecase failed", "email", payload.Email, "error", err)
+		if writePolicyViolations(w, err) {
+			return
+		}
 		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "jwt",
-		Value:    token,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteStrictMode,
-	})
-
 	slog.Info("user registered", "email", user.Email)
 
 	resp := map[string]any{
-		"username":   user.Username,
-		"email":      user.Email,
-		"created_at": user.CreatedAt,
+		"username":       user.Username,
+		"email":          user.Email,
+		"email_verified": false,
+		"created_at":     user.CreatedAt,
+	}
+	if wantsTokensInBody(r) {
+		resp["tokens"] = pair
+	} else {
+		resp["csrf_token"] = setTokenCookies(w, r, pair)
 	}
 	if err := utils.WriteJSON(w, http.StatusCreated, resp); err != nil {
 		slog.Error("register: write response failed", "error", err)
@@ -71,45 +132,347 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.userUseCase.Login(r.Context(), payload)
+	result, err := h.userUseCase.Login(r.Context(), payload)
 	if err != nil {
 		slog.Error("login: usecase failed", "email", payload.Email, "error", err)
+		if writeThrottled(w, err) {
+			return
+		}
 		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid credentials"))
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "jwt",
-		Value:    token,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteStrictMode,
-	})
-
-	slog.Info("user logged in", "email", payload.Email)
+	if result.MFAToken != "" {
+		slog.Info("login: second factor required", "email", payload.Email)
+	} else {
+		slog.Info("user logged in", "email", payload.Email)
+	}
 
-	// Можно вернуть простое сообщение
-	if err := utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "ok"}); err != nil {
+	if err := writeLoginResult(w, r, result); err != nil {
 		slog.Error("login: write response failed", "error", err)
 	}
 }
 
+// handleRefresh обменивает refresh-токен на новую пару. Токен берётся из cookie,
+// а если её нет — из JSON-тела; в этом случае новая пара тоже возвращается в теле
+func (h *Handler) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	var refreshToken string
+	fromBody := false
+	if cookie, err := r.Cookie(refreshCookieName); err == nil && cookie.Value != "" {
+		refreshToken = cookie.Value
+	} else {
+		var payload domain.RefreshTokenPayload
+		if err := utils.ParceJSON(r, &payload); err != nil {
+			slog.Error("refresh: invalid JSON", "error", err)
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := utils.Validate.Struct(payload); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		refreshToken = payload.RefreshToken
+		fromBody = true
+	}
+
+	pair, err := h.userUseCase.Refresh(r.Context(), refreshToken)
+	if err != nil {
+		slog.Error("refresh: usecase failed", "error", err)
+		if errors.Is(err, usecase.ErrInvalidRefreshToken) || errors.Is(err, usecase.ErrRefreshTokenReused) {
+			clearTokenCookies(w)
+			utils.WriteError(w, http.StatusUnauthorized, err)
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		}
+		return
+	}
+
+	if err := writeTokens(w, r, pair, fromBody); err != nil {
+		slog.Error("refresh: write response failed", "error", err)
+	}
+}
+
+// handleLogout отзывает текущие access и refresh токены и удаляет cookie.
+// Клиенты без cookie передают access-токен в Authorization, а refresh-токен — в JSON-теле
 func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	var accessToken, refreshToken string
+	if cookie, err := r.Cookie(accessCookieName); err == nil {
+		accessToken = cookie.Value
+	} else {
+		accessToken = utils.BearerToken(r)
+	}
+	if cookie, err := r.Cookie(refreshCookieName); err == nil {
+		refreshToken = cookie.Value
+	} else {
+		// Тело необязательно, поэтому ошибку разбора игнорируем
+		var payload domain.RefreshTokenPayload
+		_ = utils.ParceJSON(r, &payload)
+		refreshToken = payload.RefreshToken
+	}
+	if err := h.userUseCase.Logout(r.Context(), accessToken, refreshToken); err != nil {
+		slog.Error("logout: revoke tokens failed", "error", err)
+	}
+
+	clearTokenCookies(w)
+
+	slog.Info("user logged out")
+
+	if err := utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "logged out"}); err != nil {
+		slog.Error("logout: write response failed", "error", err)
+	}
+}
+
+// handleLogoutAll завершает все сессии текущего пользователя на всех устройствах
+func (h *Handler) handleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.ContextKeyUserID).(uuid.UUID)
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+
+	if err := h.userUseCase.LogoutAll(r.Context(), userID); err != nil {
+		slog.Error("logout all: usecase failed", "user_id", userID, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		return
+	}
+
+	clearTokenCookies(w)
+
+	slog.Info("user logged out everywhere", "user_id", userID)
+
+	if err := utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "logged out everywhere"}); err != nil {
+		slog.Error("logout all: write response failed", "error", err)
+	}
+}
+
+// handleVerifyEmail подтверждает почту по токену из письма.
+// Новый признак попадёт в access-токен при следующем /token/refresh
+func (h *Handler) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var payload domain.VerifyEmailPayload
+	if err := utils.ParceJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.userUseCase.VerifyEmail(r.Context(), payload.Token); err != nil {
+		slog.Error("verify email: usecase failed", "error", err)
+		if errors.Is(err, usecase.ErrInvalidToken) || errors.Is(err, repo.ErrOneTimeTokenInvalid) {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid or expired token"))
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		}
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "email verified"}); err != nil {
+		slog.Error("verify email: write response failed", "error", err)
+	}
+}
+
+// handleResendVerification повторно отправляет письмо подтверждения текущему пользователю
+func (h *Handler) handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	principal, ok := utils.PrincipalFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+
+	if err := h.userUseCase.SendVerificationEmail(r.Context(), principal.UserID); err != nil {
+		slog.Error("resend verification: usecase failed", "user_id", principal.UserID, "error", err)
+		if errors.Is(err, usecase.ErrEmailAlreadyVerified) {
+			utils.WriteError(w, http.StatusConflict, err)
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("server error"))
+		}
+		return
+	}
+
+	if err := utils.WriteJSON(w, http.StatusAccepted, map[string]string{"message": "verification email sent"}); err != nil {
+		slog.Error("resend verification: write response failed", "error", err)
+	}
+}
+
+// handleForgotPassword отправляет ссылку сброса пароля. Ответ одинаков для любых адресов
+func (h *Handler) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var payload domain.ForgotPasswordPayload
+	if err := utils.ParceJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.userUseCase.ForgotPassword(r.Context(), payload); err != nil {
+		if writeThrottled(w, err) {
+			return
+		}
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	resp := map[string]string{"message": "if the account exists, a reset link has been sent"}
+	if err := utils.WriteJSON(w, http.StatusAccepted, resp); err != nil {
+		slog.Error("forgot password: write response failed", "error", err)
+	}
+}
+
+// handleResetPassword устанавливает новый пароль по токену из письма
+func (h *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var payload domain.ResetPasswordPayload
+	if err := utils.ParceJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.userUseCase.ResetPassword(r.Context(), payload); err != nil {
+		slog.Error("reset password: usecase failed", "error", err)
+		if writePolicyViolations(w, err) {
+			return
+		}
+		if errors.Is(err, repo.ErrOneTimeTokenInvalid) {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid or expired token"))
+		} else {
+			utils.WriteError(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	clearTokenCookies(w)
+
+	slog.Info("password reset")
+
+	if err := utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "password changed"}); err != nil {
+		slog.Error("reset password: write response failed", "error", err)
+	}
+}
+
+// handleJWKS публикует открытые ключи, которыми другие сервисы проверяют наши токены
+func (h *Handler) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := utils.WriteJSON(w, http.StatusOK, h.userUseCase.JWKS()); err != nil {
+		slog.Error("jwks: write response failed", "error", err)
+	}
+}
+
+// writeThrottled отвечает 429 с Retry-After, если err — блокировка после серии неудачных попыток
+func writeThrottled(w http.ResponseWriter, err error) bool {
+	var throttled *throttle.ThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	utils.WriteError(w, http.StatusTooManyRequests, throttled)
+	return true
+}
+
+// writePolicyViolations отвечает 422 со списком нарушенных правил, если пароль не прошёл политику
+func writePolicyViolations(w http.ResponseWriter, err error) bool {
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	resp := map[string]any{
+		"error":      "password does not meet policy",
+		"violations": policyErr.Violations,
+	}
+	if err := utils.WriteJSON(w, http.StatusUnprocessableEntity, resp); err != nil {
+		slog.Error("write policy violations failed", "error", err)
+	}
+	return true
+}
+
+const (
+	accessCookieName  = "jwt"
+	refreshCookieName = "refresh_token"
+)
+
+// wantsTokensInBody сообщает, что клиент (мобильное приложение, скрипт) просит
+// вернуть токены в JSON-теле вместо cookie: ?token_delivery=body
+func wantsTokensInBody(r *http.Request) bool {
+	return r.URL.Query().Get("token_delivery") == "body"
+}
+
+// writeLoginResult отдаёт итог первого шага входа: токены либо, если нужен второй фактор,
+// mfa_token, который обменивается на токены на /login/mfa
+func writeLoginResult(w http.ResponseWriter, r *http.Request, result *domain.LoginResult) error {
+	if result.MFAToken != "" {
+		return utils.WriteJSON(w, http.StatusOK, map[string]any{"mfa_required": true, "mfa_token": result.MFAToken})
+	}
+	return writeTokens(w, r, result.Tokens, false)
+}
+
+// redirectLoginResult завершает вход, начатый переходом браузера по ссылке из письма или с
+// провайдера: выставляет cookie и возвращает на фронтенд. Если нужен второй фактор, mfa_token
+// отдаётся странице входа для обмена на /login/mfa
+func (h *Handler) redirectLoginResult(w http.ResponseWriter, r *http.Request, result *domain.LoginResult) {
+	if result.MFAToken != "" {
+		h.redirectToApp(w, r, "/login", url.Values{"mfa_token": {result.MFAToken}})
+		return
+	}
+	setTokenCookies(w, r, result.Tokens)
+	h.redirectToApp(w, r, "", nil)
+}
+
+// redirectToApp отправляет браузер на страницу фронтенда path. Параметры передаются во фрагменте:
+// он не уходит на сервер, поэтому не попадает в логи и Referer
+func (h *Handler) redirectToApp(w http.ResponseWriter, r *http.Request, path string, params url.Values) {
+	target := h.baseURL + path
+	if len(params) > 0 {
+		target += "#" + params.Encode()
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// writeTokens отдаёт пару токенов в cookie либо в JSON-теле, если его запросил клиент или forceBody
+func writeTokens(w http.ResponseWriter, r *http.Request, pair *domain.TokenPair, forceBody bool) error {
+	if forceBody || wantsTokensInBody(r) {
+		return utils.WriteJSON(w, http.StatusOK, pair)
+	}
+	csrfToken := setTokenCookies(w, r, pair)
+	return utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "ok", "csrf_token": csrfToken})
+}
+
+// setTokenCookies выставляет cookie с access и refresh токенами и возвращает CSRF-токен,
+// который клиент должен повторять в X-CSRF-Token на изменяющих запросах
+func setTokenCookies(w http.ResponseWriter, r *http.Request, pair *domain.TokenPair) string {
 	http.SetCookie(w, &http.Cookie{
-		Name:     "jwt",
-		Value:    "",
+		Name:     accessCookieName,
+		Value:    pair.AccessToken,
 		Path:     "/",
-		Expires:  time.Unix(0, 0),
-		MaxAge:   -1,
+		Expires:  pair.AccessExpiresAt,
 		HttpOnly: true,
 		Secure:   true,
 		SameSite: http.SameSiteStrictMode,
 	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    pair.RefreshToken,
+		Path:     "/",
+		Expires:  pair.RefreshExpiresAt,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return setCSRFCookie(w, r, pair.RefreshExpiresAt)
+}
 
-	slog.Info("user logged out")
-
-	if err := utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "logged out"}); err != nil {
-		slog.Error("logout: write response failed", "error", err)
+// clearTokenCookies удаляет cookie с токенами и CSRF-токеном
+func clearTokenCookies(w http.ResponseWriter) {
+	clearCSRFCookie(w)
+	for _, name := range []string{accessCookieName, refreshCookieName} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			Expires:  time.Unix(0, 0),
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   true,
+			SameSite: http.SameSiteStrictMode,
+		})
 	}
 }
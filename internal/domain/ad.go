@@ -27,7 +27,6 @@ type AdListOptions struct {
 }
 
 type CreateAdPayload struct {
-	AuthorID    uuid.UUID `json:"author_id" validate:"required"`
 	Title       string    `json:"title"       validate:"required,min=3,max=100"`
 	Description string    `json:"description" validate:"required,min=10,max=1000"`
 	Price       float64   `json:"price"       validate:"required,gte=0"`
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// APIKey — персональный ключ для скриптов и интеграций. Сам ключ не хранится, только его хеш;
+// Prefix — открытая часть ключа, по которой он находится в базе и узнаётся в списке.
+// Scopes — права, которые ключ даёт, не больше прав владельца
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"-"`
+}
+
+// CreatedAPIKey — только что выпущенный ключ. Key показывается один раз
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type CreateAPIKeyPayload struct {
+	Name          string   `json:"name"            validate:"required,max=64"`
+	Scopes        []string `json:"scopes"          validate:"required,min=1,dive,required"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"required,min=1,max=365"`
+}
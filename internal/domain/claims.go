@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Claims — содержимое access JWT. ID (jti) позволяет отозвать конкретный токен,
+// Issuer и Audience — сервис, выпустивший токен, и сервис, для которого он предназначен,
+// Username и Roles — имя и роли пользователя на момент выпуска, по ролям ограничивается доступ к маршрутам,
+// Generation — поколение токенов пользователя на момент выпуска ("выйти везде" увеличивает поколение),
+// SessionID (sid) — сессия, отзыв которой делает токен недействительным.
+// ClientID и Scope — OAuth-клиент, которому выдан токен, и выданные ему scopes через пробел.
+// Purpose заполнен только у одноразовых токенов (подтверждение почты и т.п.), доступа они не дают
+type Claims struct {
+	jwt.RegisteredClaims
+	Generation    int      `json:"gen"`
+	SessionID     string   `json:"sid,omitempty"`
+	ClientID      string   `json:"client_id,omitempty"`
+	Scope         string   `json:"scope,omitempty"`
+	Username      string   `json:"username,omitempty"`
+	Roles         []string `json:"roles,omitempty"`
+	Email         string   `json:"email,omitempty"`
+	EmailVerified bool     `json:"email_verified,omitempty"`
+	Purpose       string   `json:"purpose,omitempty"`
+}
+
+// UserID возвращает идентификатор пользователя из Subject
+func (c *Claims) UserID() (uuid.UUID, error) {
+	return uuid.Parse(c.Subject)
+}
+
+// TokenID возвращает jti токена
+func (c *Claims) TokenID() (uuid.UUID, error) {
+	return uuid.Parse(c.ID)
+}
+
+// Scopes возвращает scopes OAuth-токена списком
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// ClientToken сообщает, что токен выдан по client_credentials самому клиенту:
+// Subject — client_id, пользователя и сессии за токеном нет
+func (c *Claims) ClientToken() bool {
+	return c.ClientID != "" && c.Subject == c.ClientID
+}
+
+// Session возвращает идентификатор сессии. У токенов, выпущенных до появления сессий, его нет
+func (c *Claims) Session() (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.SessionID)
+	return id, err == nil
+}
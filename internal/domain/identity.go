@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// UserIdentity — аккаунт внешнего провайдера, привязанный к пользователю. Пока LinkedAt пуст,
+// привязка ждёт подтверждения по ссылке из письма и для входа не используется
+type UserIdentity struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"-"`
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"-"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LinkedAt    *time.Time `json:"-"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// SocialLoginState — начатый вход через провайдера. Ищется по хешу state; при привязке
+// провайдера к уже вошедшему пользователю UserID указывает на него
+type SocialLoginState struct {
+	StateHash    string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	UserID       *uuid.UUID
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+// SocialLoginStart — адрес страницы провайдера и state, который клиент должен сохранить
+// до возврата на callback
+type SocialLoginStart struct {
+	RedirectTo string
+	State      string
+}
+
+// SocialLoginResult — итог callback'а: либо обычный результат входа, либо сообщение о том,
+// что привязка к существующему аккаунту ждёт подтверждения по почте
+type SocialLoginResult struct {
+	LoginResult
+	LinkConfirmationRequired bool
+	Linked                   bool
+}
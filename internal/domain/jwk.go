@@ -0,0 +1,21 @@
+package domain
+
+// JWK — открытый ключ в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC и OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet — набор ключей, отдаваемый на /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// MFA — настройки TOTP пользователя. Пока EnabledAt пуст, секрет выдан, но не подтверждён
+type MFA struct {
+	UserID       uuid.UUID
+	TOTPSecret   string
+	EnabledAt    *time.Time
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+// TOTPEnrollment — данные для добавления аккаунта в приложение-аутентификатор
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// LoginResult — итог логина: либо пара токенов, либо, при включённой 2FA,
+// короткоживущий токен, который обменивается на пару на /login/mfa
+type LoginResult struct {
+	Tokens   *TokenPair
+	MFAToken string
+}
+
+type TOTPCodePayload struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type LoginMFAPayload struct {
+	MFAToken     string `json:"mfa_token"     validate:"required"`
+	Code         string `json:"code"          validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" validate:"required_without=Code"`
+}
+
+// DisableMFAPayload — отключение 2FA требует действующий код или код восстановления
+type DisableMFAPayload struct {
+	Code         string `json:"code"          validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" validate:"required_without=Code"`
+}
@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Типы grant, поддерживаемые /oauth/token
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+)
+
+// OAuthClient — зарегистрированное приложение. У публичных клиентов (SPA, мобильные) нет секрета,
+// они подтверждают себя только через PKCE. Scopes — всё, что клиент вправе запросить
+type OAuthClient struct {
+	ID           string   `json:"client_id"`
+	SecretHash   *string  `json:"-"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	GrantTypes   []string `json:"grant_types"`
+	Scopes       []string `json:"scopes"`
+	// Introspect разрешает проверять на /oauth/introspect чужие токены. Без него клиент
+	// видит только выданные ему самому
+	Introspect bool       `json:"introspect"`
+	CreatedBy  *uuid.UUID `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Confidential сообщает, есть ли у клиента секрет
+func (c *OAuthClient) Confidential() bool {
+	return c.SecretHash != nil
+}
+
+// RegisteredOAuthClient — только что зарегистрированный клиент. Secret показывается один раз
+type RegisteredOAuthClient struct {
+	OAuthClient
+	Secret string `json:"client_secret,omitempty"`
+}
+
+// OAuthAuthorization — запрос авторизации от /oauth/authorize. Пока пользователь не дал согласие,
+// кода нет; после согласия появляется CodeHash, после обмена кода — UsedAt и FamilyID выданных токенов.
+// RedirectURIExplicit — redirect_uri пришёл в запросе, а не подставлен единственный зарегистрированный:
+// только тогда /oauth/token обязан получить его же (RFC 6749, раздел 4.1.3)
+type OAuthAuthorization struct {
+	ID                  uuid.UUID
+	ClientID            string
+	UserID              uuid.UUID
+	RedirectURI         string
+	RedirectURIExplicit bool
+	Scopes              []string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeHash            *string
+	FamilyID            *uuid.UUID
+	ExpiresAt           time.Time
+	ApprovedAt          *time.Time
+	UsedAt              *time.Time
+	CreatedAt           time.Time
+}
+
+// OAuthConsentRequest — то, что экран согласия показывает пользователю
+type OAuthConsentRequest struct {
+	ID          uuid.UUID `json:"id"`
+	ClientID    string    `json:"client_id"`
+	ClientName  string    `json:"client_name"`
+	RedirectURI string    `json:"redirect_uri"`
+	Scopes      []string  `json:"scopes"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// OAuthRedirect — адрес, на который фронтенд должен отправить браузер после решения пользователя
+type OAuthRedirect struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// AuthorizeRequest — параметры /oauth/authorize
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// OAuthTokenRequest — параметры /oauth/token. ClientSecret берётся из Basic-авторизации или из формы
+type OAuthTokenRequest struct {
+	GrantType    string
+	ClientID     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	Scope        string
+}
+
+// OAuthTokenResponse — ответ /oauth/token в формате RFC 6749
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+// Подсказки о типе токена для /oauth/introspect и /oauth/revoke (RFC 7009, раздел 2.1)
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// OAuthTokenQuery — параметры /oauth/introspect и /oauth/revoke. Клиент аутентифицируется так же,
+// как на /oauth/token
+type OAuthTokenQuery struct {
+	Token         string
+	TokenTypeHint string
+	ClientID      string
+	ClientSecret  string
+}
+
+// OAuthIntrospection — ответ /oauth/introspect в формате RFC 7662. О неактивном токене
+// сообщается только active=false
+type OAuthIntrospection struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  []string `json:"aud,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	TokenID   string   `json:"jti,omitempty"`
+}
+
+type RegisterOAuthClientPayload struct {
+	Name         string   `json:"name"          validate:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris" validate:"dive,required,url"`
+	GrantTypes   []string `json:"grant_types"   validate:"required,min=1,dive,oneof=authorization_code refresh_token client_credentials"`
+	Scopes       []string `json:"scopes"        validate:"required,min=1,dive,required"`
+	Public       bool     `json:"public"`
+	Introspect   bool     `json:"introspect"`
+}
+
+type OAuthConsentPayload struct {
+	Approve bool `json:"approve"`
+}
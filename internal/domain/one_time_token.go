@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Назначения одноразовых токенов
+const (
+	PurposeEmailVerification = "email_verification"
+	PurposePasswordReset     = "password_reset"
+	PurposeMFAPending        = "mfa_pending"
+	PurposeMagicLink         = "magic_link"
+	PurposeSocialLink        = "social_link"
+	PurposeEmailChange       = "email_change"
+)
+
+// OneTimeToken — запись об одноразовом токене. Подписанные токены (JWT) ищутся по ID = jti,
+// непрозрачные — по хешу. Использованный токен повторно не принимается
+type OneTimeToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Purpose   string
+	TokenHash *string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// Principal — аутентифицированный пользователь, от имени которого выполняется запрос.
+// Permissions — объединение прав всех его ролей, SessionID — сессия, из которой пришёл токен
+// (uuid.Nil у токенов без сессии). При входе по API-ключу APIKeyID заполнен, ролей нет,
+// а Permissions ограничены scopes ключа. У токенов OAuth-клиентов заполнен ClientID и права
+// так же ограничены выданными клиенту scopes. У токенов client_credentials пользователя нет:
+// UserID — uuid.Nil, а Permissions — scopes токена, которые клиенту всё ещё разрешены
+type Principal struct {
+	UserID      uuid.UUID
+	SessionID   uuid.UUID
+	APIKeyID    uuid.UUID
+	ClientID    string
+	Roles       []string
+	Permissions []string
+}
+
+// FirstParty сообщает, что запрос сделан самим пользователем, а не ключом или OAuth-клиентом
+// от его имени. Управлять аккаунтом можно только так
+func (p Principal) FirstParty() bool {
+	return p.APIKeyID == uuid.Nil && p.ClientID == ""
+}
+
+// Service сообщает, что запрос сделан OAuth-клиентом от своего имени, без пользователя
+func (p Principal) Service() bool {
+	return p.UserID == uuid.Nil && p.ClientID != ""
+}
+
+func (p Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+func (p Principal) Can(permission string) bool {
+	return slices.Contains(p.Permissions, permission)
+}
@@ -0,0 +1,29 @@
+package domain
+
+// Роли, создаваемые миграцией
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Права, которые роли выдают пользователю
+const (
+	PermAdsWrite      = "ads:write"      // создавать и менять свои объявления
+	PermAdsModerate   = "ads:moderate"   // менять и удалять чужие объявления
+	PermUsersManage   = "users:manage"   // управлять ролями пользователей
+	PermClientsManage = "clients:manage" // регистрировать OAuth-клиентов
+)
+
+// Permissions — все права. Их же можно выдать OAuth-клиенту как scopes
+var Permissions = []string{PermAdsWrite, PermAdsModerate, PermUsersManage, PermClientsManage}
+
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type SetUserRolesPayload struct {
+	Roles []string `json:"roles" validate:"required,min=1,dive,required"`
+}
@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Session — вход пользователя с конкретного устройства. Совпадает с семейством refresh-токенов,
+// его id попадает в access-токены как sid. LastSeenAt обновляется при логине и каждом обновлении токенов
+type Session struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"-"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	LastJTI    *uuid.UUID `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"-"`
+	// Current — это сессия, из которой пришёл запрос
+	Current bool `json:"current"`
+}
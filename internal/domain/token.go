@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// RefreshToken — запись о выданном refresh-токене. Сам токен в БД не хранится, только его хеш.
+// Все токены, полученные ротацией от одного логина, относятся к одному семейству (FamilyID).
+// ClientID и Scopes заполнены у токенов, выданных OAuth-клиенту
+type RefreshToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	FamilyID  uuid.UUID
+	TokenHash string
+	ClientID  *string
+	Scopes    []string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// TokenPair — пара access JWT + opaque refresh-токен, выдаваемая при логине и обновлении
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
@@ -8,18 +8,53 @@ import (
 type RegisterUserPayload struct {
 	Username string `json:"username" validate:"required,min=3,max=30"`
 	Email    string `json:"email"    validate:"required,email,max=150"`
-	Password string `json:"password" validate:"required,min=3,max=130"`
+	Password string `json:"password" validate:"required"`
 }
 
 type User struct {
-	ID        uuid.UUID `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"`
-	CreatedAt time.Time `json:"created_at"`
+	ID              uuid.UUID  `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	Password        string     `json:"-"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at"`
 }
 
 type LoginUserPayload struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
 }
+
+type VerifyEmailPayload struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type MagicLinkPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token"    validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// UpdateProfilePayload — частичное обновление профиля: отсутствующие поля не меняются.
+// Смена почты подтверждается текущим паролем
+type UpdateProfilePayload struct {
+	Username        *string `json:"username"         validate:"omitempty,min=3,max=30"`
+	Email           *string `json:"email"            validate:"omitempty,email,max=150"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password"     validate:"required"`
+}
+
+type DeleteAccountPayload struct {
+	Password string `json:"password" validate:"required"`
+}
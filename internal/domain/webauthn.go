@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"jwt_auth_project/internal/webauthn"
+	"time"
+)
+
+// Назначения challenge'ей WebAuthn
+const (
+	WebAuthnRegistration = "registration"
+	WebAuthnLogin        = "login"
+)
+
+// WebAuthnCredential — зарегистрированный passkey пользователя
+type WebAuthnCredential struct {
+	ID           uuid.UUID  `json:"id"`
+	UserID       uuid.UUID  `json:"-"`
+	CredentialID []byte     `json:"-"`
+	PublicKey    []byte     `json:"-"`
+	SignCount    int64      `json:"-"`
+	Name         string     `json:"name"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+}
+
+// WebAuthnChallenge — выданный challenge. Для входа по discoverable-ключу UserID пуст
+type WebAuthnChallenge struct {
+	ID        uuid.UUID
+	UserID    *uuid.UUID
+	Purpose   string
+	Challenge []byte
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+type WebAuthnRegistrationOptions struct {
+	ChallengeID uuid.UUID                `json:"challenge_id"`
+	PublicKey   webauthn.CreationOptions `json:"publicKey"`
+}
+
+type WebAuthnLoginOptions struct {
+	ChallengeID uuid.UUID               `json:"challenge_id"`
+	PublicKey   webauthn.RequestOptions `json:"publicKey"`
+}
+
+type WebAuthnRegisterPayload struct {
+	ChallengeID uuid.UUID                       `json:"challenge_id" validate:"required"`
+	Name        string                          `json:"name"         validate:"omitempty,max=64"`
+	Credential  webauthn.RegistrationCredential `json:"credential"`
+}
+
+type WebAuthnLoginPayload struct {
+	ChallengeID uuid.UUID                    `json:"challenge_id" validate:"required"`
+	Credential  webauthn.AssertionCredential `json:"credential"`
+}
@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"jwt_auth_project/internal/config"
+)
+
+// Message — простое текстовое письмо
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer отправляет письма пользователям. Реализации: SMTPMailer для боевого окружения
+// и LogMailer для локальной разработки
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New создаёт Mailer по MAIL_DRIVER: "smtp" или "log"
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case config.MailDriverSMTP:
+		return NewSMTPMailer(cfg), nil
+	case config.MailDriverLog:
+		return NewLogMailer(cfg.LogFile), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
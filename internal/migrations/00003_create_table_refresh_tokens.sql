@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "REFRESH_TOKENS" (
+                                  id          UUID        PRIMARY KEY,
+                                  user_id     UUID        NOT NULL REFERENCES "USER"(id) ON DELETE CASCADE,
+                                  family_id   UUID        NOT NULL,
+                                  token_hash  TEXT        UNIQUE NOT NULL,
+                                  expires_at  TIMESTAMP   NOT NULL,
+                                  used_at     TIMESTAMP,
+                                  revoked_at  TIMESTAMP,
+                                  created_at  TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX refresh_tokens_family_id_idx ON "REFRESH_TOKENS" (family_id);
+CREATE INDEX refresh_tokens_user_id_idx ON "REFRESH_TOKENS" (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "REFRESH_TOKENS";
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "USER" ADD COLUMN token_generation INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE "REVOKED_TOKENS" (
+                                  jti         UUID        PRIMARY KEY,
+                                  user_id     UUID        NOT NULL REFERENCES "USER"(id) ON DELETE CASCADE,
+                                  expires_at  TIMESTAMP   NOT NULL,
+                                  revoked_at  TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX revoked_tokens_expires_at_idx ON "REVOKED_TOKENS" (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "REVOKED_TOKENS";
+ALTER TABLE "USER" DROP COLUMN IF EXISTS token_generation;
+-- +goose StatementEnd
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "ROLES" (
+                         name         TEXT    PRIMARY KEY,
+                         description  TEXT    NOT NULL DEFAULT ''
+);
+
+CREATE TABLE "ROLE_PERMISSIONS" (
+                                    role        TEXT    NOT NULL REFERENCES "ROLES"(name) ON DELETE CASCADE,
+                                    permission  TEXT    NOT NULL,
+                                    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE "USER_ROLES" (
+                              user_id     UUID        NOT NULL REFERENCES "USER"(id) ON DELETE CASCADE,
+                              role        TEXT        NOT NULL REFERENCES "ROLES"(name) ON DELETE CASCADE,
+                              granted_at  TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                              PRIMARY KEY (user_id, role)
+);
+
+INSERT INTO "ROLES" (name, description) VALUES
+    ('user', 'Regular user'),
+    ('moderator', 'Manages content of other users'),
+    ('admin', 'Full access');
+
+INSERT INTO "ROLE_PERMISSIONS" (role, permission) VALUES
+    ('user', 'ads:write'),
+    ('moderator', 'ads:write'),
+    ('moderator', 'ads:moderate'),
+    ('admin', 'ads:write'),
+    ('admin', 'ads:moderate'),
+    ('admin', 'users:manage');
+
+INSERT INTO "USER_ROLES" (user_id, role)
+SELECT id, 'user' FROM "USER";
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "USER_ROLES";
+DROP TABLE IF EXISTS "ROLE_PERMISSIONS";
+DROP TABLE IF EXISTS "ROLES";
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "USER" ADD COLUMN email_verified_at TIMESTAMP;
+
+CREATE TABLE "ONE_TIME_TOKENS" (
+                                   id          UUID        PRIMARY KEY,
+                                   user_id     UUID        NOT NULL REFERENCES "USER"(id) ON DELETE CASCADE,
+                                   purpose     TEXT        NOT NULL,
+                                   token_hash  TEXT        UNIQUE,
+                                   expires_at  TIMESTAMP   NOT NULL,
+                                   used_at     TIMESTAMP,
+                                   created_at  TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX one_time_tokens_user_id_idx ON "ONE_TIME_TOKENS" (user_id, purpose);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "ONE_TIME_TOKENS";
+ALTER TABLE "USER" DROP COLUMN IF EXISTS email_verified_at;
+-- +goose StatementEnd
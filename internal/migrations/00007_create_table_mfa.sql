@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "USER_MFA" (
+                            user_id         UUID        PRIMARY KEY REFERENCES "USER"(id) ON DELETE CASCADE,
+                            totp_secret     TEXT        NOT NULL,
+                            enabled_at      TIMESTAMP,
+                            last_used_step  BIGINT      NOT NULL DEFAULT 0,
+                            created_at      TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE "MFA_RECOVERY_CODES" (
+                                      id          UUID        PRIMARY KEY,
+                                      user_id     UUID        NOT NULL REFERENCES "USER"(id) ON DELETE CASCADE,
+                                      code_hash   TEXT        NOT NULL,
+                                      used_at     TIMESTAMP
+);
+CREATE INDEX mfa_recovery_codes_user_id_idx ON "MFA_RECOVERY_CODES" (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "MFA_RECOVERY_CODES";
+DROP TABLE IF EXISTS "USER_MFA";
+-- +goose StatementEnd
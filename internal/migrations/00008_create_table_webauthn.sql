@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "WEBAUTHN_CREDENTIALS" (
+                                        id              UUID        PRIMARY KEY,
+                                        user_id         UUID        NOT NULL REFERENCES "USER"(id) ON DELETE CASCADE,
+                                        credential_id   BYTEA       NOT NULL UNIQUE,
+                                        public_key      BYTEA       NOT NULL,
+                                        sign_count      BIGINT      NOT NULL DEFAULT 0,
+                                        name            TEXT        NOT NULL,
+                                        created_at      TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                        last_used_at    TIMESTAMP
+);
+CREATE INDEX webauthn_credentials_user_id_idx ON "WEBAUTHN_CREDENTIALS" (user_id);
+
+CREATE TABLE "WEBAUTHN_CHALLENGES" (
+                                       id          UUID        PRIMARY KEY,
+                                       user_id     UUID        REFERENCES "USER"(id) ON DELETE CASCADE,
+                                       purpose     TEXT        NOT NULL,
+                                       challenge   BYTEA       NOT NULL,
+                                       expires_at  TIMESTAMP   NOT NULL,
+                                       created_at  TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX webauthn_challenges_expires_at_idx ON "WEBAUTHN_CHALLENGES" (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "WEBAUTHN_CHALLENGES";
+DROP TABLE IF EXISTS "WEBAUTHN_CREDENTIALS";
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "LOGIN_THROTTLE" (
+                                  key             TEXT        PRIMARY KEY,
+                                  failures        INTEGER     NOT NULL DEFAULT 0,
+                                  last_failure    TIMESTAMP   NOT NULL,
+                                  locked_until    TIMESTAMP
+);
+CREATE INDEX login_throttle_last_failure_idx ON "LOGIN_THROTTLE" (last_failure);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "LOGIN_THROTTLE";
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Сессия — это семейство refresh-токенов: id совпадает с "REFRESH_TOKENS".family_id
+CREATE TABLE "SESSIONS" (
+                            id              UUID        PRIMARY KEY,
+                            user_id         UUID        NOT NULL REFERENCES "USER"(id) ON DELETE CASCADE,
+                            user_agent      TEXT        NOT NULL DEFAULT '',
+                            ip              TEXT        NOT NULL DEFAULT '',
+                            last_jti        UUID,
+                            created_at      TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                            last_seen_at    TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                            revoked_at      TIMESTAMP
+);
+CREATE INDEX sessions_user_id_idx ON "SESSIONS" (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "SESSIONS";
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "API_KEYS" (
+                            id              UUID        PRIMARY KEY,
+                            user_id         UUID        NOT NULL REFERENCES "USER"(id) ON DELETE CASCADE,
+                            name            TEXT        NOT NULL,
+                            prefix          TEXT        NOT NULL UNIQUE,
+                            key_hash        TEXT        NOT NULL,
+                            scopes          TEXT[]      NOT NULL,
+                            expires_at      TIMESTAMP   NOT NULL,
+                            last_used_at    TIMESTAMP,
+                            created_at      TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                            revoked_at      TIMESTAMP
+);
+CREATE INDEX api_keys_user_id_idx ON "API_KEYS" (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "API_KEYS";
+-- +goose StatementEnd
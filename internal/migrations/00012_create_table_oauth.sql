@@ -0,0 +1,56 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "OAUTH_CLIENTS" (
+                                 id              TEXT        PRIMARY KEY,
+                                 secret_hash     TEXT,
+                                 name            TEXT        NOT NULL,
+                                 redirect_uris   TEXT[]      NOT NULL,
+                                 grant_types     TEXT[]      NOT NULL,
+                                 scopes          TEXT[]      NOT NULL,
+                                 created_by      UUID        REFERENCES "USER"(id) ON DELETE SET NULL,
+                                 created_at      TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE "OAUTH_AUTHORIZATIONS" (
+                                        id              UUID        PRIMARY KEY,
+                                        client_id       TEXT        NOT NULL REFERENCES "OAUTH_CLIENTS"(id) ON DELETE CASCADE,
+                                        user_id         UUID        NOT NULL REFERENCES "USER"(id) ON DELETE CASCADE,
+                                        redirect_uri    TEXT        NOT NULL,
+                                        scopes          TEXT[]      NOT NULL,
+                                        state           TEXT        NOT NULL DEFAULT '',
+                                        code_challenge  TEXT        NOT NULL,
+                                        code_hash       TEXT        UNIQUE,
+                                        family_id       UUID,
+                                        expires_at      TIMESTAMP   NOT NULL,
+                                        approved_at     TIMESTAMP,
+                                        used_at         TIMESTAMP,
+                                        created_at      TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX oauth_authorizations_expires_at_idx ON "OAUTH_AUTHORIZATIONS" (expires_at);
+
+CREATE TABLE "OAUTH_CONSENTS" (
+                                  user_id     UUID        NOT NULL REFERENCES "USER"(id) ON DELETE CASCADE,
+                                  client_id   TEXT        NOT NULL REFERENCES "OAUTH_CLIENTS"(id) ON DELETE CASCADE,
+                                  scopes      TEXT[]      NOT NULL,
+                                  granted_at  TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                  PRIMARY KEY (user_id, client_id)
+);
+
+ALTER TABLE "REFRESH_TOKENS"
+    ADD COLUMN client_id TEXT REFERENCES "OAUTH_CLIENTS"(id) ON DELETE CASCADE,
+    ADD COLUMN scopes    TEXT[];
+
+INSERT INTO "ROLE_PERMISSIONS" (role, permission) VALUES
+    ('admin', 'clients:manage');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM "ROLE_PERMISSIONS" WHERE permission = 'clients:manage';
+ALTER TABLE "REFRESH_TOKENS"
+    DROP COLUMN IF EXISTS scopes,
+    DROP COLUMN IF EXISTS client_id;
+DROP TABLE IF EXISTS "OAUTH_CONSENTS";
+DROP TABLE IF EXISTS "OAUTH_AUTHORIZATIONS";
+DROP TABLE IF EXISTS "OAUTH_CLIENTS";
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "OAUTH_AUTHORIZATIONS" ADD COLUMN nonce TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "OAUTH_AUTHORIZATIONS" DROP COLUMN IF EXISTS nonce;
+-- +goose StatementEnd
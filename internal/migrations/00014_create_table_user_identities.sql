@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "USER_IDENTITIES" (
+                                   id              UUID        PRIMARY KEY,
+                                   user_id         UUID        NOT NULL REFERENCES "USER"(id) ON DELETE CASCADE,
+                                   provider        TEXT        NOT NULL,
+                                   subject         TEXT        NOT NULL,
+                                   email           TEXT        NOT NULL DEFAULT '',
+                                   created_at      TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                   linked_at       TIMESTAMP,
+                                   last_login_at   TIMESTAMP,
+                                   UNIQUE (provider, subject)
+);
+CREATE INDEX user_identities_user_id_idx ON "USER_IDENTITIES" (user_id);
+
+CREATE TABLE "SOCIAL_LOGIN_STATES" (
+                                       state_hash      TEXT        PRIMARY KEY,
+                                       provider        TEXT        NOT NULL,
+                                       nonce           TEXT        NOT NULL,
+                                       code_verifier   TEXT        NOT NULL,
+                                       user_id         UUID        REFERENCES "USER"(id) ON DELETE CASCADE,
+                                       expires_at      TIMESTAMP   NOT NULL,
+                                       created_at      TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "SOCIAL_LOGIN_STATES";
+DROP TABLE IF EXISTS "USER_IDENTITIES";
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "ONE_TIME_TOKENS" ADD COLUMN failed_attempts INT NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "ONE_TIME_TOKENS" DROP COLUMN IF EXISTS failed_attempts;
+-- +goose StatementEnd
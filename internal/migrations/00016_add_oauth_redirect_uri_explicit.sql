@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "OAUTH_AUTHORIZATIONS" ADD COLUMN redirect_uri_explicit BOOLEAN NOT NULL DEFAULT TRUE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "OAUTH_AUTHORIZATIONS" DROP COLUMN IF EXISTS redirect_uri_explicit;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "OAUTH_CLIENTS" ADD COLUMN introspect BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "OAUTH_CLIENTS" DROP COLUMN IF EXISTS introspect;
+-- +goose StatementEnd
@@ -0,0 +1,79 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestParseJWKS(t *testing.T) {
+	enc := base64.RawURLEncoding
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weakRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecX := enc.EncodeToString(ecKey.X.FillBytes(make([]byte, 32)))
+	ecY := enc.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32)))
+	rsaJWK := func(kid string, key *rsa.PrivateKey) jwk {
+		return jwk{Kty: "RSA", Kid: kid, N: enc.EncodeToString(key.N.Bytes()), E: enc.EncodeToString(big.NewInt(int64(key.E)).Bytes())}
+	}
+	encryption := rsaJWK("enc", rsaKey)
+	encryption.Use = "enc"
+
+	set := []jwk{
+		rsaJWK("rsa", rsaKey),
+		{Kty: "EC", Kid: "ec", Use: "sig", Crv: "P-256", X: ecX, Y: ecY},
+		{Kty: "OKP", Kid: "ed", Crv: "Ed25519", X: enc.EncodeToString(edKey)},
+		// Дальше ключи, которые должны быть пропущены
+		encryption,
+		rsaJWK("weak", weakRSA),
+		{Kty: "EC", Kid: "off-curve", Crv: "P-256", X: ecX, Y: enc.EncodeToString(make([]byte, 32))},
+		{Kty: "EC", Kid: "short", Crv: "P-256", X: ecX[:10], Y: ecY},
+		{Kty: "EC", Kid: "secp256k1", Crv: "secp256k1", X: ecX, Y: ecY},
+		{Kty: "OKP", Kid: "x25519", Crv: "X25519", X: enc.EncodeToString(edKey)},
+		{Kty: "oct", Kid: "oct"},
+	}
+	data, err := json.Marshal(map[string][]jwk{"keys": set})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		t.Fatalf("parseJWKS: %v", err)
+	}
+	if len(keys) != 3 {
+		t.Fatalf("parsed %d keys, want rsa, ec and ed only: %v", len(keys), keys)
+	}
+	if pub, ok := keys["rsa"].(*rsa.PublicKey); !ok || !pub.Equal(&rsaKey.PublicKey) {
+		t.Fatalf("rsa key = %v", keys["rsa"])
+	}
+	if pub, ok := keys["ec"].(*ecdsa.PublicKey); !ok || !pub.Equal(&ecKey.PublicKey) {
+		t.Fatalf("ec key = %v", keys["ec"])
+	}
+	if pub, ok := keys["ed"].(ed25519.PublicKey); !ok || !pub.Equal(edKey) {
+		t.Fatalf("ed key = %v", keys["ed"])
+	}
+
+	if _, err := parseJWKS([]byte(`{"keys": {}}`)); err == nil {
+		t.Fatal("malformed jwks accepted")
+	}
+}
@@ -0,0 +1,336 @@
+// Package oidc реализует клиентскую сторону OpenID Connect для входа через внешних провайдеров:
+// discovery, authorization code flow с PKCE и проверку ID-токена по JWKS провайдера
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"jwt_auth_project/internal/config"
+)
+
+const (
+	// metadataTTL — как долго доверять закешированным discovery-документу и JWKS
+	metadataTTL = time.Hour
+	// jwksRefreshInterval — не чаще этого JWKS перечитывается из-за неизвестного kid
+	jwksRefreshInterval = time.Minute
+	// clockSkew — допустимое расхождение часов с провайдером
+	clockSkew = time.Minute
+
+	httpTimeout     = 10 * time.Second
+	maxResponseSize = 1 << 20
+)
+
+var (
+	ErrProvider       = errors.New("oidc: provider request failed")
+	ErrInvalidIDToken = errors.New("oidc: invalid id token")
+)
+
+// supportedAlgs — алгоритмы подписи ID-токена, которые мы умеем проверять.
+// HS256 с секретом клиента не поддерживается
+var supportedAlgs = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// metadata — нужная нам часть discovery-документа провайдера
+type metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	SigningAlgs           []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// Identity — проверенные сведения о пользователе из ID-токена провайдера
+type Identity struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+// Provider — внешний OpenID Connect провайдер. Discovery-документ и ключи загружаются
+// при первом обращении и кешируются, так что недоступность провайдера не мешает старту сервиса
+type Provider struct {
+	Name string
+
+	issuer       string
+	clientID     string
+	clientSecret string
+	scopes       []string
+	redirectURL  string
+	client       *http.Client
+
+	mu         sync.Mutex
+	meta       *metadata
+	metaLoaded time.Time
+	keys       map[string]crypto.PublicKey
+	keysLoaded time.Time
+}
+
+// NewProvider создаёт провайдера. redirectURL — наш callback, зарегистрированный у провайдера
+func NewProvider(cfg config.SocialProviderConfig, redirectURL string) *Provider {
+	return &Provider{
+		Name:         cfg.Name,
+		issuer:       cfg.Issuer,
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		scopes:       cfg.Scopes,
+		redirectURL:  redirectURL,
+		client:       &http.Client{Timeout: httpTimeout},
+	}
+}
+
+// AuthCodeURL возвращает адрес страницы входа провайдера с state, nonce и PKCE-challenge (S256)
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	target, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid authorization endpoint", ErrProvider)
+	}
+	query := target.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.clientID)
+	query.Set("redirect_uri", p.redirectURL)
+	query.Set("scope", strings.Join(p.scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	target.RawQuery = query.Encode()
+	return target.String(), nil
+}
+
+// Exchange обменивает код авторизации на токены и возвращает сведения из проверенного ID-токена
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+		"code_verifier": {codeVerifier},
+	}
+	if p.clientSecret == "" {
+		form.Set("client_id", p.clientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.clientID), url.QueryEscape(p.clientSecret))
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := p.do(req, &tokens); err != nil {
+		return nil, err
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("%w: token response has no id_token", ErrProvider)
+	}
+	return p.verifyIDToken(ctx, meta, tokens.IDToken, nonce)
+}
+
+// idTokenClaims — claims ID-токена провайдера
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	AuthorizedParty   string   `json:"azp"`
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     flexBool `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+}
+
+// verifyIDToken проверяет подпись, issuer, audience, срок и nonce ID-токена (OIDC Core, 3.1.3.7)
+func (p *Provider) verifyIDToken(ctx context.Context, meta *metadata, raw, nonce string) (*Identity, error) {
+	algs := supportedAlgs
+	if len(meta.SigningAlgs) > 0 {
+		algs = slices.DeleteFunc(slices.Clone(meta.SigningAlgs), func(alg string) bool {
+			return !slices.Contains(supportedAlgs, alg)
+		})
+	}
+
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, meta, kid)
+	},
+		jwt.WithValidMethods(algs),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(p.clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.clientID {
+		return nil, fmt.Errorf("%w: azp does not match client", ErrInvalidIDToken)
+	}
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+
+	return &Identity{
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		EmailVerified:     bool(claims.EmailVerified),
+		Name:              claims.Name,
+		PreferredUsername: claims.PreferredUsername,
+	}, nil
+}
+
+// metadata возвращает discovery-документ, при необходимости загружая его заново.
+// Запрос к провайдеру идёт без блокировки: медленный провайдер не должен задерживать
+// тех, кому хватает кеша
+func (p *Provider) metadata(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	cached, loaded := p.meta, p.metaLoaded
+	p.mu.Unlock()
+	if cached != nil && time.Since(loaded) < metadataTTL {
+		return cached, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var meta metadata
+	if err := p.do(req, &meta); err != nil {
+		return nil, err
+	}
+	// Провайдер обязан назвать себя тем же issuer, по которому мы его нашли
+	if strings.TrimRight(meta.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("%w: issuer mismatch %q", ErrProvider, meta.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: incomplete discovery document", ErrProvider)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Ключи могли смениться вместе с документом
+	if p.meta == nil || p.meta.JWKSURI != meta.JWKSURI {
+		p.keys = nil
+	}
+	p.meta, p.metaLoaded = &meta, time.Now()
+	return &meta, nil
+}
+
+// key ищет ключ проверки по kid. Неизвестный kid означает ротацию ключей у провайдера,
+// поэтому JWKS перечитывается, но не чаще jwksRefreshInterval. Как и в metadata,
+// блокировка держится только на время работы с кешем
+func (p *Provider) key(ctx context.Context, meta *metadata, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	keys, loaded := p.keys, p.keysLoaded
+	p.mu.Unlock()
+
+	stale := keys == nil || time.Since(loaded) > metadataTTL
+	if !stale {
+		if key, ok := lookupKey(keys, kid); ok {
+			return key, nil
+		}
+		if time.Since(loaded) < jwksRefreshInterval {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, meta.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var raw json.RawMessage
+	if err := p.do(req, &raw); err != nil {
+		return nil, err
+	}
+	keys, err = parseJWKS(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProvider, err)
+	}
+
+	p.mu.Lock()
+	p.keys, p.keysLoaded = keys, time.Now()
+	p.mu.Unlock()
+
+	if key, ok := lookupKey(keys, kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// lookupKey ищет ключ по kid. Токен без kid допустим, только если ключ единственный
+func lookupKey(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if key, ok := keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// do выполняет запрос к провайдеру и декодирует JSON-ответ в out
+func (p *Provider) do(req *http.Request, out any) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrProvider, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrProvider, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s returned %d: %s", ErrProvider, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("%w: decode response: %w", ErrProvider, err)
+	}
+	return nil
+}
+
+// flexBool принимает email_verified и как булево значение, и как строку "true"/"false":
+// некоторые провайдеры отдают его строкой
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true":
+		*b = true
+	case "false", "null", "":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	return nil
+}
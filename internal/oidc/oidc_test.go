@@ -0,0 +1,219 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"jwt_auth_project/internal/config"
+	"jwt_auth_project/internal/oidc/oidctest"
+)
+
+const (
+	testClientID    = "rp-client"
+	testRedirectURL = "https://api.example.com/login/social/mock/callback"
+	testNonce       = "nonce-123"
+	testVerifier    = "verifier-verifier-verifier-verifier-verifier"
+)
+
+func newProvider(idp *oidctest.IdP) *Provider {
+	return NewProvider(config.SocialProviderConfig{
+		Name:         "mock",
+		Issuer:       idp.URL,
+		ClientID:     idp.ClientID,
+		ClientSecret: idp.ClientSecret,
+		Scopes:       []string{"openid", "email"},
+	}, testRedirectURL)
+}
+
+// login проходит вход у провайдера и обменивает полученный код
+func login(t *testing.T, idp *oidctest.IdP, p *Provider) (*Identity, error) {
+	t.Helper()
+	sum := sha256.Sum256([]byte(testVerifier))
+	authURL, err := p.AuthCodeURL(context.Background(), "state", testNonce, base64.RawURLEncoding.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code, state := idp.Login(t, authURL)
+	if state != "state" {
+		t.Fatalf("state = %q", state)
+	}
+	return p.Exchange(context.Background(), code, testVerifier, testNonce)
+}
+
+func TestExchangeVerifiesIDToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(jwt.MapClaims)
+		wantErr bool
+	}{
+		{name: "valid", mutate: func(jwt.MapClaims) {}},
+		{name: "email_verified as string", mutate: func(c jwt.MapClaims) { c["email_verified"] = "true" }},
+		{name: "several audiences with our azp", mutate: func(c jwt.MapClaims) {
+			c["aud"] = []string{testClientID, "other"}
+			c["azp"] = testClientID
+		}},
+		{name: "wrong issuer", mutate: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, wantErr: true},
+		{name: "wrong audience", mutate: func(c jwt.MapClaims) { c["aud"] = "other" }, wantErr: true},
+		{name: "several audiences with foreign azp", mutate: func(c jwt.MapClaims) {
+			c["aud"] = []string{testClientID, "other"}
+			c["azp"] = "other"
+		}, wantErr: true},
+		{name: "several audiences without azp", mutate: func(c jwt.MapClaims) {
+			c["aud"] = []string{testClientID, "other"}
+		}, wantErr: true},
+		{name: "wrong nonce", mutate: func(c jwt.MapClaims) { c["nonce"] = "replayed" }, wantErr: true},
+		{name: "no nonce", mutate: func(c jwt.MapClaims) { delete(c, "nonce") }, wantErr: true},
+		{name: "expired", mutate: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, wantErr: true},
+		{name: "no exp", mutate: func(c jwt.MapClaims) { delete(c, "exp") }, wantErr: true},
+		{name: "issued in the future", mutate: func(c jwt.MapClaims) { c["iat"] = time.Now().Add(time.Hour).Unix() }, wantErr: true},
+		{name: "no sub", mutate: func(c jwt.MapClaims) { delete(c, "sub") }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := oidctest.New(t, testClientID, "secret")
+			idp.SetClaims(tt.mutate)
+
+			identity, err := login(t, idp, newProvider(idp))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidIDToken) {
+					t.Fatalf("err = %v, want ErrInvalidIDToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if identity.Subject != "idp-user-1" || identity.Email != "alice@example.com" || !identity.EmailVerified {
+				t.Fatalf("identity = %+v", identity)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRejectsHS256(t *testing.T) {
+	idp := oidctest.New(t, testClientID, "secret")
+	p := newProvider(idp)
+	meta, err := p.metadata(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Секрет клиента известен провайдеру, но подписи им мы не принимаем
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":   idp.URL,
+		"sub":   "idp-user-1",
+		"aud":   testClientID,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": testNonce,
+	})
+	token.Header["kid"] = "k1"
+	raw, err := token.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.verifyIDToken(context.Background(), meta, raw, testNonce); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("err = %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	idp := oidctest.New(t, testClientID, "secret")
+	idp.SetDiscovery("issuer", "https://evil.example.com")
+
+	_, err := newProvider(idp).AuthCodeURL(context.Background(), "state", testNonce, "challenge")
+	if !errors.Is(err, ErrProvider) {
+		t.Fatalf("err = %v, want ErrProvider", err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	idp := oidctest.New(t, testClientID, "secret")
+	p := newProvider(idp)
+	if _, err := login(t, idp, p); err != nil {
+		t.Fatalf("login with k1: %v", err)
+	}
+
+	// Только что перечитанный JWKS не запрашивается снова из-за неизвестного kid
+	idp.Rotate(t, "k2")
+	if _, err := login(t, idp, p); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("err = %v, want ErrInvalidIDToken right after a refresh", err)
+	}
+	if hits := idp.JWKSHits(); hits != 1 {
+		t.Fatalf("jwks fetched %d times, want 1", hits)
+	}
+
+	p.mu.Lock()
+	p.keysLoaded = p.keysLoaded.Add(-jwksRefreshInterval)
+	p.mu.Unlock()
+	if _, err := login(t, idp, p); err != nil {
+		t.Fatalf("login with rotated k2: %v", err)
+	}
+	if hits := idp.JWKSHits(); hits != 2 {
+		t.Fatalf("jwks fetched %d times, want 2", hits)
+	}
+
+	// Снятый с публикации ключ больше не принимается
+	idp.UseKey("k1")
+	if _, err := login(t, idp, p); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("err = %v, want ErrInvalidIDToken for a retired key", err)
+	}
+}
+
+func TestSlowJWKSDoesNotBlockCache(t *testing.T) {
+	idp := oidctest.New(t, testClientID, "secret")
+	p := newProvider(idp)
+	if _, err := login(t, idp, p); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	meta, err := p.metadata(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := idp.BlockJWKS()
+	defer release()
+	p.mu.Lock()
+	p.keysLoaded = p.keysLoaded.Add(-jwksRefreshInterval)
+	p.mu.Unlock()
+
+	// Запрос с неизвестным kid повисает на JWKS провайдера
+	refreshed := make(chan error, 1)
+	go func() {
+		_, err := p.key(context.Background(), meta, "unknown")
+		refreshed <- err
+	}()
+	for idp.JWKSHits() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Кешированные документ и ключ отдаются, не дожидаясь провайдера
+	done := make(chan error, 1)
+	go func() {
+		if _, err := p.metadata(context.Background()); err != nil {
+			done <- err
+			return
+		}
+		_, err := p.key(context.Background(), meta, "k1")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("cached lookup: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("cached lookup blocked by a JWKS request in flight")
+	}
+
+	release()
+	if err := <-refreshed; err == nil {
+		t.Fatal("unknown kid accepted")
+	}
+}
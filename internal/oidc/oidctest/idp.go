@@ -0,0 +1,280 @@
+// Package oidctest — провайдер OpenID Connect на httptest-сервере для тестов входа через
+// внешних провайдеров. Реализует discovery, JWKS, страницу входа, сразу отдающую код,
+// и token endpoint с проверкой PKCE. ID-токены подписываются ES256
+package oidctest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// grant — выданный, но ещё не обменянный код
+type grant struct {
+	redirectURI string
+	nonce       string
+	challenge   string
+}
+
+// IdP — провайдер, зарегистрировавший одного клиента ClientID/ClientSecret
+type IdP struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	mu        sync.Mutex
+	keys      map[string]*ecdsa.PrivateKey
+	published []string
+	signing   string
+	codes     map[string]grant
+	claims    func(jwt.MapClaims)
+	discovery map[string]any
+	jwksHits  int
+	jwksGate  chan struct{}
+}
+
+// New запускает провайдера с ключом подписи "k1". Сервер останавливается по завершении теста
+func New(t testing.TB, clientID, clientSecret string) *IdP {
+	t.Helper()
+	idp := &IdP{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		keys:         map[string]*ecdsa.PrivateKey{},
+		codes:        map[string]grant{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", idp.handleDiscovery)
+	mux.HandleFunc("GET /jwks", idp.handleJWKS)
+	mux.HandleFunc("GET /authorize", idp.handleAuthorize)
+	mux.HandleFunc("POST /token", idp.handleToken)
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	idp.Rotate(t, "k1")
+	return idp
+}
+
+// Rotate заводит ключ kid и подписывает им дальнейшие ID-токены. В JWKS остаётся только он;
+// прежние ключи можно вернуть в работу через UseKey
+func (idp *IdP) Rotate(t testing.TB, kid string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.keys[kid] = key
+	idp.published = []string{kid}
+	idp.signing = kid
+}
+
+// UseKey подписывает дальнейшие ID-токены ключом kid, даже если тот уже не опубликован
+func (idp *IdP) UseKey(kid string) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.signing = kid
+}
+
+// SetClaims задаёт правку claims каждого следующего ID-токена. По умолчанию токен выдан
+// пользователю idp-user-1 с подтверждённым alice@example.com
+func (idp *IdP) SetClaims(fn func(jwt.MapClaims)) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.claims = fn
+}
+
+// SetDiscovery подменяет поле discovery-документа
+func (idp *IdP) SetDiscovery(field string, value any) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	if idp.discovery == nil {
+		idp.discovery = map[string]any{}
+	}
+	idp.discovery[field] = value
+}
+
+// JWKSHits возвращает, сколько раз запрашивали JWKS
+func (idp *IdP) JWKSHits() int {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	return idp.jwksHits
+}
+
+// BlockJWKS задерживает ответы JWKS до вызова возвращённой функции. Вызывать её можно
+// несколько раз
+func (idp *IdP) BlockJWKS() (release func()) {
+	gate := make(chan struct{})
+	idp.mu.Lock()
+	idp.jwksGate = gate
+	idp.mu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			idp.mu.Lock()
+			idp.jwksGate = nil
+			idp.mu.Unlock()
+			close(gate)
+		})
+	}
+}
+
+// Login проходит страницу входа провайдера по адресу authURL и возвращает code и state
+// из редиректа обратно к клиенту
+func (idp *IdP) Login(t testing.TB, authURL string) (code, state string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location, err := resp.Location()
+	if err != nil {
+		t.Fatalf("authorize: status %d, no redirect: %v", resp.StatusCode, err)
+	}
+	if errCode := location.Query().Get("error"); errCode != "" {
+		t.Fatalf("authorize: %s", errCode)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func (idp *IdP) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	doc := map[string]any{
+		"issuer":                                idp.URL,
+		"authorization_endpoint":                idp.URL + "/authorize",
+		"token_endpoint":                        idp.URL + "/token",
+		"jwks_uri":                              idp.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"ES256"},
+	}
+	for field, value := range idp.discovery {
+		doc[field] = value
+	}
+	idp.mu.Unlock()
+	writeJSON(w, http.StatusOK, doc)
+}
+
+func (idp *IdP) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	idp.jwksHits++
+	gate := idp.jwksGate
+	idp.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	enc := base64.RawURLEncoding
+	keys := []map[string]string{}
+	for _, kid := range idp.published {
+		pub := idp.keys[kid].PublicKey
+		keys = append(keys, map[string]string{
+			"kty": "EC",
+			"kid": kid,
+			"use": "sig",
+			"alg": "ES256",
+			"crv": "P-256",
+			"x":   enc.EncodeToString(pub.X.FillBytes(make([]byte, 32))),
+			"y":   enc.EncodeToString(pub.Y.FillBytes(make([]byte, 32))),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"keys": keys})
+}
+
+// handleAuthorize сразу «входит» пользователем и редиректит к клиенту с кодом
+func (idp *IdP) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("client_id") != idp.ClientID || query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+	idp.mu.Lock()
+	idp.codes[code] = grant{redirectURI: redirectURI.String(), nonce: query.Get("nonce"), challenge: query.Get("code_challenge")}
+	idp.mu.Unlock()
+
+	back := redirectURI.Query()
+	back.Set("code", code)
+	back.Set("state", query.Get("state"))
+	redirectURI.RawQuery = back.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (idp *IdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if id, secret, ok := r.BasicAuth(); !ok || id != idp.ClientID || secret != idp.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	code := r.PostForm.Get("code")
+	g, ok := idp.codes[code]
+	delete(idp.codes, code)
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != g.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            idp.URL,
+		"sub":            "idp-user-1",
+		"aud":            idp.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          g.nonce,
+		"email":          "alice@example.com",
+		"email_verified": true,
+	}
+	if idp.claims != nil {
+		idp.claims(claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = idp.signing
+	idToken, err := token.SignedString(idp.keys[idp.signing])
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("sign: %v", err)})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,77 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// prefixLength — длина префикса SHA-1, как в k-anonymity API Have I Been Pwned
+const prefixLength = 5
+
+// BreachedList — локальный список утёкших паролей. Хранятся только SHA-1 хеши,
+// сгруппированные по пятисимвольному префиксу: так же устроены выгрузки Have I Been Pwned,
+// и список можно заменить удалённым запросом по префиксу, не меняя интерфейс
+type BreachedList struct {
+	ranges map[string]map[string]struct{}
+}
+
+// LoadBreachedList читает файл, где каждая строка — SHA-1 пароля в hex,
+// опционально с ":count" в конце (формат выгрузки HIBP). Пустые строки и # комментарии пропускаются
+func LoadBreachedList(path string) (*BreachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	list := &BreachedList{ranges: make(map[string]map[string]struct{})}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(text, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("%s:%d: expected SHA-1 hex digest", path, line)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		list.add(hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Contains сообщает, есть ли пароль в списке
+func (l *BreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	_, found := l.ranges[hash[:prefixLength]][hash[prefixLength:]]
+	return found
+}
+
+// Len возвращает число хешей в списке
+func (l *BreachedList) Len() int {
+	n := 0
+	for _, suffixes := range l.ranges {
+		n += len(suffixes)
+	}
+	return n
+}
+
+func (l *BreachedList) add(hash string) {
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+	if l.ranges[prefix] == nil {
+		l.ranges[prefix] = make(map[string]struct{})
+	}
+	l.ranges[prefix][suffix] = struct{}{}
+}
@@ -0,0 +1,146 @@
+// Package password хеширует пароли Argon2id и хранит результат в формате PHC:
+//
+//	$argon2id$v=19$m=65536,t=1,p=4$<salt>$<hash>
+//
+// Кроме того, проверяет хеши старого формата "salt$hash" и bcrypt-хеши,
+// импортированные из прежней системы. Такие хеши помечаются как требующие пересчёта
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"jwt_auth_project/internal/config"
+)
+
+const (
+	saltLength = 16
+	keyLength  = 32
+)
+
+// Параметры, которыми хешировались пароли до перехода на PHC
+var legacyParams = params{memory: 64 * 1024, time: 1, threads: 4}
+
+var ErrInvalidHash = errors.New("invalid password hash format")
+
+type params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+// Hasher хеширует пароли текущими параметрами из конфига
+type Hasher struct {
+	current params
+}
+
+func NewHasher(cfg config.PasswordConfig) *Hasher {
+	return &Hasher{current: params{memory: cfg.Memory, time: cfg.Time, threads: cfg.Threads}}
+}
+
+// Hash возвращает PHC-строку для пароля
+func (h *Hasher) Hash(password string) (string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	p := h.current
+	key := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, keyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify сравнивает пароль с хешем. needsRehash сообщает, что пароль верный,
+// но хеш стоит пересчитать текущими параметрами
+func (h *Hasher) Verify(encoded, password string) (ok, needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		p, salt, key, err := decodePHC(encoded)
+		if err != nil {
+			return false, false, err
+		}
+		ok := compareArgon2(password, salt, key, p)
+		return ok, ok && p != h.current, nil
+
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		return true, true, nil
+
+	default:
+		salt, key, err := decodeLegacy(encoded)
+		if err != nil {
+			return false, false, err
+		}
+		ok := compareArgon2(password, salt, key, legacyParams)
+		return ok, ok, nil
+	}
+}
+
+func compareArgon2(password string, salt, key []byte, p params) bool {
+	computed := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, computed) == 1
+}
+
+// decodePHC разбирает "$argon2id$v=19$m=..,t=..,p=..$salt$hash"
+func decodePHC(encoded string) (params, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params{}, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params{}, nil, nil, fmt.Errorf("%w: unsupported argon2 version", ErrInvalidHash)
+	}
+
+	var p params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return params{}, nil, nil, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+	if p.time == 0 || p.threads == 0 {
+		return params{}, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params{}, nil, nil, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params{}, nil, nil, ErrInvalidHash
+	}
+	return p, salt, key, nil
+}
+
+// decodeLegacy разбирает старый формат "salt$hash"
+func decodeLegacy(encoded string) ([]byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 2 {
+		return nil, nil, ErrInvalidHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil || len(key) == 0 {
+		return nil, nil, ErrInvalidHash
+	}
+	return salt, key, nil
+}
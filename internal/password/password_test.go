@@ -0,0 +1,82 @@
+package password
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"jwt_auth_project/internal/config"
+)
+
+const testPassword = "correct horse battery"
+
+var testSalt = []byte("0123456789abcdef")
+
+func encodePHC(p params, key []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(testSalt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func TestVerify(t *testing.T) {
+	h := NewHasher(config.PasswordConfig{Memory: 1024, Time: 1, Threads: 1})
+	current, err := h.Hash(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := params{memory: 512, time: 2, threads: 1}
+	oldKey := argon2.IDKey([]byte(testPassword), testSalt, old.time, old.memory, old.threads, keyLength)
+
+	legacyKey := argon2.IDKey([]byte(testPassword), testSalt, legacyParams.time, legacyParams.memory, legacyParams.threads, keyLength)
+	legacy := base64.RawStdEncoding.EncodeToString(testSalt) + "$" + base64.RawStdEncoding.EncodeToString(legacyKey)
+
+	bcrypted, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		encoded     string
+		password    string
+		ok          bool
+		needsRehash bool
+		err         error
+	}{
+		{name: "phc current params", encoded: current, password: testPassword, ok: true},
+		{name: "phc current params wrong password", encoded: current, password: "wrong"},
+		{name: "phc old params", encoded: encodePHC(old, oldKey), password: testPassword, ok: true, needsRehash: true},
+		{name: "phc old params wrong password", encoded: encodePHC(old, oldKey), password: "wrong"},
+		{name: "legacy salt$hash", encoded: legacy, password: testPassword, ok: true, needsRehash: true},
+		{name: "legacy wrong password", encoded: legacy, password: "wrong"},
+		{name: "bcrypt", encoded: string(bcrypted), password: testPassword, ok: true, needsRehash: true},
+		{name: "bcrypt wrong password", encoded: string(bcrypted), password: "wrong"},
+
+		{name: "phc missing hash", encoded: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA", password: testPassword, err: ErrInvalidHash},
+		{name: "phc unsupported version", encoded: "$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5", password: testPassword, err: ErrInvalidHash},
+		{name: "phc garbled params", encoded: "$argon2id$v=19$memory=1024$c2FsdA$a2V5", password: testPassword, err: ErrInvalidHash},
+		{name: "phc zero time", encoded: "$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5", password: testPassword, err: ErrInvalidHash},
+		{name: "phc bad salt encoding", encoded: "$argon2id$v=19$m=1024,t=1,p=1$!!!$a2V5", password: testPassword, err: ErrInvalidHash},
+		{name: "phc empty hash", encoded: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$", password: testPassword, err: ErrInvalidHash},
+		{name: "legacy without separator", encoded: "c2FsdGhhc2g", password: testPassword, err: ErrInvalidHash},
+		{name: "empty hash", encoded: "", password: testPassword, err: ErrInvalidHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := h.Verify(tt.encoded, tt.password)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if ok != tt.ok || needsRehash != tt.needsRehash {
+				t.Fatalf("ok, needsRehash = %v, %v; want %v, %v", ok, needsRehash, tt.ok, tt.needsRehash)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"jwt_auth_project/internal/config"
+)
+
+// Правила политики паролей
+const (
+	RuleMinLength   = "min_length"
+	RuleMaxLength   = "max_length"
+	RuleCharClasses = "char_classes"
+	RuleUserInfo    = "contains_user_info"
+	RuleBreached    = "breached"
+)
+
+// minUserInfoLength — слишком короткие части имени/почты не проверяем, иначе ложные срабатывания
+const minUserInfoLength = 3
+
+// Violation — нарушенное правило политики
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError возвращается, если пароль нарушает хотя бы одно правило
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	rules := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		rules = append(rules, v.Rule)
+	}
+	return "password does not meet policy: " + strings.Join(rules, ", ")
+}
+
+// Policy — требования к новым паролям. Проверяются при регистрации, сбросе и смене пароля,
+// но не при логине: существующие пароли продолжают работать
+type Policy struct {
+	MinLength int
+	MaxLength int
+	// MinCharClasses — сколько классов символов из четырёх (строчные, заглавные, цифры, прочие) нужно
+	MinCharClasses int
+	// Breached — список утёкших паролей, может быть nil
+	Breached *BreachedList
+}
+
+// NewPolicy собирает политику из конфига и загружает список утёкших паролей, если он задан
+func NewPolicy(cfg config.PasswordConfig) (*Policy, error) {
+	policy := &Policy{
+		MinLength:      cfg.MinLength,
+		MaxLength:      cfg.MaxLength,
+		MinCharClasses: cfg.MinCharClasses,
+	}
+	if cfg.BreachedListFile != "" {
+		list, err := LoadBreachedList(cfg.BreachedListFile)
+		if err != nil {
+			return nil, fmt.Errorf("load breached password list: %w", err)
+		}
+		policy.Breached = list
+	}
+	return policy, nil
+}
+
+// Check проверяет пароль. userInputs — имя пользователя, email и т.п.: пароль не должен их содержать
+func (p *Policy) Check(password string, userInputs ...string) error {
+	var violations []Violation
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, Violation{
+			Rule:    RuleMinLength,
+			Message: fmt.Sprintf("password must be at least %d characters long", p.MinLength),
+		})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, Violation{
+			Rule:    RuleMaxLength,
+			Message: fmt.Sprintf("password must be at most %d characters long", p.MaxLength),
+		})
+		// Дальше не проверяем: хешировать для списка утечек слишком длинную строку незачем
+		return &PolicyError{Violations: violations}
+	}
+
+	if classes := charClasses(password); classes < p.MinCharClasses {
+		violations = append(violations, Violation{
+			Rule: RuleCharClasses,
+			Message: fmt.Sprintf("password must contain at least %d of: lowercase letters, "+
+				"uppercase letters, digits, symbols", p.MinCharClasses),
+		})
+	}
+
+	if containsUserInfo(password, userInputs) {
+		violations = append(violations, Violation{
+			Rule:    RuleUserInfo,
+			Message: "password must not contain your username or email",
+		})
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violations = append(violations, Violation{
+			Rule:    RuleBreached,
+			Message: "this password has appeared in a data breach, choose another one",
+		})
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func charClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	n := 0
+	for _, has := range []bool{lower, upper, digit, other} {
+		if has {
+			n++
+		}
+	}
+	return n
+}
+
+// containsUserInfo ищет в пароле имя пользователя, email и локальную часть email без учёта регистра
+func containsUserInfo(password string, inputs []string) bool {
+	lowered := strings.ToLower(password)
+	for _, input := range inputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		candidates := []string{input}
+		if local, _, ok := strings.Cut(input, "@"); ok {
+			candidates = append(candidates, local)
+		}
+		for _, c := range candidates {
+			if utf8.RuneCountInString(c) >= minUserInfoLength && strings.Contains(lowered, c) {
+				return true
+			}
+		}
+	}
+	return false
+}
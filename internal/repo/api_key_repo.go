@@ -0,0 +1,111 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"jwt_auth_project/internal/domain"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+type APIKeyRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewAPIKeyRepo(pool *pgxpool.Pool) *APIKeyRepo {
+	return &APIKeyRepo{pool: pool}
+}
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, k domain.APIKey) error
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]domain.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error
+	TouchAPIKey(ctx context.Context, id uuid.UUID) error
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at, revoked_at`
+
+func scanAPIKey(row pgx.Row) (*domain.APIKey, error) {
+	k := new(domain.APIKey)
+	err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.KeyHash, &k.Scopes,
+		&k.ExpiresAt, &k.LastUsedAt, &k.CreatedAt, &k.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func (r *APIKeyRepo) CreateAPIKey(ctx context.Context, k domain.APIKey) error {
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO "API_KEYS" (id, user_id, name, prefix, key_hash, scopes, expires_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    `, k.ID, k.UserID, k.Name, k.Prefix, k.KeyHash, k.Scopes, k.ExpiresAt, k.CreatedAt)
+	return err
+}
+
+func (r *APIKeyRepo) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error) {
+	k, err := scanAPIKey(r.pool.QueryRow(ctx, `
+        SELECT `+apiKeyColumns+` FROM "API_KEYS" WHERE prefix = $1
+    `, prefix))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return k, err
+}
+
+// ListAPIKeys возвращает неотозванные ключи пользователя, включая истёкшие
+func (r *APIKeyRepo) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]domain.APIKey, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT `+apiKeyColumns+` FROM "API_KEYS"
+        WHERE user_id = $1 AND revoked_at IS NULL
+        ORDER BY created_at DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []domain.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+func (r *APIKeyRepo) RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error {
+	cmd, err := r.pool.Exec(ctx, `
+        UPDATE "API_KEYS"
+        SET revoked_at = $3
+        WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+    `, id, userID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey обновляет время последнего использования не чаще раза в минуту,
+// чтобы каждый запрос скрипта не превращался в запись в базу
+func (r *APIKeyRepo) TouchAPIKey(ctx context.Context, id uuid.UUID) error {
+	now := time.Now().UTC()
+	_, err := r.pool.Exec(ctx, `
+        UPDATE "API_KEYS"
+        SET last_used_at = $2
+        WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $3)
+    `, id, now, now.Add(-time.Minute))
+	return err
+}
@@ -0,0 +1,170 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"jwt_auth_project/internal/domain"
+)
+
+var (
+	ErrIdentityNotFound = errors.New("identity not found")
+	// ErrIdentityExists — аккаунт провайдера уже привязан к пользователю
+	ErrIdentityExists = errors.New("identity is already linked")
+	// ErrSocialStateInvalid — state не найден, уже использован или истёк
+	ErrSocialStateInvalid = errors.New("social login state is invalid or expired")
+)
+
+type IdentityRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewIdentityRepo(pool *pgxpool.Pool) *IdentityRepo {
+	return &IdentityRepo{pool: pool}
+}
+
+type IdentityRepository interface {
+	CreateIdentity(ctx context.Context, i domain.UserIdentity) error
+	GetLinkedIdentity(ctx context.Context, provider, subject string) (*domain.UserIdentity, error)
+	ConfirmIdentity(ctx context.Context, id uuid.UUID) (*domain.UserIdentity, error)
+	ListIdentities(ctx context.Context, userID uuid.UUID) ([]domain.UserIdentity, error)
+	DeleteIdentity(ctx context.Context, userID, id uuid.UUID) error
+	TouchIdentity(ctx context.Context, id uuid.UUID) error
+	CreateSocialLoginState(ctx context.Context, s domain.SocialLoginState) error
+	ConsumeSocialLoginState(ctx context.Context, stateHash, provider string) (*domain.SocialLoginState, error)
+}
+
+const identityColumns = `id, user_id, provider, subject, email, created_at, linked_at, last_login_at`
+
+func scanIdentity(row pgx.Row) (*domain.UserIdentity, error) {
+	i := new(domain.UserIdentity)
+	err := row.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt, &i.LinkedAt, &i.LastLoginAt)
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// CreateIdentity сохраняет привязку. Неподтверждённая привязка того же аккаунта провайдера
+// заменяется новой, подтверждённая — нет
+func (r *IdentityRepo) CreateIdentity(ctx context.Context, i domain.UserIdentity) error {
+	cmd, err := r.pool.Exec(ctx, `
+        INSERT INTO "USER_IDENTITIES" (id, user_id, provider, subject, email, created_at, linked_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT (provider, subject) DO UPDATE
+        SET id = EXCLUDED.id, user_id = EXCLUDED.user_id, email = EXCLUDED.email,
+            created_at = EXCLUDED.created_at, linked_at = EXCLUDED.linked_at
+        WHERE "USER_IDENTITIES".linked_at IS NULL
+    `, i.ID, i.UserID, i.Provider, i.Subject, i.Email, i.CreatedAt, i.LinkedAt)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrIdentityExists
+	}
+	return nil
+}
+
+// GetLinkedIdentity ищет подтверждённую привязку аккаунта провайдера
+func (r *IdentityRepo) GetLinkedIdentity(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	i, err := scanIdentity(r.pool.QueryRow(ctx, `
+        SELECT `+identityColumns+` FROM "USER_IDENTITIES"
+        WHERE provider = $1 AND subject = $2 AND linked_at IS NOT NULL
+    `, provider, subject))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrIdentityNotFound
+	}
+	return i, err
+}
+
+// ConfirmIdentity подтверждает ожидающую привязку и возвращает её
+func (r *IdentityRepo) ConfirmIdentity(ctx context.Context, id uuid.UUID) (*domain.UserIdentity, error) {
+	i, err := scanIdentity(r.pool.QueryRow(ctx, `
+        UPDATE "USER_IDENTITIES"
+        SET linked_at = $2
+        WHERE id = $1 AND linked_at IS NULL
+        RETURNING `+identityColumns+`
+    `, id, time.Now().UTC()))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrIdentityNotFound
+	}
+	return i, err
+}
+
+// ListIdentities возвращает подтверждённые привязки пользователя
+func (r *IdentityRepo) ListIdentities(ctx context.Context, userID uuid.UUID) ([]domain.UserIdentity, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT `+identityColumns+` FROM "USER_IDENTITIES"
+        WHERE user_id = $1 AND linked_at IS NOT NULL
+        ORDER BY linked_at
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []domain.UserIdentity{}
+	for rows.Next() {
+		i, err := scanIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, *i)
+	}
+	return identities, rows.Err()
+}
+
+func (r *IdentityRepo) DeleteIdentity(ctx context.Context, userID, id uuid.UUID) error {
+	cmd, err := r.pool.Exec(ctx, `
+        DELETE FROM "USER_IDENTITIES" WHERE id = $1 AND user_id = $2
+    `, id, userID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrIdentityNotFound
+	}
+	return nil
+}
+
+func (r *IdentityRepo) TouchIdentity(ctx context.Context, id uuid.UUID) error {
+	_, err := r.pool.Exec(ctx, `
+        UPDATE "USER_IDENTITIES" SET last_login_at = $2 WHERE id = $1
+    `, id, time.Now().UTC())
+	return err
+}
+
+// CreateSocialLoginState сохраняет state и заодно чистит просроченные
+func (r *IdentityRepo) CreateSocialLoginState(ctx context.Context, s domain.SocialLoginState) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM "SOCIAL_LOGIN_STATES" WHERE expires_at < $1`, time.Now().UTC()); err != nil {
+		return err
+	}
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO "SOCIAL_LOGIN_STATES" (state_hash, provider, nonce, code_verifier, user_id, expires_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `, s.StateHash, s.Provider, s.Nonce, s.CodeVerifier, s.UserID, s.ExpiresAt, s.CreatedAt)
+	return err
+}
+
+// ConsumeSocialLoginState удаляет state и возвращает его, если он ещё действителен.
+// Каждый state годится ровно для одного возврата с провайдера
+func (r *IdentityRepo) ConsumeSocialLoginState(ctx context.Context, stateHash, provider string) (*domain.SocialLoginState, error) {
+	var s domain.SocialLoginState
+	err := r.pool.QueryRow(ctx, `
+        DELETE FROM "SOCIAL_LOGIN_STATES"
+        WHERE state_hash = $1 AND provider = $2 AND expires_at > $3
+        RETURNING state_hash, provider, nonce, code_verifier, user_id, expires_at, created_at
+    `, stateHash, provider, time.Now().UTC()).Scan(&s.StateHash, &s.Provider, &s.Nonce, &s.CodeVerifier, &s.UserID, &s.ExpiresAt, &s.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSocialStateInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,148 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"jwt_auth_project/internal/domain"
+)
+
+var (
+	ErrMFANotFound          = errors.New("mfa is not configured")
+	ErrTOTPStepUsed         = errors.New("totp code already used")
+	ErrRecoveryCodeNotFound = errors.New("recovery code is invalid or already used")
+)
+
+type MFARepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewMFARepo(pool *pgxpool.Pool) *MFARepo {
+	return &MFARepo{pool: pool}
+}
+
+type MFARepository interface {
+	GetMFA(ctx context.Context, userID uuid.UUID) (*domain.MFA, error)
+	SavePendingTOTP(ctx context.Context, userID uuid.UUID, secret string) error
+	EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes []string) error
+	DisableMFA(ctx context.Context, userID uuid.UUID) error
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) error
+}
+
+func (r *MFARepo) GetMFA(ctx context.Context, userID uuid.UUID) (*domain.MFA, error) {
+	var m domain.MFA
+	err := r.pool.QueryRow(ctx, `
+        SELECT user_id, totp_secret, enabled_at, last_used_step, created_at
+        FROM "USER_MFA"
+        WHERE user_id = $1
+    `, userID).Scan(&m.UserID, &m.TOTPSecret, &m.EnabledAt, &m.LastUsedStep, &m.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMFANotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// SavePendingTOTP сохраняет новый неподтверждённый секрет, заменяя предыдущий неподтверждённый.
+// Включённую 2FA не трогает
+func (r *MFARepo) SavePendingTOTP(ctx context.Context, userID uuid.UUID, secret string) error {
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO "USER_MFA" (user_id, totp_secret, created_at)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (user_id) DO UPDATE
+        SET totp_secret = EXCLUDED.totp_secret, last_used_step = 0, created_at = EXCLUDED.created_at
+        WHERE "USER_MFA".enabled_at IS NULL
+    `, userID, secret, time.Now().UTC())
+	return err
+}
+
+// EnableTOTP подтверждает секрет и заменяет коды восстановления одной транзакцией
+func (r *MFARepo) EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes []string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+        UPDATE "USER_MFA"
+        SET enabled_at = $2, last_used_step = $3
+        WHERE user_id = $1 AND enabled_at IS NULL AND last_used_step < $3
+    `, userID, time.Now().UTC(), step)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTOTPStepUsed
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM "MFA_RECOVERY_CODES" WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryHashes {
+		if _, err := tx.Exec(ctx, `
+            INSERT INTO "MFA_RECOVERY_CODES" (id, user_id, code_hash)
+            VALUES ($1, $2, $3)
+        `, uuid.New(), userID, hash); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *MFARepo) DisableMFA(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM "MFA_RECOVERY_CODES" WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM "USER_MFA" WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UseTOTPStep атомарно запоминает шаг принятого кода. Код того же или более раннего шага
+// повторно не принимается — так один перехваченный код нельзя использовать дважды
+func (r *MFARepo) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	tag, err := r.pool.Exec(ctx, `
+        UPDATE "USER_MFA"
+        SET last_used_step = $2
+        WHERE user_id = $1 AND last_used_step < $2
+    `, userID, step)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTOTPStepUsed
+	}
+	return nil
+}
+
+// UseRecoveryCode гасит код восстановления пользователя
+func (r *MFARepo) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) error {
+	tag, err := r.pool.Exec(ctx, `
+        UPDATE "MFA_RECOVERY_CODES"
+        SET used_at = $3
+        WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+    `, userID, hash, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"jwt_auth_project/internal/domain"
+)
+
+var (
+	ErrOAuthClientNotFound   = errors.New("oauth client not found")
+	ErrAuthorizationNotFound = errors.New("authorization request not found or expired")
+	// ErrAuthorizationCodeUsed возвращается ConsumeAuthorizationCode при повторном предъявлении кода
+	ErrAuthorizationCodeUsed = errors.New("authorization code already used")
+)
+
+type OAuthRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewOAuthRepo(pool *pgxpool.Pool) *OAuthRepo {
+	return &OAuthRepo{pool: pool}
+}
+
+type OAuthRepository interface {
+	CreateClient(ctx context.Context, c domain.OAuthClient) error
+	GetClient(ctx context.Context, id string) (*domain.OAuthClient, error)
+	ListClients(ctx context.Context) ([]domain.OAuthClient, error)
+	DeleteClient(ctx context.Context, id string) error
+	CreateAuthorization(ctx context.Context, a domain.OAuthAuthorization) error
+	GetPendingAuthorization(ctx context.Context, userID, id uuid.UUID) (*domain.OAuthAuthorization, error)
+	ApproveAuthorization(ctx context.Context, userID, id uuid.UUID, codeHash string, expiresAt time.Time) (*domain.OAuthAuthorization, error)
+	DeleteAuthorization(ctx context.Context, userID, id uuid.UUID) (*domain.OAuthAuthorization, error)
+	GetAuthorizationByCode(ctx context.Context, codeHash string) (*domain.OAuthAuthorization, error)
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string, familyID uuid.UUID) (*domain.OAuthAuthorization, error)
+	GetConsent(ctx context.Context, userID uuid.UUID, clientID string) ([]string, error)
+	SaveConsent(ctx context.Context, userID uuid.UUID, clientID string, scopes []string) error
+}
+
+const oauthClientColumns = `id, secret_hash, name, redirect_uris, grant_types, scopes, introspect, created_by, created_at`
+
+func scanOAuthClient(row pgx.Row) (*domain.OAuthClient, error) {
+	var c domain.OAuthClient
+	err := row.Scan(&c.ID, &c.SecretHash, &c.Name, &c.RedirectURIs, &c.GrantTypes, &c.Scopes, &c.Introspect, &c.CreatedBy, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+const oauthAuthorizationColumns = `id, client_id, user_id, redirect_uri, redirect_uri_explicit, scopes, state, nonce,
+        code_challenge, code_hash, family_id, expires_at, approved_at, used_at, created_at`
+
+func scanOAuthAuthorization(row pgx.Row) (*domain.OAuthAuthorization, error) {
+	var a domain.OAuthAuthorization
+	err := row.Scan(&a.ID, &a.ClientID, &a.UserID, &a.RedirectURI, &a.RedirectURIExplicit, &a.Scopes, &a.State, &a.Nonce,
+		&a.CodeChallenge, &a.CodeHash, &a.FamilyID, &a.ExpiresAt, &a.ApprovedAt, &a.UsedAt, &a.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAuthorizationNotFound
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *OAuthRepo) CreateClient(ctx context.Context, c domain.OAuthClient) error {
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO "OAUTH_CLIENTS" (`+oauthClientColumns+`)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    `, c.ID, c.SecretHash, c.Name, c.RedirectURIs, c.GrantTypes, c.Scopes, c.Introspect, c.CreatedBy, c.CreatedAt)
+	return err
+}
+
+func (r *OAuthRepo) GetClient(ctx context.Context, id string) (*domain.OAuthClient, error) {
+	c, err := scanOAuthClient(r.pool.QueryRow(ctx, `SELECT `+oauthClientColumns+` FROM "OAUTH_CLIENTS" WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrOAuthClientNotFound
+	}
+	return c, err
+}
+
+func (r *OAuthRepo) ListClients(ctx context.Context) ([]domain.OAuthClient, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+oauthClientColumns+` FROM "OAUTH_CLIENTS" ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := []domain.OAuthClient{}
+	for rows.Next() {
+		c, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, *c)
+	}
+	return clients, rows.Err()
+}
+
+// DeleteClient удаляет клиента; его refresh-токены, коды и согласия удаляются каскадом
+func (r *OAuthRepo) DeleteClient(ctx context.Context, id string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM "OAUTH_CLIENTS" WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrOAuthClientNotFound
+	}
+	return nil
+}
+
+// CreateAuthorization сохраняет запрос авторизации и заодно чистит просроченные
+func (r *OAuthRepo) CreateAuthorization(ctx context.Context, a domain.OAuthAuthorization) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM "OAUTH_AUTHORIZATIONS" WHERE expires_at < $1`, time.Now().UTC().Add(-24*time.Hour)); err != nil {
+		return err
+	}
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO "OAUTH_AUTHORIZATIONS" (`+oauthAuthorizationColumns+`)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+    `, a.ID, a.ClientID, a.UserID, a.RedirectURI, a.RedirectURIExplicit, a.Scopes, a.State, a.Nonce,
+		a.CodeChallenge, a.CodeHash, a.FamilyID, a.ExpiresAt, a.ApprovedAt, a.UsedAt, a.CreatedAt)
+	return err
+}
+
+// GetPendingAuthorization возвращает запрос пользователя, который ещё ждёт согласия
+func (r *OAuthRepo) GetPendingAuthorization(ctx context.Context, userID, id uuid.UUID) (*domain.OAuthAuthorization, error) {
+	return scanOAuthAuthorization(r.pool.QueryRow(ctx, `
+        SELECT `+oauthAuthorizationColumns+` FROM "OAUTH_AUTHORIZATIONS"
+        WHERE id = $1 AND user_id = $2 AND approved_at IS NULL AND expires_at > $3
+    `, id, userID, time.Now().UTC()))
+}
+
+// ApproveAuthorization привязывает к ожидающему запросу код и сокращает срок до срока жизни кода
+func (r *OAuthRepo) ApproveAuthorization(ctx context.Context, userID, id uuid.UUID, codeHash string, expiresAt time.Time) (*domain.OAuthAuthorization, error) {
+	now := time.Now().UTC()
+	return scanOAuthAuthorization(r.pool.QueryRow(ctx, `
+        UPDATE "OAUTH_AUTHORIZATIONS"
+        SET code_hash = $3, approved_at = $4, expires_at = $5
+        WHERE id = $1 AND user_id = $2 AND approved_at IS NULL AND expires_at > $4
+        RETURNING `+oauthAuthorizationColumns,
+		id, userID, codeHash, now, expiresAt))
+}
+
+// DeleteAuthorization удаляет ожидающий запрос, от которого пользователь отказался
+func (r *OAuthRepo) DeleteAuthorization(ctx context.Context, userID, id uuid.UUID) (*domain.OAuthAuthorization, error) {
+	return scanOAuthAuthorization(r.pool.QueryRow(ctx, `
+        DELETE FROM "OAUTH_AUTHORIZATIONS"
+        WHERE id = $1 AND user_id = $2 AND approved_at IS NULL AND expires_at > $3
+        RETURNING `+oauthAuthorizationColumns,
+		id, userID, time.Now().UTC()))
+}
+
+// GetAuthorizationByCode возвращает запрос по хешу кода, не погашая код. Срок и использованность
+// не проверяются: это дело ConsumeAuthorizationCode
+func (r *OAuthRepo) GetAuthorizationByCode(ctx context.Context, codeHash string) (*domain.OAuthAuthorization, error) {
+	return scanOAuthAuthorization(r.pool.QueryRow(ctx, `
+        SELECT `+oauthAuthorizationColumns+` FROM "OAUTH_AUTHORIZATIONS" WHERE code_hash = $1
+    `, codeHash))
+}
+
+// ConsumeAuthorizationCode помечает код использованным и запоминает семейство токенов, выданных по нему.
+// Если код уже использован, возвращает запись вместе с ErrAuthorizationCodeUsed,
+// чтобы выданные по нему токены можно было отозвать
+func (r *OAuthRepo) ConsumeAuthorizationCode(ctx context.Context, codeHash string, familyID uuid.UUID) (*domain.OAuthAuthorization, error) {
+	now := time.Now().UTC()
+	a, err := scanOAuthAuthorization(r.pool.QueryRow(ctx, `
+        UPDATE "OAUTH_AUTHORIZATIONS"
+        SET used_at = $2, family_id = $3
+        WHERE code_hash = $1 AND used_at IS NULL AND expires_at > $2
+        RETURNING `+oauthAuthorizationColumns,
+		codeHash, now, familyID))
+	if !errors.Is(err, ErrAuthorizationNotFound) {
+		return a, err
+	}
+
+	a, err = scanOAuthAuthorization(r.pool.QueryRow(ctx, `
+        SELECT `+oauthAuthorizationColumns+` FROM "OAUTH_AUTHORIZATIONS"
+        WHERE code_hash = $1 AND used_at IS NOT NULL
+    `, codeHash))
+	if err != nil {
+		return nil, err
+	}
+	return a, ErrAuthorizationCodeUsed
+}
+
+// GetConsent возвращает scopes, на которые пользователь уже согласился для клиента
+func (r *OAuthRepo) GetConsent(ctx context.Context, userID uuid.UUID, clientID string) ([]string, error) {
+	var scopes []string
+	err := r.pool.QueryRow(ctx, `
+        SELECT scopes FROM "OAUTH_CONSENTS" WHERE user_id = $1 AND client_id = $2
+    `, userID, clientID).Scan(&scopes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return scopes, err
+}
+
+// SaveConsent запоминает согласие пользователя, заменяя прежнее
+func (r *OAuthRepo) SaveConsent(ctx context.Context, userID uuid.UUID, clientID string, scopes []string) error {
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO "OAUTH_CONSENTS" (user_id, client_id, scopes, granted_at)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (user_id, client_id) DO UPDATE SET scopes = EXCLUDED.scopes, granted_at = EXCLUDED.granted_at
+    `, userID, clientID, scopes, time.Now().UTC())
+	return err
+}
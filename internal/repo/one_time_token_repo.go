@@ -0,0 +1,122 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"jwt_auth_project/internal/domain"
+)
+
+// ErrOneTimeTokenInvalid — токен не найден, уже использован или истёк
+var ErrOneTimeTokenInvalid = errors.New("token is invalid or expired")
+
+type OneTimeTokenRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewOneTimeTokenRepo(pool *pgxpool.Pool) *OneTimeTokenRepo {
+	return &OneTimeTokenRepo{pool: pool}
+}
+
+type OneTimeTokenRepository interface {
+	CreateOneTimeToken(ctx context.Context, t domain.OneTimeToken) error
+	ConsumeOneTimeToken(ctx context.Context, id uuid.UUID, purpose string) (uuid.UUID, error)
+	ConsumeOneTimeTokenByHash(ctx context.Context, hash, purpose string) (uuid.UUID, error)
+	FindOneTimeToken(ctx context.Context, id uuid.UUID, purpose string) (uuid.UUID, error)
+	FindOneTimeTokenByHash(ctx context.Context, hash, purpose string) (uuid.UUID, error)
+	InvalidateUserTokens(ctx context.Context, userID uuid.UUID, purpose string) error
+	FailOneTimeToken(ctx context.Context, id uuid.UUID, purpose string, maxAttempts int) error
+}
+
+func (r *OneTimeTokenRepo) CreateOneTimeToken(ctx context.Context, t domain.OneTimeToken) error {
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO "ONE_TIME_TOKENS" (id, user_id, purpose, token_hash, expires_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+    `, t.ID, t.UserID, t.Purpose, t.TokenHash, t.ExpiresAt, t.CreatedAt)
+	return err
+}
+
+// ConsumeOneTimeToken атомарно помечает токен использованным и возвращает его владельца
+func (r *OneTimeTokenRepo) ConsumeOneTimeToken(ctx context.Context, id uuid.UUID, purpose string) (uuid.UUID, error) {
+	now := time.Now().UTC()
+	var userID uuid.UUID
+	err := r.pool.QueryRow(ctx, `
+        UPDATE "ONE_TIME_TOKENS"
+        SET used_at = $3
+        WHERE id = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+        RETURNING user_id
+    `, id, purpose, now).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, ErrOneTimeTokenInvalid
+	}
+	return userID, err
+}
+
+// ConsumeOneTimeTokenByHash — то же, что ConsumeOneTimeToken, для непрозрачных токенов, хранимых хешем
+func (r *OneTimeTokenRepo) ConsumeOneTimeTokenByHash(ctx context.Context, hash, purpose string) (uuid.UUID, error) {
+	now := time.Now().UTC()
+	var userID uuid.UUID
+	err := r.pool.QueryRow(ctx, `
+        UPDATE "ONE_TIME_TOKENS"
+        SET used_at = $3
+        WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+        RETURNING user_id
+    `, hash, purpose, now).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, ErrOneTimeTokenInvalid
+	}
+	return userID, err
+}
+
+// FindOneTimeToken возвращает владельца действующего токена, не погашая его
+func (r *OneTimeTokenRepo) FindOneTimeToken(ctx context.Context, id uuid.UUID, purpose string) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := r.pool.QueryRow(ctx, `
+        SELECT user_id FROM "ONE_TIME_TOKENS"
+        WHERE id = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+    `, id, purpose, time.Now().UTC()).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, ErrOneTimeTokenInvalid
+	}
+	return userID, err
+}
+
+// FindOneTimeTokenByHash возвращает владельца действующего токена, не погашая его
+func (r *OneTimeTokenRepo) FindOneTimeTokenByHash(ctx context.Context, hash, purpose string) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := r.pool.QueryRow(ctx, `
+        SELECT user_id FROM "ONE_TIME_TOKENS"
+        WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+    `, hash, purpose, time.Now().UTC()).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, ErrOneTimeTokenInvalid
+	}
+	return userID, err
+}
+
+// FailOneTimeToken засчитывает неудачную попытку воспользоваться токеном и гасит его
+// на maxAttempts-й попытке
+func (r *OneTimeTokenRepo) FailOneTimeToken(ctx context.Context, id uuid.UUID, purpose string, maxAttempts int) error {
+	_, err := r.pool.Exec(ctx, `
+        UPDATE "ONE_TIME_TOKENS"
+        SET failed_attempts = failed_attempts + 1,
+            used_at = CASE WHEN failed_attempts + 1 >= $3 THEN $4 ELSE used_at END
+        WHERE id = $1 AND purpose = $2 AND used_at IS NULL
+    `, id, purpose, maxAttempts, time.Now().UTC())
+	return err
+}
+
+// InvalidateUserTokens гасит все неиспользованные токены пользователя с назначением purpose
+func (r *OneTimeTokenRepo) InvalidateUserTokens(ctx context.Context, userID uuid.UUID, purpose string) error {
+	_, err := r.pool.Exec(ctx, `
+        UPDATE "ONE_TIME_TOKENS"
+        SET used_at = $3
+        WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+    `, userID, purpose, time.Now().UTC())
+	return err
+}
@@ -0,0 +1,114 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"jwt_auth_project/internal/domain"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	// ErrRefreshTokenUsed возвращается Rotate, если токен уже был использован (или отозван) параллельным запросом
+	ErrRefreshTokenUsed = errors.New("refresh token already used")
+)
+
+type RefreshTokenRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewRefreshTokenRepo(pool *pgxpool.Pool) *RefreshTokenRepo {
+	return &RefreshTokenRepo{pool: pool}
+}
+
+type RefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, t domain.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, hash string) (*domain.RefreshToken, error)
+	Rotate(ctx context.Context, oldID uuid.UUID, next domain.RefreshToken) error
+	RevokeFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeUserTokens(ctx context.Context, userID uuid.UUID) error
+}
+
+func (r *RefreshTokenRepo) CreateRefreshToken(ctx context.Context, t domain.RefreshToken) error {
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO "REFRESH_TOKENS" (id, user_id, family_id, token_hash, client_id, scopes, expires_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    `, t.ID, t.UserID, t.FamilyID, t.TokenHash, t.ClientID, t.Scopes, t.ExpiresAt, t.CreatedAt)
+	return err
+}
+
+func (r *RefreshTokenRepo) GetRefreshTokenByHash(ctx context.Context, hash string) (*domain.RefreshToken, error) {
+	t := new(domain.RefreshToken)
+	err := r.pool.QueryRow(ctx, `
+        SELECT id, user_id, family_id, token_hash, client_id, scopes, expires_at, used_at, revoked_at, created_at
+        FROM "REFRESH_TOKENS"
+        WHERE token_hash = $1
+    `, hash).Scan(
+		&t.ID, &t.UserID, &t.FamilyID, &t.TokenHash, &t.ClientID, &t.Scopes,
+		&t.ExpiresAt, &t.UsedAt, &t.RevokedAt, &t.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// Rotate в одной транзакции помечает старый токен использованным и сохраняет новый.
+// Если старый токен уже использован или отозван, возвращает ErrRefreshTokenUsed.
+func (r *RefreshTokenRepo) Rotate(ctx context.Context, oldID uuid.UUID, next domain.RefreshToken) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	cmd, err := tx.Exec(ctx, `
+        UPDATE "REFRESH_TOKENS"
+        SET used_at = $2
+        WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL
+    `, oldID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrRefreshTokenUsed
+	}
+
+	_, err = tx.Exec(ctx, `
+        INSERT INTO "REFRESH_TOKENS" (id, user_id, family_id, token_hash, client_id, scopes, expires_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    `, next.ID, next.UserID, next.FamilyID, next.TokenHash, next.ClientID, next.Scopes, next.ExpiresAt, next.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RevokeFamily отзывает все ещё не отозванные токены семейства
+func (r *RefreshTokenRepo) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := r.pool.Exec(ctx, `
+        UPDATE "REFRESH_TOKENS"
+        SET revoked_at = $2
+        WHERE family_id = $1 AND revoked_at IS NULL
+    `, familyID, time.Now().UTC())
+	return err
+}
+
+// RevokeUserTokens отзывает все refresh-токены пользователя
+func (r *RefreshTokenRepo) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.pool.Exec(ctx, `
+        UPDATE "REFRESH_TOKENS"
+        SET revoked_at = $2
+        WHERE user_id = $1 AND revoked_at IS NULL
+    `, userID, time.Now().UTC())
+	return err
+}
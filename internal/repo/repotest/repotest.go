@@ -0,0 +1,816 @@
+// Package repotest — хранилища в памяти для тестов usecase и обработчиков. Каждое повторяет семантику
+// своего репозитория в той мере, в какой на неё полагаются тесты; остальные методы интерфейса
+// не реализованы
+package repotest
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/mailer"
+	"jwt_auth_project/internal/repo"
+)
+
+type Users struct {
+	repo.UserRepository
+	mu    sync.Mutex
+	users map[uuid.UUID]*domain.User
+}
+
+func NewUsers() *Users {
+	return &Users{users: map[uuid.UUID]*domain.User{}}
+}
+
+func (f *Users) GetUserByEmail(_ context.Context, email string) (*domain.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if strings.EqualFold(u.Email, email) {
+			c := *u
+			return &c, nil
+		}
+	}
+	return nil, repo.ErrUserNotFound
+}
+
+func (f *Users) GetUserByID(_ context.Context, id uuid.UUID) (*domain.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u, ok := f.users[id]
+	if !ok {
+		return nil, repo.ErrUserNotFound
+	}
+	c := *u
+	return &c, nil
+}
+
+func (f *Users) CreateUser(_ context.Context, user domain.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if strings.EqualFold(u.Email, user.Email) || u.Username == user.Username {
+			return repo.ErrUserExists
+		}
+	}
+	f.users[user.ID] = &user
+	return nil
+}
+
+func (f *Users) DeleteUser(_ context.Context, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.users[id]; !ok {
+		return repo.ErrUserNotFound
+	}
+	delete(f.users, id)
+	return nil
+}
+
+func (f *Users) MarkEmailVerified(_ context.Context, id uuid.UUID, email string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u, ok := f.users[id]
+	if !ok || !strings.EqualFold(u.Email, email) {
+		return repo.ErrUserNotFound
+	}
+	now := time.Now().UTC()
+	u.EmailVerifiedAt = &now
+	return nil
+}
+
+func (f *Users) UpdatePassword(_ context.Context, id uuid.UUID, passwordHash string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u, ok := f.users[id]
+	if !ok {
+		return repo.ErrUserNotFound
+	}
+	u.Password = passwordHash
+	return nil
+}
+
+func (f *Users) UpdateUser(_ context.Context, user domain.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.users[user.ID]; !ok {
+		return repo.ErrUserNotFound
+	}
+	for _, u := range f.users {
+		if u.ID != user.ID && (strings.EqualFold(u.Email, user.Email) || u.Username == user.Username) {
+			return repo.ErrUserExists
+		}
+	}
+	f.users[user.ID] = &user
+	return nil
+}
+
+type RefreshTokens struct {
+	repo.RefreshTokenRepository
+	mu     sync.Mutex
+	tokens map[string]*domain.RefreshToken
+}
+
+func NewRefreshTokens() *RefreshTokens {
+	return &RefreshTokens{tokens: map[string]*domain.RefreshToken{}}
+}
+
+func (f *RefreshTokens) CreateRefreshToken(_ context.Context, t domain.RefreshToken) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tokens[t.TokenHash] = &t
+	return nil
+}
+
+func (f *RefreshTokens) GetRefreshTokenByHash(_ context.Context, hash string) (*domain.RefreshToken, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t, ok := f.tokens[hash]
+	if !ok {
+		return nil, repo.ErrRefreshTokenNotFound
+	}
+	c := *t
+	return &c, nil
+}
+
+func (f *RefreshTokens) Rotate(_ context.Context, oldID uuid.UUID, next domain.RefreshToken) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, t := range f.tokens {
+		if t.ID == oldID {
+			if t.UsedAt != nil || t.RevokedAt != nil {
+				return repo.ErrRefreshTokenUsed
+			}
+			now := time.Now().UTC()
+			t.UsedAt = &now
+			f.tokens[next.TokenHash] = &next
+			return nil
+		}
+	}
+	return repo.ErrRefreshTokenUsed
+}
+
+func (f *RefreshTokens) RevokeFamily(_ context.Context, familyID uuid.UUID) error {
+	f.revoke(func(t *domain.RefreshToken) bool { return t.FamilyID == familyID })
+	return nil
+}
+
+func (f *RefreshTokens) RevokeUserTokens(_ context.Context, userID uuid.UUID) error {
+	f.revoke(func(t *domain.RefreshToken) bool { return t.UserID == userID })
+	return nil
+}
+
+func (f *RefreshTokens) revoke(match func(*domain.RefreshToken) bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now().UTC()
+	for _, t := range f.tokens {
+		if t.RevokedAt == nil && match(t) {
+			t.RevokedAt = &now
+		}
+	}
+}
+
+type Sessions struct {
+	repo.SessionRepository
+	mu       sync.Mutex
+	sessions map[uuid.UUID]*domain.Session
+}
+
+func NewSessions() *Sessions {
+	return &Sessions{sessions: map[uuid.UUID]*domain.Session{}}
+}
+
+func (f *Sessions) TouchSession(_ context.Context, s domain.Session) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if existing, ok := f.sessions[s.ID]; ok {
+		existing.LastJTI = s.LastJTI
+		existing.LastSeenAt = s.LastSeenAt
+		return nil
+	}
+	s.CreatedAt = s.LastSeenAt
+	f.sessions[s.ID] = &s
+	return nil
+}
+
+func (f *Sessions) RevokeSessionByID(_ context.Context, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.sessions[id]; ok && s.RevokedAt == nil {
+		now := time.Now().UTC()
+		s.RevokedAt = &now
+	}
+	return nil
+}
+
+func (f *Sessions) RevokeUserSessions(_ context.Context, userID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now().UTC()
+	for _, s := range f.sessions {
+		if s.UserID == userID && s.RevokedAt == nil {
+			s.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (f *Sessions) IsSessionRevoked(_ context.Context, id uuid.UUID) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.sessions[id]
+	return ok && s.RevokedAt != nil, nil
+}
+
+// Revocations хранит отозванные jti и поколения токенов. Поколение — колонка пользователя,
+// поэтому у удалённого из users пользователя его нет
+type Revocations struct {
+	mu          sync.Mutex
+	users       *Users
+	revoked     map[uuid.UUID]bool
+	generations map[uuid.UUID]int
+}
+
+func NewRevocations(users *Users) *Revocations {
+	return &Revocations{users: users, revoked: map[uuid.UUID]bool{}, generations: map[uuid.UUID]int{}}
+}
+
+func (f *Revocations) userExists(userID uuid.UUID) bool {
+	f.users.mu.Lock()
+	defer f.users.mu.Unlock()
+	_, ok := f.users.users[userID]
+	return ok
+}
+
+func (f *Revocations) RevokeToken(_ context.Context, jti, _ uuid.UUID, _ time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.revoked[jti] = true
+	return nil
+}
+
+func (f *Revocations) IsTokenRevoked(_ context.Context, jti uuid.UUID) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.revoked[jti], nil
+}
+
+func (f *Revocations) GetTokenGeneration(_ context.Context, userID uuid.UUID) (int, error) {
+	if !f.userExists(userID) {
+		return 0, repo.ErrUserNotFound
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.generations[userID], nil
+}
+
+func (f *Revocations) BumpTokenGeneration(_ context.Context, userID uuid.UUID) (int, error) {
+	if !f.userExists(userID) {
+		return 0, repo.ErrUserNotFound
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.generations[userID]++
+	return f.generations[userID], nil
+}
+
+// Roles хранит роли пользователей. Пользователь без назначенных ролей считается обычным (user)
+type Roles struct {
+	mu    sync.Mutex
+	roles map[uuid.UUID][]string
+}
+
+func NewRoles() *Roles {
+	return &Roles{roles: map[uuid.UUID][]string{}}
+}
+
+// ListRoles возвращает роли user и admin с правами, как их заводят миграции
+func (f *Roles) ListRoles(context.Context) ([]domain.Role, error) {
+	return []domain.Role{
+		{Name: domain.RoleUser, Permissions: []string{domain.PermAdsWrite}},
+		{Name: domain.RoleAdmin, Permissions: []string{
+			domain.PermAdsWrite, domain.PermAdsModerate, domain.PermUsersManage, domain.PermClientsManage,
+		}},
+	}, nil
+}
+
+func (f *Roles) GetUserRoles(_ context.Context, userID uuid.UUID) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if roles, ok := f.roles[userID]; ok {
+		return roles, nil
+	}
+	return []string{domain.RoleUser}, nil
+}
+
+func (f *Roles) SetUserRoles(_ context.Context, userID uuid.UUID, roles []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.roles[userID] = roles
+	return nil
+}
+
+type oneTimeToken struct {
+	domain.OneTimeToken
+	failedAttempts int
+}
+
+type OneTimeTokens struct {
+	repo.OneTimeTokenRepository
+	mu     sync.Mutex
+	tokens map[uuid.UUID]*oneTimeToken
+}
+
+func NewOneTimeTokens() *OneTimeTokens {
+	return &OneTimeTokens{tokens: map[uuid.UUID]*oneTimeToken{}}
+}
+
+func (f *OneTimeTokens) CreateOneTimeToken(_ context.Context, t domain.OneTimeToken) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tokens[t.ID] = &oneTimeToken{OneTimeToken: t}
+	return nil
+}
+
+// live ищет действующий токен. Вызывается под f.mu
+func (f *OneTimeTokens) live(match func(*oneTimeToken) bool) *oneTimeToken {
+	now := time.Now()
+	for _, t := range f.tokens {
+		if t.UsedAt == nil && now.Before(t.ExpiresAt) && match(t) {
+			return t
+		}
+	}
+	return nil
+}
+
+func (f *OneTimeTokens) consume(match func(*oneTimeToken) bool) (uuid.UUID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := f.live(match)
+	if t == nil {
+		return uuid.Nil, repo.ErrOneTimeTokenInvalid
+	}
+	now := time.Now().UTC()
+	t.UsedAt = &now
+	return t.UserID, nil
+}
+
+func (f *OneTimeTokens) find(match func(*oneTimeToken) bool) (uuid.UUID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := f.live(match)
+	if t == nil {
+		return uuid.Nil, repo.ErrOneTimeTokenInvalid
+	}
+	return t.UserID, nil
+}
+
+func byID(id uuid.UUID, purpose string) func(*oneTimeToken) bool {
+	return func(t *oneTimeToken) bool { return t.ID == id && t.Purpose == purpose }
+}
+
+func byHash(hash, purpose string) func(*oneTimeToken) bool {
+	return func(t *oneTimeToken) bool {
+		return t.TokenHash != nil && *t.TokenHash == hash && t.Purpose == purpose
+	}
+}
+
+func (f *OneTimeTokens) ConsumeOneTimeToken(_ context.Context, id uuid.UUID, purpose string) (uuid.UUID, error) {
+	return f.consume(byID(id, purpose))
+}
+
+func (f *OneTimeTokens) ConsumeOneTimeTokenByHash(_ context.Context, hash, purpose string) (uuid.UUID, error) {
+	return f.consume(byHash(hash, purpose))
+}
+
+func (f *OneTimeTokens) FindOneTimeToken(_ context.Context, id uuid.UUID, purpose string) (uuid.UUID, error) {
+	return f.find(byID(id, purpose))
+}
+
+func (f *OneTimeTokens) FindOneTimeTokenByHash(_ context.Context, hash, purpose string) (uuid.UUID, error) {
+	return f.find(byHash(hash, purpose))
+}
+
+func (f *OneTimeTokens) FailOneTimeToken(_ context.Context, id uuid.UUID, purpose string, maxAttempts int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if t, ok := f.tokens[id]; ok && t.Purpose == purpose && t.UsedAt == nil {
+		t.failedAttempts++
+		if t.failedAttempts >= maxAttempts {
+			now := time.Now().UTC()
+			t.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+func (f *OneTimeTokens) InvalidateUserTokens(_ context.Context, userID uuid.UUID, purpose string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now().UTC()
+	for _, t := range f.tokens {
+		if t.UserID == userID && t.Purpose == purpose && t.UsedAt == nil {
+			t.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+// MFA — ни у кого не включён второй фактор
+type MFA struct {
+	repo.MFARepository
+}
+
+func (MFA) GetMFA(context.Context, uuid.UUID) (*domain.MFA, error) {
+	return nil, repo.ErrMFANotFound
+}
+
+type WebAuthn struct {
+	mu          sync.Mutex
+	challenges  map[uuid.UUID]domain.WebAuthnChallenge
+	credentials []domain.WebAuthnCredential
+}
+
+func NewWebAuthn() *WebAuthn {
+	return &WebAuthn{challenges: map[uuid.UUID]domain.WebAuthnChallenge{}}
+}
+
+func (f *WebAuthn) CreateChallenge(_ context.Context, c domain.WebAuthnChallenge) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.challenges[c.ID] = c
+	return nil
+}
+
+func (f *WebAuthn) ConsumeChallenge(_ context.Context, id uuid.UUID, purpose string) (*domain.WebAuthnChallenge, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.challenges[id]
+	if !ok || c.Purpose != purpose || !time.Now().Before(c.ExpiresAt) {
+		return nil, repo.ErrChallengeInvalid
+	}
+	delete(f.challenges, id)
+	return &c, nil
+}
+
+// ExpireChallenge переводит срок действия challenge в прошлое
+func (f *WebAuthn) ExpireChallenge(id uuid.UUID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := f.challenges[id]
+	c.ExpiresAt = time.Now().Add(-time.Second)
+	f.challenges[id] = c
+}
+
+func (f *WebAuthn) CreateCredential(_ context.Context, c domain.WebAuthnCredential) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, existing := range f.credentials {
+		if bytes.Equal(existing.CredentialID, c.CredentialID) {
+			return repo.ErrCredentialExists
+		}
+	}
+	f.credentials = append(f.credentials, c)
+	return nil
+}
+
+func (f *WebAuthn) GetCredential(_ context.Context, credentialID []byte) (*domain.WebAuthnCredential, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.credentials {
+		if bytes.Equal(c.CredentialID, credentialID) {
+			return &c, nil
+		}
+	}
+	return nil, repo.ErrCredentialNotFound
+}
+
+func (f *WebAuthn) ListCredentials(_ context.Context, userID uuid.UUID) ([]domain.WebAuthnCredential, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	creds := []domain.WebAuthnCredential{}
+	for _, c := range f.credentials {
+		if c.UserID == userID {
+			creds = append(creds, c)
+		}
+	}
+	return creds, nil
+}
+
+func (f *WebAuthn) UpdateSignCount(_ context.Context, id uuid.UUID, signCount int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.credentials {
+		if f.credentials[i].ID == id {
+			now := time.Now().UTC()
+			f.credentials[i].SignCount = signCount
+			f.credentials[i].LastUsedAt = &now
+		}
+	}
+	return nil
+}
+
+func (f *WebAuthn) DeleteCredential(_ context.Context, userID, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, c := range f.credentials {
+		if c.ID == id && c.UserID == userID {
+			f.credentials = append(f.credentials[:i], f.credentials[i+1:]...)
+			return nil
+		}
+	}
+	return repo.ErrCredentialNotFound
+}
+
+type OAuth struct {
+	mu             sync.Mutex
+	clients        map[string]domain.OAuthClient
+	authorizations map[uuid.UUID]*domain.OAuthAuthorization
+	consents       map[string][]string
+}
+
+func NewOAuth() *OAuth {
+	return &OAuth{
+		clients:        map[string]domain.OAuthClient{},
+		authorizations: map[uuid.UUID]*domain.OAuthAuthorization{},
+		consents:       map[string][]string{},
+	}
+}
+
+func (f *OAuth) CreateClient(_ context.Context, c domain.OAuthClient) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clients[c.ID] = c
+	return nil
+}
+
+func (f *OAuth) GetClient(_ context.Context, id string) (*domain.OAuthClient, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.clients[id]
+	if !ok {
+		return nil, repo.ErrOAuthClientNotFound
+	}
+	return &c, nil
+}
+
+func (f *OAuth) ListClients(context.Context) ([]domain.OAuthClient, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clients := []domain.OAuthClient{}
+	for _, c := range f.clients {
+		clients = append(clients, c)
+	}
+	return clients, nil
+}
+
+func (f *OAuth) DeleteClient(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.clients[id]; !ok {
+		return repo.ErrOAuthClientNotFound
+	}
+	delete(f.clients, id)
+	return nil
+}
+
+func (f *OAuth) CreateAuthorization(_ context.Context, a domain.OAuthAuthorization) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.authorizations[a.ID] = &a
+	return nil
+}
+
+// pending ищет запрос пользователя, ждущий согласия. Вызывается под f.mu
+func (f *OAuth) pending(userID, id uuid.UUID) (*domain.OAuthAuthorization, error) {
+	a, ok := f.authorizations[id]
+	if !ok || a.UserID != userID || a.ApprovedAt != nil || !time.Now().Before(a.ExpiresAt) {
+		return nil, repo.ErrAuthorizationNotFound
+	}
+	return a, nil
+}
+
+func (f *OAuth) GetPendingAuthorization(_ context.Context, userID, id uuid.UUID) (*domain.OAuthAuthorization, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a, err := f.pending(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	c := *a
+	return &c, nil
+}
+
+func (f *OAuth) ApproveAuthorization(_ context.Context, userID, id uuid.UUID, codeHash string, expiresAt time.Time) (*domain.OAuthAuthorization, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a, err := f.pending(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	a.CodeHash = &codeHash
+	a.ApprovedAt = &now
+	a.ExpiresAt = expiresAt
+	c := *a
+	return &c, nil
+}
+
+func (f *OAuth) DeleteAuthorization(_ context.Context, userID, id uuid.UUID) (*domain.OAuthAuthorization, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a, err := f.pending(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	delete(f.authorizations, id)
+	return a, nil
+}
+
+// byCode ищет запрос по хешу кода. Вызывается под f.mu
+func (f *OAuth) byCode(codeHash string) *domain.OAuthAuthorization {
+	for _, a := range f.authorizations {
+		if a.CodeHash != nil && *a.CodeHash == codeHash {
+			return a
+		}
+	}
+	return nil
+}
+
+func (f *OAuth) GetAuthorizationByCode(_ context.Context, codeHash string) (*domain.OAuthAuthorization, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a := f.byCode(codeHash)
+	if a == nil {
+		return nil, repo.ErrAuthorizationNotFound
+	}
+	c := *a
+	return &c, nil
+}
+
+func (f *OAuth) ConsumeAuthorizationCode(_ context.Context, codeHash string, familyID uuid.UUID) (*domain.OAuthAuthorization, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a := f.byCode(codeHash)
+	if a == nil {
+		return nil, repo.ErrAuthorizationNotFound
+	}
+	if a.UsedAt != nil {
+		c := *a
+		return &c, repo.ErrAuthorizationCodeUsed
+	}
+	if !time.Now().Before(a.ExpiresAt) {
+		return nil, repo.ErrAuthorizationNotFound
+	}
+	now := time.Now().UTC()
+	a.UsedAt = &now
+	a.FamilyID = &familyID
+	c := *a
+	return &c, nil
+}
+
+func (f *OAuth) GetConsent(_ context.Context, userID uuid.UUID, clientID string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.consents[userID.String()+"/"+clientID], nil
+}
+
+func (f *OAuth) SaveConsent(_ context.Context, userID uuid.UUID, clientID string, scopes []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.consents[userID.String()+"/"+clientID] = scopes
+	return nil
+}
+
+// Identities хранит привязки аккаунтов провайдеров и начатые входы через них
+type Identities struct {
+	mu         sync.Mutex
+	identities map[uuid.UUID]*domain.UserIdentity
+	states     map[string]domain.SocialLoginState
+}
+
+func NewIdentities() *Identities {
+	return &Identities{identities: map[uuid.UUID]*domain.UserIdentity{}, states: map[string]domain.SocialLoginState{}}
+}
+
+func (f *Identities) CreateIdentity(_ context.Context, i domain.UserIdentity) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for id, existing := range f.identities {
+		if existing.Provider == i.Provider && existing.Subject == i.Subject {
+			if existing.LinkedAt != nil {
+				return repo.ErrIdentityExists
+			}
+			delete(f.identities, id)
+		}
+	}
+	f.identities[i.ID] = &i
+	return nil
+}
+
+func (f *Identities) GetLinkedIdentity(_ context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, i := range f.identities {
+		if i.Provider == provider && i.Subject == subject && i.LinkedAt != nil {
+			c := *i
+			return &c, nil
+		}
+	}
+	return nil, repo.ErrIdentityNotFound
+}
+
+func (f *Identities) ConfirmIdentity(_ context.Context, id uuid.UUID) (*domain.UserIdentity, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i, ok := f.identities[id]
+	if !ok || i.LinkedAt != nil {
+		return nil, repo.ErrIdentityNotFound
+	}
+	now := time.Now().UTC()
+	i.LinkedAt = &now
+	c := *i
+	return &c, nil
+}
+
+func (f *Identities) ListIdentities(_ context.Context, userID uuid.UUID) ([]domain.UserIdentity, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	identities := []domain.UserIdentity{}
+	for _, i := range f.identities {
+		if i.UserID == userID && i.LinkedAt != nil {
+			identities = append(identities, *i)
+		}
+	}
+	return identities, nil
+}
+
+func (f *Identities) DeleteIdentity(_ context.Context, userID, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if i, ok := f.identities[id]; !ok || i.UserID != userID {
+		return repo.ErrIdentityNotFound
+	}
+	delete(f.identities, id)
+	return nil
+}
+
+func (f *Identities) TouchIdentity(_ context.Context, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if i, ok := f.identities[id]; ok {
+		now := time.Now().UTC()
+		i.LastLoginAt = &now
+	}
+	return nil
+}
+
+func (f *Identities) CreateSocialLoginState(_ context.Context, s domain.SocialLoginState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.states[s.StateHash] = s
+	return nil
+}
+
+func (f *Identities) ConsumeSocialLoginState(_ context.Context, stateHash, provider string) (*domain.SocialLoginState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.states[stateHash]
+	if !ok || s.Provider != provider || !s.ExpiresAt.After(time.Now()) {
+		return nil, repo.ErrSocialStateInvalid
+	}
+	delete(f.states, stateHash)
+	return &s, nil
+}
+
+// Mailer запоминает отправленные письма
+type Mailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (f *Mailer) Send(_ context.Context, msg mailer.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+// To возвращает письма, отправленные на адрес
+func (f *Mailer) To(addr string) []mailer.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []mailer.Message
+	for _, m := range f.sent {
+		if m.To == addr {
+			out = append(out, m)
+		}
+	}
+	return out
+}
@@ -0,0 +1,133 @@
+package repo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CachedRevocationRepo — in-memory кеш поверх RevocationRepository.
+// Отозванные jti кешируются до истечения токена (отзыв необратим), отрицательные ответы
+// и поколения пользователей — на ttl, чтобы отзывы с других инстансов подхватывались быстро.
+type CachedRevocationRepo struct {
+	next RevocationRepository
+	ttl  time.Duration
+
+	mu          sync.RWMutex
+	revoked     map[uuid.UUID]time.Time // jti -> до какого момента держать в кеше
+	notRevoked  map[uuid.UUID]time.Time
+	generations map[uuid.UUID]cachedGeneration
+	lastSweep   time.Time
+}
+
+type cachedGeneration struct {
+	value   int
+	expires time.Time
+}
+
+func NewCachedRevocationRepo(next RevocationRepository, ttl time.Duration) *CachedRevocationRepo {
+	return &CachedRevocationRepo{
+		next:        next,
+		ttl:         ttl,
+		revoked:     make(map[uuid.UUID]time.Time),
+		notRevoked:  make(map[uuid.UUID]time.Time),
+		generations: make(map[uuid.UUID]cachedGeneration),
+		lastSweep:   time.Now(),
+	}
+}
+
+func (c *CachedRevocationRepo) RevokeToken(ctx context.Context, jti, userID uuid.UUID, expiresAt time.Time) error {
+	if err := c.next.RevokeToken(ctx, jti, userID, expiresAt); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.revoked[jti] = expiresAt
+	delete(c.notRevoked, jti)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *CachedRevocationRepo) IsTokenRevoked(ctx context.Context, jti uuid.UUID) (bool, error) {
+	now := time.Now()
+	c.mu.RLock()
+	if _, ok := c.revoked[jti]; ok {
+		c.mu.RUnlock()
+		return true, nil
+	}
+	if until, ok := c.notRevoked[jti]; ok && now.Before(until) {
+		c.mu.RUnlock()
+		return false, nil
+	}
+	c.mu.RUnlock()
+
+	revoked, err := c.next.IsTokenRevoked(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweepLocked(now)
+	if revoked {
+		// Срок жизни токена тут неизвестен, держим запись как минимум ttl
+		c.revoked[jti] = now.Add(c.ttl)
+	} else {
+		c.notRevoked[jti] = now.Add(c.ttl)
+	}
+	return revoked, nil
+}
+
+func (c *CachedRevocationRepo) GetTokenGeneration(ctx context.Context, userID uuid.UUID) (int, error) {
+	now := time.Now()
+	c.mu.RLock()
+	g, ok := c.generations[userID]
+	c.mu.RUnlock()
+	if ok && now.Before(g.expires) {
+		return g.value, nil
+	}
+
+	gen, err := c.next.GetTokenGeneration(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	c.generations[userID] = cachedGeneration{value: gen, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return gen, nil
+}
+
+func (c *CachedRevocationRepo) BumpTokenGeneration(ctx context.Context, userID uuid.UUID) (int, error) {
+	gen, err := c.next.BumpTokenGeneration(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	c.generations[userID] = cachedGeneration{value: gen, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return gen, nil
+}
+
+// sweepLocked раз в ttl удаляет устаревшие записи. Вызывается под c.mu
+func (c *CachedRevocationRepo) sweepLocked(now time.Time) {
+	if now.Sub(c.lastSweep) < c.ttl {
+		return
+	}
+	c.lastSweep = now
+	for jti, until := range c.revoked {
+		if now.After(until) {
+			delete(c.revoked, jti)
+		}
+	}
+	for jti, until := range c.notRevoked {
+		if now.After(until) {
+			delete(c.notRevoked, jti)
+		}
+	}
+	for id, g := range c.generations {
+		if now.After(g.expires) {
+			delete(c.generations, id)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RevocationRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewRevocationRepo(pool *pgxpool.Pool) *RevocationRepo {
+	return &RevocationRepo{pool: pool}
+}
+
+// RevocationRepository хранит отозванные jti и поколение токенов каждого пользователя
+type RevocationRepository interface {
+	RevokeToken(ctx context.Context, jti, userID uuid.UUID, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti uuid.UUID) (bool, error)
+	GetTokenGeneration(ctx context.Context, userID uuid.UUID) (int, error)
+	BumpTokenGeneration(ctx context.Context, userID uuid.UUID) (int, error)
+}
+
+func (r *RevocationRepo) RevokeToken(ctx context.Context, jti, userID uuid.UUID, expiresAt time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO "REVOKED_TOKENS" (jti, user_id, expires_at, revoked_at)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (jti) DO NOTHING
+    `, jti, userID, expiresAt.UTC(), time.Now().UTC())
+	return err
+}
+
+func (r *RevocationRepo) IsTokenRevoked(ctx context.Context, jti uuid.UUID) (bool, error) {
+	var revoked bool
+	err := r.pool.QueryRow(ctx, `
+        SELECT EXISTS (SELECT 1 FROM "REVOKED_TOKENS" WHERE jti = $1)
+    `, jti).Scan(&revoked)
+	return revoked, err
+}
+
+func (r *RevocationRepo) GetTokenGeneration(ctx context.Context, userID uuid.UUID) (int, error) {
+	var gen int
+	err := r.pool.QueryRow(ctx, `SELECT token_generation FROM "USER" WHERE id = $1`, userID).Scan(&gen)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrUserNotFound
+	}
+	return gen, err
+}
+
+// BumpTokenGeneration увеличивает поколение токенов пользователя и возвращает новое значение
+func (r *RevocationRepo) BumpTokenGeneration(ctx context.Context, userID uuid.UUID) (int, error) {
+	var gen int
+	err := r.pool.QueryRow(ctx, `
+        UPDATE "USER"
+        SET token_generation = token_generation + 1
+        WHERE id = $1
+        RETURNING token_generation
+    `, userID).Scan(&gen)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrUserNotFound
+	}
+	return gen, err
+}
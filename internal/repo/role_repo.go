@@ -0,0 +1,106 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"jwt_auth_project/internal/domain"
+)
+
+var ErrUnknownRole = errors.New("unknown role")
+
+type RoleRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewRoleRepo(pool *pgxpool.Pool) *RoleRepo {
+	return &RoleRepo{pool: pool}
+}
+
+type RoleRepository interface {
+	ListRoles(ctx context.Context) ([]domain.Role, error)
+	GetUserRoles(ctx context.Context, userID uuid.UUID) ([]string, error)
+	SetUserRoles(ctx context.Context, userID uuid.UUID, roles []string) error
+}
+
+// ListRoles возвращает все роли вместе с их правами
+func (r *RoleRepo) ListRoles(ctx context.Context) ([]domain.Role, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT r.name, r.description, COALESCE(array_agg(p.permission ORDER BY p.permission)
+                   FILTER (WHERE p.permission IS NOT NULL), '{}')
+        FROM "ROLES" r
+        LEFT JOIN "ROLE_PERMISSIONS" p ON p.role = r.name
+        GROUP BY r.name, r.description
+        ORDER BY r.name
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []domain.Role
+	for rows.Next() {
+		var role domain.Role
+		if err := rows.Scan(&role.Name, &role.Description, &role.Permissions); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (r *RoleRepo) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT role FROM "USER_ROLES" WHERE user_id = $1 ORDER BY role
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// SetUserRoles заменяет набор ролей пользователя. Для несуществующего пользователя — ErrUserNotFound
+func (r *RoleRepo) SetUserRoles(ctx context.Context, userID uuid.UUID, roles []string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var known int
+	if err := tx.QueryRow(ctx, `SELECT count(*) FROM "ROLES" WHERE name = ANY($1)`, roles).Scan(&known); err != nil {
+		return err
+	}
+	if known != len(roles) {
+		return ErrUnknownRole
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM "USER_ROLES" WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+        INSERT INTO "USER_ROLES" (user_id, role)
+        SELECT $1, unnest($2::text[])
+    `, userID, roles); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return tx.Commit(ctx)
+}
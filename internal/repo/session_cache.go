@@ -0,0 +1,110 @@
+package repo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CachedSessionRepo кеширует проверку отзыва сессий, которая выполняется на каждый запрос.
+// Отзыв необратим, поэтому отозванные сессии кешируются насовсем (до очистки), действующие — на ttl.
+// Отзывы на этом инстансе применяются сразу, с других — не позже чем через ttl
+type CachedSessionRepo struct {
+	SessionRepository
+	ttl time.Duration
+
+	mu        sync.RWMutex
+	revoked   map[uuid.UUID]struct{}
+	active    map[uuid.UUID]time.Time
+	lastSweep time.Time
+}
+
+func NewCachedSessionRepo(next SessionRepository, ttl time.Duration) *CachedSessionRepo {
+	return &CachedSessionRepo{
+		SessionRepository: next,
+		ttl:               ttl,
+		revoked:           make(map[uuid.UUID]struct{}),
+		active:            make(map[uuid.UUID]time.Time),
+		lastSweep:         time.Now(),
+	}
+}
+
+func (c *CachedSessionRepo) IsSessionRevoked(ctx context.Context, id uuid.UUID) (bool, error) {
+	now := time.Now()
+	c.mu.RLock()
+	if _, ok := c.revoked[id]; ok {
+		c.mu.RUnlock()
+		return true, nil
+	}
+	if until, ok := c.active[id]; ok && now.Before(until) {
+		c.mu.RUnlock()
+		return false, nil
+	}
+	c.mu.RUnlock()
+
+	revoked, err := c.SessionRepository.IsSessionRevoked(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweepLocked(now)
+	if revoked {
+		c.revoked[id] = struct{}{}
+	} else {
+		c.active[id] = now.Add(c.ttl)
+	}
+	return revoked, nil
+}
+
+func (c *CachedSessionRepo) RevokeSession(ctx context.Context, userID, id uuid.UUID) error {
+	if err := c.SessionRepository.RevokeSession(ctx, userID, id); err != nil {
+		return err
+	}
+	c.markRevoked(id)
+	return nil
+}
+
+func (c *CachedSessionRepo) RevokeSessionByID(ctx context.Context, id uuid.UUID) error {
+	if err := c.SessionRepository.RevokeSessionByID(ctx, id); err != nil {
+		return err
+	}
+	c.markRevoked(id)
+	return nil
+}
+
+// RevokeUserSessions не трогает кеш: сессии пользователя в нём не сгруппированы, а его
+// токены к этому моменту уже отозваны сменой поколения (см. userUseCase.LogoutAll)
+func (c *CachedSessionRepo) RevokeUserSessions(ctx context.Context, userID uuid.UUID) error {
+	return c.SessionRepository.RevokeUserSessions(ctx, userID)
+}
+
+func (c *CachedSessionRepo) markRevoked(id uuid.UUID) {
+	c.mu.Lock()
+	c.revoked[id] = struct{}{}
+	delete(c.active, id)
+	c.mu.Unlock()
+}
+
+// sweepLocked раз в ttl удаляет устаревшие записи. Отозванные сессии держим, пока кеш
+// не разрастётся: повторно они приходят только с украденными или старыми токенами. Вызывается под c.mu
+func (c *CachedSessionRepo) sweepLocked(now time.Time) {
+	if now.Sub(c.lastSweep) < c.ttl {
+		return
+	}
+	c.lastSweep = now
+	for id, until := range c.active {
+		if now.After(until) {
+			delete(c.active, id)
+		}
+	}
+	if len(c.revoked) > maxCachedRevokedSessions {
+		c.revoked = make(map[uuid.UUID]struct{})
+	}
+}
+
+// maxCachedRevokedSessions ограничивает память под отозванные сессии
+const maxCachedRevokedSessions = 100_000
@@ -0,0 +1,119 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"jwt_auth_project/internal/domain"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewSessionRepo(pool *pgxpool.Pool) *SessionRepo {
+	return &SessionRepo{pool: pool}
+}
+
+type SessionRepository interface {
+	// TouchSession создаёт сессию или обновляет её last_seen_at, адрес, user agent и последний jti
+	TouchSession(ctx context.Context, s domain.Session) error
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]domain.Session, error)
+	RevokeSession(ctx context.Context, userID, id uuid.UUID) error
+	RevokeSessionByID(ctx context.Context, id uuid.UUID) error
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID) error
+	IsSessionRevoked(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+func (r *SessionRepo) TouchSession(ctx context.Context, s domain.Session) error {
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO "SESSIONS" (id, user_id, user_agent, ip, last_jti, created_at, last_seen_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $6)
+        ON CONFLICT (id) DO UPDATE
+        SET user_agent = EXCLUDED.user_agent,
+            ip = EXCLUDED.ip,
+            last_jti = EXCLUDED.last_jti,
+            last_seen_at = EXCLUDED.last_seen_at
+        WHERE "SESSIONS".user_id = EXCLUDED.user_id
+    `, s.ID, s.UserID, s.UserAgent, s.IP, s.LastJTI, s.LastSeenAt)
+	return err
+}
+
+// ListSessions возвращает действующие сессии пользователя, последние активные — первыми
+func (r *SessionRepo) ListSessions(ctx context.Context, userID uuid.UUID) ([]domain.Session, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT id, user_id, user_agent, ip, last_jti, created_at, last_seen_at, revoked_at
+        FROM "SESSIONS"
+        WHERE user_id = $1 AND revoked_at IS NULL
+        ORDER BY last_seen_at DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []domain.Session{}
+	for rows.Next() {
+		var s domain.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.LastJTI, &s.CreatedAt, &s.LastSeenAt, &s.RevokedAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession отзывает сессию пользователя. Чужие и уже отозванные сессии не находятся
+func (r *SessionRepo) RevokeSession(ctx context.Context, userID, id uuid.UUID) error {
+	cmd, err := r.pool.Exec(ctx, `
+        UPDATE "SESSIONS"
+        SET revoked_at = $3
+        WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+    `, id, userID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeSessionByID отзывает сессию без проверки владельца — для выхода и обнаруженной кражи токена
+func (r *SessionRepo) RevokeSessionByID(ctx context.Context, id uuid.UUID) error {
+	_, err := r.pool.Exec(ctx, `
+        UPDATE "SESSIONS"
+        SET revoked_at = $2
+        WHERE id = $1 AND revoked_at IS NULL
+    `, id, time.Now().UTC())
+	return err
+}
+
+func (r *SessionRepo) RevokeUserSessions(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.pool.Exec(ctx, `
+        UPDATE "SESSIONS"
+        SET revoked_at = $2
+        WHERE user_id = $1 AND revoked_at IS NULL
+    `, userID, time.Now().UTC())
+	return err
+}
+
+// IsSessionRevoked сообщает, отозвана ли сессия. Неизвестная сессия считается отозванной
+func (r *SessionRepo) IsSessionRevoked(ctx context.Context, id uuid.UUID) (bool, error) {
+	var revokedAt *time.Time
+	err := r.pool.QueryRow(ctx, `SELECT revoked_at FROM "SESSIONS" WHERE id = $1`, id).Scan(&revokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return revokedAt != nil, nil
+}
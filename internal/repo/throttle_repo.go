@@ -0,0 +1,102 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"jwt_auth_project/internal/throttle"
+)
+
+// ThrottleRepo — хранилище счётчиков неудачных попыток в Postgres, общее для всех инстансов
+type ThrottleRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewThrottleRepo(pool *pgxpool.Pool) *ThrottleRepo {
+	return &ThrottleRepo{pool: pool}
+}
+
+// Reserve учитывает попытку одной транзакцией. UPSERT блокирует строку ключа до коммита, поэтому
+// параллельная попытка по тому же ключу дождётся выставленной здесь блокировки и увидит её
+func (r *ThrottleRepo) Reserve(ctx context.Context, key string, now time.Time, window time.Duration, lockFor func(int) time.Duration) (throttle.Entry, bool, error) {
+	now = now.UTC()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return throttle.Entry{}, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Заблокированный ключ не меняется; last_failure хранит время последней учтённой попытки
+	var (
+		e           throttle.Entry
+		lockedUntil *time.Time
+		locked      bool
+	)
+	err = tx.QueryRow(ctx, `
+        INSERT INTO "LOGIN_THROTTLE" (key, failures, last_failure)
+        VALUES ($1, 1, $2)
+        ON CONFLICT (key) DO UPDATE
+        SET failures = CASE
+                WHEN "LOGIN_THROTTLE".locked_until > $2 THEN "LOGIN_THROTTLE".failures
+                WHEN "LOGIN_THROTTLE".last_failure < $3 THEN 1
+                ELSE "LOGIN_THROTTLE".failures + 1
+            END,
+            last_failure = CASE
+                WHEN "LOGIN_THROTTLE".locked_until > $2 THEN "LOGIN_THROTTLE".last_failure
+                ELSE EXCLUDED.last_failure
+            END
+        RETURNING failures, locked_until, COALESCE(locked_until > $2, FALSE)
+    `, key, now, now.Add(-window)).Scan(&e.Failures, &lockedUntil, &locked)
+	if err != nil {
+		return throttle.Entry{}, false, err
+	}
+	if lockedUntil != nil {
+		e.LockedUntil = *lockedUntil
+	}
+	if locked {
+		return e, false, nil
+	}
+
+	if d := lockFor(e.Failures); d > 0 {
+		// Время берётся из RETURNING, чтобы Release сравнивал его с хранимым без потери точности
+		if err := tx.QueryRow(ctx, `
+            UPDATE "LOGIN_THROTTLE" SET locked_until = $2 WHERE key = $1
+            RETURNING locked_until
+        `, key, now.Add(d)).Scan(&e.LockedUntil); err != nil {
+			return throttle.Entry{}, false, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return throttle.Entry{}, false, err
+	}
+
+	// Попутно удаляем давно забытые ключи
+	if _, err := r.pool.Exec(ctx, `
+        DELETE FROM "LOGIN_THROTTLE"
+        WHERE last_failure < $1 AND (locked_until IS NULL OR locked_until < $2)
+    `, now.Add(-window), now); err != nil {
+		return throttle.Entry{}, false, err
+	}
+	return e, true, nil
+}
+
+func (r *ThrottleRepo) Release(ctx context.Context, key string, lockedUntil time.Time) error {
+	var until *time.Time
+	if !lockedUntil.IsZero() {
+		until = &lockedUntil
+	}
+	_, err := r.pool.Exec(ctx, `
+        UPDATE "LOGIN_THROTTLE"
+        SET failures = GREATEST(failures - 1, 0),
+            locked_until = CASE WHEN locked_until = $2 THEN NULL ELSE locked_until END
+        WHERE key = $1
+    `, key, until)
+	return err
+}
+
+func (r *ThrottleRepo) Reset(ctx context.Context, key string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM "LOGIN_THROTTLE" WHERE key = $1`, key)
+	return err
+}
@@ -3,9 +3,17 @@ package repo
 import (
 	"context"
 	"errors"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"jwt_auth_project/internal/domain"
+	"time"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists   = errors.New("username or email already taken")
 )
 
 type UserRepo struct {
@@ -19,15 +27,19 @@ func NewUserRepo(pool *pgxpool.Pool) *UserRepo {
 
 type UserRepository interface {
 	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
-	GetUserByID(ctx context.Context, id int64) (*domain.User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (*domain.User, error)
 	CreateUser(ctx context.Context, user domain.User) error
+	MarkEmailVerified(ctx context.Context, id uuid.UUID, email string) error
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+	UpdateUser(ctx context.Context, user domain.User) error
+	DeleteUser(ctx context.Context, id uuid.UUID) error
 }
 
 func (r *UserRepo) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
 	u := new(domain.User)
 	err := r.pool.
 		QueryRow(ctx,
-			`SELECT id, username, email, password_hash, created_at
+			`SELECT id, username, email, password_hash, email_verified_at, created_at
              FROM "USER"
              WHERE email = $1`, email).
 		Scan(
@@ -35,11 +47,12 @@ func (r *UserRepo) GetUserByEmail(ctx context.Context, email string) (*domain.Us
 			&u.Username,
 			&u.Email,
 			&u.Password,
+			&u.EmailVerifiedAt,
 			&u.CreatedAt,
 		)
 
 	if err == pgx.ErrNoRows {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 	if err != nil {
 		return nil, err
@@ -47,11 +60,11 @@ func (r *UserRepo) GetUserByEmail(ctx context.Context, email string) (*domain.Us
 	return u, nil
 }
 
-func (r *UserRepo) GetUserByID(ctx context.Context, id int64) (*domain.User, error) {
+func (r *UserRepo) GetUserByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
 	u := new(domain.User)
 	err := r.pool.
 		QueryRow(ctx,
-			`SELECT id, username, email, password_hash, created_at
+			`SELECT id, username, email, password_hash, email_verified_at, created_at
              FROM "USER"
              WHERE id = $1`, id).
 		Scan(
@@ -59,13 +72,16 @@ func (r *UserRepo) GetUserByID(ctx context.Context, id int64) (*domain.User, err
 			&u.Username,
 			&u.Email,
 			&u.Password,
+			&u.EmailVerifiedAt,
 			&u.CreatedAt,
 		)
 
 	if err == pgx.ErrNoRows {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
 	}
-
 	return u, nil
 }
 
@@ -81,6 +97,85 @@ func (r *UserRepo) CreateUser(ctx context.Context, user domain.User) error {
 			user.Password,
 			user.CreatedAt,
 		)
-
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrUserExists
+	}
 	return err
 }
+
+// MarkEmailVerified отмечает почту подтверждённой, если она не менялась с момента выпуска токена
+func (r *UserRepo) MarkEmailVerified(ctx context.Context, id uuid.UUID, email string) error {
+	cmd, err := r.pool.
+		Exec(ctx,
+			`UPDATE "USER"
+             SET email_verified_at = $3
+             WHERE id = $1 AND email = $2`,
+			id,
+			email,
+			time.Now().UTC(),
+		)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *UserRepo) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	cmd, err := r.pool.
+		Exec(ctx,
+			`UPDATE "USER"
+             SET password_hash = $2
+             WHERE id = $1`,
+			id,
+			passwordHash,
+		)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// UpdateUser сохраняет имя, почту и признак её подтверждения
+func (r *UserRepo) UpdateUser(ctx context.Context, user domain.User) error {
+	cmd, err := r.pool.
+		Exec(ctx,
+			`UPDATE "USER"
+             SET username = $2, email = $3, email_verified_at = $4
+             WHERE id = $1`,
+			user.ID,
+			user.Username,
+			user.Email,
+			user.EmailVerifiedAt,
+		)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrUserExists
+	}
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// DeleteUser удаляет пользователя. Токены, роли, объявления и прочие связанные записи
+// удаляются каскадно внешними ключами
+func (r *UserRepo) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	cmd, err := r.pool.Exec(ctx, `DELETE FROM "USER" WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"jwt_auth_project/internal/domain"
+)
+
+var (
+	ErrChallengeInvalid   = errors.New("challenge is invalid or expired")
+	ErrCredentialNotFound = errors.New("credential not found")
+	ErrCredentialExists   = errors.New("credential already registered")
+)
+
+type WebAuthnRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewWebAuthnRepo(pool *pgxpool.Pool) *WebAuthnRepo {
+	return &WebAuthnRepo{pool: pool}
+}
+
+type WebAuthnRepository interface {
+	CreateChallenge(ctx context.Context, c domain.WebAuthnChallenge) error
+	ConsumeChallenge(ctx context.Context, id uuid.UUID, purpose string) (*domain.WebAuthnChallenge, error)
+	CreateCredential(ctx context.Context, c domain.WebAuthnCredential) error
+	GetCredential(ctx context.Context, credentialID []byte) (*domain.WebAuthnCredential, error)
+	ListCredentials(ctx context.Context, userID uuid.UUID) ([]domain.WebAuthnCredential, error)
+	UpdateSignCount(ctx context.Context, id uuid.UUID, signCount int64) error
+	DeleteCredential(ctx context.Context, userID, id uuid.UUID) error
+}
+
+// CreateChallenge сохраняет challenge и заодно чистит просроченные
+func (r *WebAuthnRepo) CreateChallenge(ctx context.Context, c domain.WebAuthnChallenge) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM "WEBAUTHN_CHALLENGES" WHERE expires_at < $1`, time.Now().UTC()); err != nil {
+		return err
+	}
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO "WEBAUTHN_CHALLENGES" (id, user_id, purpose, challenge, expires_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+    `, c.ID, c.UserID, c.Purpose, c.Challenge, c.ExpiresAt, c.CreatedAt)
+	return err
+}
+
+// ConsumeChallenge удаляет challenge и возвращает его, если он ещё действителен.
+// Каждый challenge годится ровно для одной попытки
+func (r *WebAuthnRepo) ConsumeChallenge(ctx context.Context, id uuid.UUID, purpose string) (*domain.WebAuthnChallenge, error) {
+	var c domain.WebAuthnChallenge
+	err := r.pool.QueryRow(ctx, `
+        DELETE FROM "WEBAUTHN_CHALLENGES"
+        WHERE id = $1 AND purpose = $2 AND expires_at > $3
+        RETURNING id, user_id, purpose, challenge, expires_at, created_at
+    `, id, purpose, time.Now().UTC()).Scan(&c.ID, &c.UserID, &c.Purpose, &c.Challenge, &c.ExpiresAt, &c.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrChallengeInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *WebAuthnRepo) CreateCredential(ctx context.Context, c domain.WebAuthnCredential) error {
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO "WEBAUTHN_CREDENTIALS" (id, user_id, credential_id, public_key, sign_count, name, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `, c.ID, c.UserID, c.CredentialID, c.PublicKey, c.SignCount, c.Name, c.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrCredentialExists
+	}
+	return err
+}
+
+func (r *WebAuthnRepo) GetCredential(ctx context.Context, credentialID []byte) (*domain.WebAuthnCredential, error) {
+	var c domain.WebAuthnCredential
+	err := r.pool.QueryRow(ctx, `
+        SELECT id, user_id, credential_id, public_key, sign_count, name, created_at, last_used_at
+        FROM "WEBAUTHN_CREDENTIALS"
+        WHERE credential_id = $1
+    `, credentialID).Scan(&c.ID, &c.UserID, &c.CredentialID, &c.PublicKey, &c.SignCount, &c.Name, &c.CreatedAt, &c.LastUsedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCredentialNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *WebAuthnRepo) ListCredentials(ctx context.Context, userID uuid.UUID) ([]domain.WebAuthnCredential, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT id, user_id, credential_id, public_key, sign_count, name, created_at, last_used_at
+        FROM "WEBAUTHN_CREDENTIALS"
+        WHERE user_id = $1
+        ORDER BY created_at
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	creds := []domain.WebAuthnCredential{}
+	for rows.Next() {
+		var c domain.WebAuthnCredential
+		if err := rows.Scan(&c.ID, &c.UserID, &c.CredentialID, &c.PublicKey, &c.SignCount, &c.Name, &c.CreatedAt, &c.LastUsedAt); err != nil {
+			return nil, err
+		}
+		creds = append(creds, c)
+	}
+	return creds, rows.Err()
+}
+
+// UpdateSignCount запоминает новое значение счётчика и время последнего входа
+func (r *WebAuthnRepo) UpdateSignCount(ctx context.Context, id uuid.UUID, signCount int64) error {
+	_, err := r.pool.Exec(ctx, `
+        UPDATE "WEBAUTHN_CREDENTIALS"
+        SET sign_count = $2, last_used_at = $3
+        WHERE id = $1
+    `, id, signCount, time.Now().UTC())
+	return err
+}
+
+func (r *WebAuthnRepo) DeleteCredential(ctx context.Context, userID, id uuid.UUID) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM "WEBAUTHN_CREDENTIALS" WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCredentialNotFound
+	}
+	return nil
+}
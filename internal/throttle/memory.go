@@ -0,0 +1,80 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	Entry
+	lastAttempt time.Time
+}
+
+// MemoryStore держит счётчики в памяти процесса. Годится для одного инстанса
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+func (s *MemoryStore) Reserve(_ context.Context, key string, now time.Time, window time.Duration, lockFor func(int) time.Duration) (Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now, window)
+
+	e, ok := s.entries[key]
+	if ok && e.LockedUntil.After(now) {
+		return e.Entry, false, nil
+	}
+	if !ok || now.Sub(e.lastAttempt) > window {
+		e = &memoryEntry{}
+		s.entries[key] = e
+	}
+	e.Failures++
+	e.lastAttempt = now
+	if d := lockFor(e.Failures); d > 0 {
+		e.LockedUntil = now.Add(d)
+	}
+	return e.Entry, true, nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, key string, lockedUntil time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	if e.Failures > 0 {
+		e.Failures--
+	}
+	if !lockedUntil.IsZero() && e.LockedUntil.Equal(lockedUntil) {
+		e.LockedUntil = time.Time{}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep раз в window удаляет забытые и разблокированные ключи, чтобы карта не росла бесконечно
+func (s *MemoryStore) sweep(now time.Time, window time.Duration) {
+	if now.Sub(s.lastSweep) < window {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if now.Sub(e.lastAttempt) > window && now.After(e.LockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Package throttle считает неудачные попытки (логина, ввода кода) по произвольным ключам
+// и после порога блокирует ключ с экспоненциально растущей задержкой
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Entry — состояние ключа в хранилище
+type Entry struct {
+	Failures    int
+	LockedUntil time.Time
+}
+
+// Store хранит счётчики. Память подходит для одного инстанса, Postgres — для нескольких
+type Store interface {
+	// Reserve атомарно учитывает попытку по ключу, если он не заблокирован: увеличивает счётчик
+	// (если последняя попытка была раньше now-window, счёт начинается заново) и, когда
+	// lockFor(failures) больше нуля, блокирует ключ до now+lockFor(failures). Заблокированный
+	// ключ не меняется, тогда ok=false. Entry — состояние ключа после вызова
+	Reserve(ctx context.Context, key string, now time.Time, window time.Duration, lockFor func(failures int) time.Duration) (e Entry, ok bool, err error)
+	// Release отменяет учтённую попытку: уменьшает счётчик и снимает блокировку, если это всё ещё
+	// lockedUntil, поставленная при её учёте
+	Release(ctx context.Context, key string, lockedUntil time.Time) error
+	Reset(ctx context.Context, key string) error
+}
+
+// Policy задаёт, сколько ошибок прощается и как растёт блокировка
+type Policy struct {
+	// FreeAttempts — сколько неудач подряд допускается без задержки
+	FreeAttempts int
+	// BaseDelay — блокировка после первой неудачи сверх FreeAttempts, дальше она удваивается
+	BaseDelay time.Duration
+	// MaxDelay — потолок блокировки, по сути временный локаут
+	MaxDelay time.Duration
+	// Window — через сколько без неудач счётчик забывается
+	Window time.Duration
+}
+
+// ThrottledError возвращается, пока ключ заблокирован
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("too many attempts, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+type Limiter struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
+
+func NewLimiter(store Store, policy Policy) *Limiter {
+	return &Limiter{store: store, policy: policy, now: time.Now}
+}
+
+// Reservation — попытка, учтённая Reserve
+type Reservation struct {
+	key         string
+	lockedUntil time.Time
+}
+
+// Reserve учитывает попытку по ключу ещё до того, как известен её исход, и возвращает
+// *ThrottledError, если ключ заблокирован. Попытка сразу считается неудачной: после порога ключ
+// блокируется до проверки, поэтому из параллельной серии запросов проходит не больше, чем
+// при последовательных. Удачную попытку отменяет Release или Reset
+func (l *Limiter) Reserve(ctx context.Context, key string) (Reservation, error) {
+	now := l.now()
+	entry, ok, err := l.store.Reserve(ctx, key, now, l.policy.Window, l.policy.delay)
+	if err != nil {
+		return Reservation{}, err
+	}
+	if !ok {
+		return Reservation{}, &ThrottledError{RetryAfter: entry.LockedUntil.Sub(now)}
+	}
+	return Reservation{key: key, lockedUntil: entry.LockedUntil}, nil
+}
+
+// Release отменяет попытку, когда сбросить весь счётчик нельзя. Пустую Reservation пропускает
+func (l *Limiter) Release(ctx context.Context, r Reservation) error {
+	if r.key == "" {
+		return nil
+	}
+	return l.store.Release(ctx, r.key, r.lockedUntil)
+}
+
+// Reset сбрасывает счётчики, например после успешного логина
+func (l *Limiter) Reset(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if err := l.store.Reset(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// delay — задержка после failures неудач: BaseDelay * 2^(failures-FreeAttempts-1), но не больше MaxDelay
+func (p Policy) delay(failures int) time.Duration {
+	over := failures - p.FreeAttempts
+	if over <= 0 {
+		return 0
+	}
+	factor := math.Pow(2, float64(over-1))
+	d := time.Duration(float64(p.BaseDelay) * factor)
+	if d <= 0 || d > p.MaxDelay || math.IsInf(factor, 0) {
+		return p.MaxDelay
+	}
+	return d
+}
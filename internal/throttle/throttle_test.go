@@ -0,0 +1,178 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	FreeAttempts: 3,
+	BaseDelay:    time.Second,
+	MaxDelay:     time.Minute,
+	Window:       time.Hour,
+}
+
+// newTestLimiter возвращает ограничитель с часами, которые двигает тест
+func newTestLimiter() (*Limiter, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(NewMemoryStore(), testPolicy)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func wantThrottled(t *testing.T, err error, retryAfter time.Duration) {
+	t.Helper()
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("err = %v, want ThrottledError", err)
+	}
+	if throttled.RetryAfter != retryAfter {
+		t.Fatalf("RetryAfter = %s, want %s", throttled.RetryAfter, retryAfter)
+	}
+}
+
+func TestPolicyDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: 0},
+		{failures: 3, want: 0},
+		{failures: 4, want: time.Second},
+		{failures: 5, want: 2 * time.Second},
+		{failures: 9, want: 32 * time.Second},
+		{failures: 10, want: time.Minute},
+		{failures: 100, want: time.Minute},
+		{failures: 1 << 20, want: time.Minute},
+	}
+	for _, tt := range tests {
+		if got := testPolicy.delay(tt.failures); got != tt.want {
+			t.Errorf("delay(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLimiterLockout(t *testing.T) {
+	ctx := context.Background()
+	l, now := newTestLimiter()
+
+	// Бесплатные попытки и первая сверх порога проходят, последняя блокирует ключ
+	for i := 0; i < testPolicy.FreeAttempts+1; i++ {
+		if _, err := l.Reserve(ctx, "k"); err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+	_, err := l.Reserve(ctx, "k")
+	wantThrottled(t, err, time.Second)
+
+	// Отклонённые попытки не удлиняют блокировку, следующая после неё — удваивает
+	*now = now.Add(time.Second)
+	if _, err := l.Reserve(ctx, "k"); err != nil {
+		t.Fatalf("attempt after lockout: %v", err)
+	}
+	_, err = l.Reserve(ctx, "k")
+	wantThrottled(t, err, 2*time.Second)
+
+	// Другие ключи не затронуты, Reset снимает блокировку
+	if _, err := l.Reserve(ctx, "other"); err != nil {
+		t.Fatalf("other key: %v", err)
+	}
+	if err := l.Reset(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Reserve(ctx, "k"); err != nil {
+		t.Fatalf("after Reset: %v", err)
+	}
+}
+
+func TestLimiterWindowForgetsFailures(t *testing.T) {
+	ctx := context.Background()
+	l, now := newTestLimiter()
+	for i := 0; i < testPolicy.FreeAttempts; i++ {
+		if _, err := l.Reserve(ctx, "k"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	*now = now.Add(testPolicy.Window + time.Second)
+	for i := 0; i < testPolicy.FreeAttempts+1; i++ {
+		if _, err := l.Reserve(ctx, "k"); err != nil {
+			t.Fatalf("attempt %d in a new window: %v", i+1, err)
+		}
+	}
+	_, err := l.Reserve(ctx, "k")
+	wantThrottled(t, err, time.Second)
+}
+
+func TestLimiterRelease(t *testing.T) {
+	ctx := context.Background()
+	l, now := newTestLimiter()
+	for i := 0; i < testPolicy.FreeAttempts; i++ {
+		if _, err := l.Reserve(ctx, "k"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Отменённая попытка снимает поставленную ею блокировку и не идёт в счёт
+	r, err := l.Reserve(ctx, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Release(ctx, r); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Reserve(ctx, "k"); err != nil {
+		t.Fatalf("after Release: %v", err)
+	}
+
+	// Блокировку, поставленную более поздней попыткой, Release не трогает
+	*now = now.Add(time.Second)
+	earlier, err := l.Reserve(ctx, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	*now = now.Add(2 * time.Second)
+	if _, err := l.Reserve(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Release(ctx, earlier); err != nil {
+		t.Fatal(err)
+	}
+	_, err = l.Reserve(ctx, "k")
+	wantThrottled(t, err, 4*time.Second)
+
+	if err := l.Release(ctx, Reservation{}); err != nil {
+		t.Fatalf("empty reservation: %v", err)
+	}
+}
+
+func TestLimiterConcurrentBurst(t *testing.T) {
+	ctx := context.Background()
+	l, _ := newTestLimiter()
+
+	const burst = 50
+	var (
+		passed atomic.Int32
+		wg     sync.WaitGroup
+		start  = make(chan struct{})
+	)
+	for i := 0; i < burst; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if _, err := l.Reserve(ctx, "k"); err == nil {
+				passed.Add(1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if got, want := int(passed.Load()), testPolicy.FreeAttempts+1; got != want {
+		t.Fatalf("%d of %d parallel attempts passed, want %d", got, burst, want)
+	}
+}
@@ -18,20 +18,24 @@ import (
 	"jwt_auth_project/internal/repo"
 )
 
+// ErrForbidden возвращается, когда пользователь пытается изменить чужое объявление
+var ErrForbidden = errors.New("forbidden")
+
 // AdsUseCase описывает бизнес-логику по работе с объявлениями
 // CRUD операций и взаимодействие с S3
 type AdsUseCase interface {
 	InitBucket(ctx context.Context) error
-	CreateAd(ctx context.Context, p domain.CreateAdPayload) (*domain.Ad, error)
+	CreateAd(ctx context.Context, actor domain.Principal, p domain.CreateAdPayload) (*domain.Ad, error)
 	GetAdByID(ctx context.Context, id uuid.UUID) (*domain.Ad, error)
 	ListAds(ctx context.Context, opts domain.AdListOptions) ([]*domain.Ad, error)
-	UpdateAd(ctx context.Context, p domain.UpdateAdPayload) (*domain.Ad, error)
-	DeleteAd(ctx context.Context, id uuid.UUID) error
+	UpdateAd(ctx context.Context, actor domain.Principal, p domain.UpdateAdPayload) (*domain.Ad, error)
+	DeleteAd(ctx context.Context, actor domain.Principal, id uuid.UUID) error
 }
 
 // adsUseCase — реализация AdsUseCase
 type adsUseCase struct {
 	repo         repo.AdsRepository
+	users        repo.UserRepository
 	s3           *s3.S3
 	bucket       string
 	validate     *validator.Validate
@@ -39,16 +43,19 @@ type adsUseCase struct {
 }
 
 // NewAdsUsecase создаёт новый экземпляр usecase
+// users нужен, чтобы проверять подтверждение почты автора
 // s3Client — клиент из config.NewS3Client(), bucket — название бакета
 // maxImageSize — максимальный размер картинки в байтах
 func NewAdsUsecase(
 	repo repo.AdsRepository,
+	users repo.UserRepository,
 	s3Client *s3.S3,
 	bucket string,
 	maxImageSize int64,
 ) AdsUseCase {
 	return &adsUseCase{
 		repo:         repo,
+		users:        users,
 		s3:           s3Client,
 		bucket:       bucket,
 		validate:     validator.New(),
@@ -65,8 +72,17 @@ func (u *adsUseCase) InitBucket(ctx context.Context) error {
 	return err
 }
 
-// CreateAd валидирует payload, загружает картинку в S3 и сохраняет объявление
-func (u *adsUseCase) CreateAd(ctx context.Context, p domain.CreateAdPayload) (*domain.Ad, error) {
+// CreateAd валидирует payload, загружает картинку в S3 и сохраняет объявление. Автор — actor,
+// публиковать объявления могут только пользователи с подтверждённой почтой. Подтверждение берётся
+// из базы, а не из токена: токен, выпущенный до подтверждения, действует ещё до конца срока
+func (u *adsUseCase) CreateAd(ctx context.Context, actor domain.Principal, p domain.CreateAdPayload) (*domain.Ad, error) {
+	author, err := u.users.GetUserByID(ctx, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if author.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
+	}
 	p.Title = strings.TrimSpace(p.Title)
 	p.Description = strings.TrimSpace(p.Description)
 	if err := u.validate.Struct(p); err != nil {
@@ -94,7 +110,7 @@ func (u *adsUseCase) CreateAd(ctx context.Context, p domain.CreateAdPayload) (*d
 	// 6. Сохранение модели в БД
 	ad := &domain.Ad{
 		ID:          id,
-		AuthorID:    p.AuthorID,
+		AuthorID:    actor.UserID,
 		Title:       p.Title,
 		Description: p.Description,
 		Price:       p.Price,
@@ -118,8 +134,9 @@ func (u *adsUseCase) ListAds(ctx context.Context, opts domain.AdListOptions) ([]
 	return u.repo.ListAds(ctx, opts)
 }
 
-// UpdateAd обновляет объявление и при необходимости заменяет картинку
-func (u *adsUseCase) UpdateAd(ctx context.Context, p domain.UpdateAdPayload) (*domain.Ad, error) {
+// UpdateAd обновляет объявление и при необходимости заменяет картинку.
+// Менять объявление может только автор или модератор
+func (u *adsUseCase) UpdateAd(ctx context.Context, actor domain.Principal, p domain.UpdateAdPayload) (*domain.Ad, error) {
 	if err := u.validate.Struct(p); err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
@@ -128,6 +145,9 @@ func (u *adsUseCase) UpdateAd(ctx context.Context, p domain.UpdateAdPayload) (*d
 	if err != nil {
 		return nil, err
 	}
+	if !canManageAd(actor, existing) {
+		return nil, ErrForbidden
+	}
 
 	if p.Image != nil {
 		if p.ImageSize > u.maxImageSize {
@@ -159,6 +179,19 @@ func (u *adsUseCase) UpdateAd(ctx context.Context, p domain.UpdateAdPayload) (*d
 	return existing, nil
 }
 
-func (u *adsUseCase) DeleteAd(ctx context.Context, id uuid.UUID) error {
+// DeleteAd удаляет объявление. Удалить может только автор или модератор
+func (u *adsUseCase) DeleteAd(ctx context.Context, actor domain.Principal, id uuid.UUID) error {
+	existing, err := u.repo.GetAdByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !canManageAd(actor, existing) {
+		return ErrForbidden
+	}
 	return u.repo.DeleteAd(ctx, id)
 }
+
+// canManageAd проверяет, что actor — автор объявления или модератор контента
+func canManageAd(actor domain.Principal, ad *domain.Ad) bool {
+	return ad.AuthorID == actor.UserID || actor.Can(domain.PermAdsModerate)
+}
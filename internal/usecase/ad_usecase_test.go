@@ -0,0 +1,40 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/domain"
+)
+
+func TestCreateAdChecksVerificationInDatabase(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	ads := NewAdsUsecase(nil, env.users, nil, "ads", 1<<20)
+
+	user := domain.User{ID: uuid.New(), Username: "alice", Email: "alice@example.com"}
+	if err := env.users.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	// Токен выпущен до подтверждения почты и продолжает действовать после него
+	actor := domain.Principal{UserID: user.ID}
+
+	if _, err := ads.CreateAd(ctx, actor, domain.CreateAdPayload{}); !errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("unverified: err = %v, want ErrEmailNotVerified", err)
+	}
+
+	if err := env.uc.SendVerificationEmail(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.uc.VerifyEmail(ctx, tokenFromMail(t, waitMail(t, env.mail, user.Email))); err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+
+	// Пустое объявление отклоняется уже валидацией, а не проверкой почты
+	if _, err := ads.CreateAd(ctx, actor, domain.CreateAdPayload{}); err == nil || errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("verified: err = %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/utils"
+)
+
+// apiKeyTag — начало каждого ключа, чтобы его было легко узнать в логах и сканерах секретов
+const apiKeyTag = "ak_"
+
+var (
+	ErrInvalidAPIKey   = errors.New("invalid api key")
+	ErrScopeNotAllowed = errors.New("scope is not granted to the user")
+)
+
+// APIKeyUseCase описывает выпуск, отзыв и проверку персональных API-ключей
+type APIKeyUseCase interface {
+	CreateAPIKey(ctx context.Context, actor domain.Principal, payload domain.CreateAPIKeyPayload) (*domain.CreatedAPIKey, error)
+	ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]domain.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error
+	Authenticate(ctx context.Context, key string) (*domain.Principal, error)
+}
+
+type apiKeyUseCase struct {
+	repo     repo.APIKeyRepository
+	userRepo repo.UserRepository
+	roleUC   RoleUseCase
+}
+
+func NewAPIKeyUsecase(r repo.APIKeyRepository, userRepo repo.UserRepository, roleUC RoleUseCase) APIKeyUseCase {
+	return &apiKeyUseCase{repo: r, userRepo: userRepo, roleUC: roleUC}
+}
+
+// CreateAPIKey выпускает ключ с правами из scopes. Выдать можно только те права,
+// что есть у владельца сейчас. Ключ вида ak_<prefix>_<secret> возвращается один раз
+func (u *apiKeyUseCase) CreateAPIKey(ctx context.Context, actor domain.Principal, payload domain.CreateAPIKeyPayload) (*domain.CreatedAPIKey, error) {
+	payload.Name = strings.TrimSpace(payload.Name)
+	if err := utils.Validate.Struct(payload); err != nil {
+		return nil, err
+	}
+	scopes := slices.Clone(payload.Scopes)
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+	for _, scope := range scopes {
+		if !actor.Can(scope) {
+			return nil, fmt.Errorf("%w: %s", ErrScopeNotAllowed, scope)
+		}
+	}
+
+	prefixBytes := make([]byte, 6)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return nil, err
+	}
+	prefix := hex.EncodeToString(prefixBytes)
+	secret, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	raw := apiKeyTag + prefix + "_" + secret
+
+	now := time.Now().UTC()
+	key := domain.APIKey{
+		ID:        uuid.New(),
+		UserID:    actor.UserID,
+		Name:      payload.Name,
+		Prefix:    prefix,
+		KeyHash:   hashToken(raw),
+		Scopes:    scopes,
+		ExpiresAt: now.AddDate(0, 0, payload.ExpiresInDays),
+		CreatedAt: now,
+	}
+	if err := u.repo.CreateAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+	return &domain.CreatedAPIKey{APIKey: key, Key: raw}, nil
+}
+
+func (u *apiKeyUseCase) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]domain.APIKey, error) {
+	return u.repo.ListAPIKeys(ctx, userID)
+}
+
+func (u *apiKeyUseCase) RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) error {
+	return u.repo.RevokeAPIKey(ctx, userID, id)
+}
+
+// Authenticate проверяет ключ и возвращает principal, права которого — пересечение scopes ключа
+// с текущими правами владельца: понижение в ролях сразу урезает и его ключи
+func (u *apiKeyUseCase) Authenticate(ctx context.Context, raw string) (*domain.Principal, error) {
+	prefix, ok := apiKeyPrefix(raw)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	key, err := u.repo.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, repo.ErrAPIKeyNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashToken(raw))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+	if key.RevokedAt != nil || time.Now().After(key.ExpiresAt) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	// Ключ удалённого пользователя недействителен
+	if _, err := u.userRepo.GetUserByID(ctx, key.UserID); err != nil {
+		return nil, err
+	}
+	roles, err := u.roleUC.GetUserRoles(ctx, key.UserID)
+	if err != nil {
+		return nil, err
+	}
+	granted, err := u.roleUC.Permissions(ctx, roles)
+	if err != nil {
+		return nil, err
+	}
+	var permissions []string
+	for _, scope := range key.Scopes {
+		if slices.Contains(granted, scope) {
+			permissions = append(permissions, scope)
+		}
+	}
+
+	if err := u.repo.TouchAPIKey(ctx, key.ID); err != nil {
+		slog.Warn("api key: update last use failed", "key_id", key.ID, "error", err)
+	}
+
+	return &domain.Principal{
+		UserID:      key.UserID,
+		APIKeyID:    key.ID,
+		Permissions: permissions,
+	}, nil
+}
+
+// apiKeyPrefix извлекает prefix из ключа вида ak_<prefix>_<secret>
+func apiKeyPrefix(raw string) (string, bool) {
+	rest, ok := strings.CutPrefix(raw, apiKeyTag)
+	if !ok {
+		return "", false
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || prefix == "" || secret == "" {
+		return "", false
+	}
+	return prefix, true
+}
@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/config"
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/mailer"
+	"jwt_auth_project/internal/repo"
+)
+
+// emailVerificationTTL — сколько живёт ссылка подтверждения почты. Это самый долгоживущий
+// одноразовый токен: ключи подписи хранятся, пока он может быть действителен
+const emailVerificationTTL = config.PurposeTokenMaxLifetime
+
+var (
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+	ErrEmailNotVerified     = errors.New("email is not verified")
+)
+
+// SendVerificationEmail выпускает подписанный одноразовый токен и отправляет ссылку подтверждения
+func (u *userUseCase) SendVerificationEmail(ctx context.Context, userID uuid.UUID) error {
+	user, err := u.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := u.issuePurposeToken(ctx, user, domain.PurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", u.baseURL, url.QueryEscape(token))
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("Hi %s,\n\nplease confirm your email by opening the link below:\n%s\n\n"+
+			"The link is valid for 24 hours.", user.Username, link),
+	})
+}
+
+// VerifyEmail проверяет подпись токена, гасит его и отмечает почту подтверждённой.
+// Если почта успела смениться после выпуска токена, подтверждение не засчитывается
+func (u *userUseCase) VerifyEmail(ctx context.Context, token string) error {
+	claims, err := u.parsePurposeToken(token, domain.PurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+	jti, err := claims.TokenID()
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	userID, err := u.otpRepo.ConsumeOneTimeToken(ctx, jti, domain.PurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+	if err := u.repo.MarkEmailVerified(ctx, userID, claims.Email); err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+	return nil
+}
+
+// issuePurposeToken подписывает одноразовый JWT с назначением purpose и регистрирует его jti,
+// чтобы токен можно было погасить после использования
+func (u *userUseCase) issuePurposeToken(ctx context.Context, user *domain.User, purpose string, ttl time.Duration) (string, error) {
+	return u.issuePurposeTokenWithID(ctx, user, purpose, ttl, uuid.New())
+}
+
+// issuePurposeTokenWithID — то же, что issuePurposeToken, с заданным jti: так токен
+// можно связать с записью, которую он подтверждает
+func (u *userUseCase) issuePurposeTokenWithID(ctx context.Context, user *domain.User, purpose string, ttl time.Duration, jti uuid.UUID) (string, error) {
+	// Иначе keyctl retire может удалить ключ раньше, чем истечёт токен
+	if ttl > config.PurposeTokenMaxLifetime {
+		return "", fmt.Errorf("purpose token ttl %s exceeds %s", ttl, config.PurposeTokenMaxLifetime)
+	}
+	now := time.Now().UTC()
+	if err := u.otpRepo.CreateOneTimeToken(ctx, domain.OneTimeToken{
+		ID:        jti,
+		UserID:    user.ID,
+		Purpose:   purpose,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}); err != nil {
+		return "", err
+	}
+
+	return u.signClaims(domain.Claims{
+		RegisteredClaims: u.registeredClaims(jti.String(), user.ID.String(), now, ttl),
+		Email:            user.Email,
+		Purpose:          purpose,
+	})
+}
+
+// parsePurposeToken проверяет подпись и срок одноразового токена и его назначение
+func (u *userUseCase) parsePurposeToken(token, purpose string) (*domain.Claims, error) {
+	claims, err := u.parseToken(token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if claims.Purpose != purpose {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/config"
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/oidc"
+	"jwt_auth_project/internal/oidc/oidctest"
+	"jwt_auth_project/internal/password"
+	"jwt_auth_project/internal/repo/repotest"
+	"jwt_auth_project/internal/throttle"
+	"jwt_auth_project/internal/webauthn"
+)
+
+const (
+	testIssuer  = "https://api.example.com"
+	testBaseURL = "https://app.example.com"
+	testRPID    = "app.example.com"
+	testOrigin  = "https://app.example.com"
+)
+
+// testEnv — usecase поверх хранилищ в памяти
+type testEnv struct {
+	uc         *userUseCase
+	users      *repotest.Users
+	refresh    *repotest.RefreshTokens
+	sessions   *repotest.Sessions
+	otp        *repotest.OneTimeTokens
+	webauthn   *repotest.WebAuthn
+	oauth      *repotest.OAuth
+	identities *repotest.Identities
+	mail       *repotest.Mailer
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	env := &testEnv{
+		users:      repotest.NewUsers(),
+		refresh:    repotest.NewRefreshTokens(),
+		sessions:   repotest.NewSessions(),
+		otp:        repotest.NewOneTimeTokens(),
+		webauthn:   repotest.NewWebAuthn(),
+		oauth:      repotest.NewOAuth(),
+		identities: repotest.NewIdentities(),
+		mail:       &repotest.Mailer{},
+	}
+	passwordCfg := config.PasswordConfig{Memory: 1024, Time: 1, Threads: 1, MinLength: 8, MaxLength: 128, MinCharClasses: 1}
+	policy, err := password.NewPolicy(passwordCfg)
+	if err != nil {
+		t.Fatalf("password policy: %v", err)
+	}
+	jwtCfg := config.JWTConfig{
+		Keys:            config.KeyRing{Current: config.NewSecretKey("test", "test-secret")},
+		Lifetime:        15 * time.Minute,
+		RefreshLifetime: time.Hour,
+		Issuer:          testIssuer,
+		Audience:        testIssuer,
+		Leeway:          30 * time.Second,
+	}
+	env.uc = NewUserUsecase(
+		env.users, env.refresh, env.sessions, repotest.NewRevocations(env.users), repotest.NewRoles(),
+		env.otp, repotest.MFA{}, env.webauthn, env.oauth, env.identities, map[string]*oidc.Provider{},
+		webauthn.NewRelyingParty(testRPID, "Test", []string{testOrigin}),
+		throttle.NewMemoryStore(), password.NewHasher(passwordCfg), policy, env.mail,
+		jwtCfg, testBaseURL, testIssuer,
+	).(*userUseCase)
+	return env
+}
+
+// addUser заводит пользователя с паролем pw и подтверждённой почтой
+func (env *testEnv) addUser(t *testing.T, email, pw string) *domain.User {
+	t.Helper()
+	hashed, err := env.uc.passwords.Hash(pw)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	verified := time.Now().UTC()
+	user := domain.User{
+		ID:              uuid.New(),
+		Username:        strings.Split(email, "@")[0],
+		Email:           email,
+		Password:        hashed,
+		EmailVerifiedAt: &verified,
+		CreatedAt:       verified,
+	}
+	if err := env.users.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return &user
+}
+
+// addProvider подключает провайдера входа name, работающего на httptest-сервере
+func (env *testEnv) addProvider(t *testing.T, name string) *oidctest.IdP {
+	t.Helper()
+	idp := oidctest.New(t, name+"-client", "secret")
+	env.uc.providers[name] = oidc.NewProvider(config.SocialProviderConfig{
+		Name:         name,
+		Issuer:       idp.URL,
+		ClientID:     idp.ClientID,
+		ClientSecret: idp.ClientSecret,
+		Scopes:       []string{"openid", "email", "profile"},
+	}, testIssuer+"/login/social/"+name+"/callback")
+	return idp
+}
@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/repo"
+)
+
+// Introspect реализует /oauth/introspect (RFC 7662): сообщает, действует ли токен, и его атрибуты.
+// Спрашивать могут только конфиденциальные клиенты: о своих токенах, а с правом Introspect
+// (API-шлюз и другие ресурсные серверы) — о любых токенах сервиса
+func (u *userUseCase) Introspect(ctx context.Context, req domain.OAuthTokenQuery) (*domain.OAuthIntrospection, error) {
+	client, err := u.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.Confidential() {
+		return nil, &OAuthError{Code: OAuthInvalidClient, Description: "public clients cannot introspect tokens"}
+	}
+	if req.Token == "" {
+		return nil, &OAuthError{Code: OAuthInvalidRequest, Description: "token is required"}
+	}
+
+	// Подсказка лишь задаёт порядок поиска (RFC 7662, раздел 2.1)
+	lookups := []func(context.Context, string) (*domain.OAuthIntrospection, error){u.introspectAccessToken, u.introspectRefreshToken}
+	if req.TokenTypeHint == domain.TokenTypeHintRefreshToken {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+	for _, lookup := range lookups {
+		info, err := lookup(ctx, req.Token)
+		if err != nil {
+			return nil, err
+		}
+		if info == nil {
+			continue
+		}
+		// Чужие токены видит только клиент с правом introspect, например API-шлюз. Остальным
+		// они показываются неактивными (RFC 7662, раздел 4), чтобы по ответу нельзя было
+		// узнать, кому и с какими scopes выдан перехваченный токен
+		if !client.Introspect && info.ClientID != client.ID {
+			break
+		}
+		return info, nil
+	}
+	return &domain.OAuthIntrospection{Active: false}, nil
+}
+
+// introspectAccessToken проверяет access-токен так же, как AuthMiddleware. nil — токен не действует
+func (u *userUseCase) introspectAccessToken(ctx context.Context, token string) (*domain.OAuthIntrospection, error) {
+	// Сначала отсеиваем всё, что не является нашим access-токеном: после этого ошибка ValidateToken —
+	// либо отзыв, либо сбой хранилища, и о сбое нужно сообщить, а не объявлять токен недействующим
+	parsed, err := u.parseToken(token)
+	if err != nil || parsed.Purpose != "" {
+		return nil, nil
+	}
+	if _, err := parsed.TokenID(); err != nil {
+		return nil, nil
+	}
+	if _, err := parsed.UserID(); err != nil && !parsed.ClientToken() {
+		return nil, nil
+	}
+
+	// Токены удалённого пользователя не действуют, как и отозванные
+	claims, err := u.ValidateToken(ctx, token)
+	if errors.Is(err, ErrTokenRevoked) || errors.Is(err, repo.ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info := &domain.OAuthIntrospection{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		Username:  claims.Username,
+		TokenType: "Bearer",
+		Subject:   claims.Subject,
+		Audience:  claims.Audience,
+		Issuer:    claims.Issuer,
+		TokenID:   claims.ID,
+	}
+	if claims.ExpiresAt != nil {
+		info.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		info.IssuedAt = claims.IssuedAt.Unix()
+	}
+	return info, nil
+}
+
+// introspectRefreshToken ищет refresh-токен по хешу. Использованный при ротации токен уже не действует
+func (u *userUseCase) introspectRefreshToken(ctx context.Context, token string) (*domain.OAuthIntrospection, error) {
+	stored, err := u.refreshRepo.GetRefreshTokenByHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, repo.ErrRefreshTokenNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if stored.RevokedAt != nil || stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, nil
+	}
+
+	info := &domain.OAuthIntrospection{
+		Active:    true,
+		Scope:     strings.Join(stored.Scopes, " "),
+		TokenType: domain.TokenTypeHintRefreshToken,
+		ExpiresAt: stored.ExpiresAt.Unix(),
+		IssuedAt:  stored.CreatedAt.Unix(),
+		Subject:   stored.UserID.String(),
+	}
+	if stored.ClientID != nil {
+		info.ClientID = *stored.ClientID
+	}
+	return info, nil
+}
+
+// Revoke реализует /oauth/revoke (RFC 7009). Клиент отзывает только свои токены; отзыв refresh-токена
+// завершает всю сессию, а с ней и выданные в ней access-токены. Неизвестный или уже недействующий
+// токен ошибкой не считается — отзывать в нём нечего. Подсказка token_type_hint не нужна:
+// непрозрачный refresh-токен ищется по хешу, а access-токен распознаётся как JWT
+func (u *userUseCase) Revoke(ctx context.Context, req domain.OAuthTokenQuery) error {
+	client, err := u.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return err
+	}
+	if req.Token == "" {
+		return &OAuthError{Code: OAuthInvalidRequest, Description: "token is required"}
+	}
+	notOwned := &OAuthError{Code: OAuthUnauthorizedClient, Description: "token was not issued to the client"}
+
+	stored, err := u.refreshRepo.GetRefreshTokenByHash(ctx, hashToken(req.Token))
+	switch {
+	case err == nil:
+		if stored.ClientID == nil || *stored.ClientID != client.ID {
+			return notOwned
+		}
+		return u.revokeSession(ctx, stored.FamilyID)
+	case !errors.Is(err, repo.ErrRefreshTokenNotFound):
+		return err
+	}
+
+	claims, err := u.parseToken(req.Token)
+	if err != nil || claims.Purpose != "" {
+		return nil
+	}
+	if claims.ClientID != client.ID {
+		return notOwned
+	}
+	// Отзыв по jti привязан к пользователю, а у токена client_credentials его нет
+	if claims.ClientToken() {
+		return &OAuthError{Code: OAuthUnsupportedTokenType, Description: "client credentials tokens cannot be revoked; they expire shortly"}
+	}
+	return u.revokeAccessToken(ctx, claims)
+}
@@ -0,0 +1,137 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/domain"
+)
+
+func TestIntrospectOnlyOwnTokensWithoutGrant(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.addUser(t, "alice@example.com", "correct horse battery")
+	client := registerTestClient(t, env)
+	other := registerTestClient(t, env)
+	gateway, err := env.uc.RegisterOAuthClient(ctx, uuid.New(), domain.RegisterOAuthClientPayload{
+		Name:         "API gateway",
+		RedirectURIs: []string{testRedirectURI},
+		GrantTypes:   []string{domain.GrantClientCredentials},
+		Scopes:       []string{domain.ScopeOpenID},
+		Introspect:   true,
+	})
+	if err != nil {
+		t.Fatalf("RegisterOAuthClient: %v", err)
+	}
+
+	verifier, challenge := pkcePair("v")
+	code := authorizeCode(t, env, user.ID, domain.AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            client.ID,
+		RedirectURI:         testRedirectURI,
+		CodeChallenge:       challenge,
+		CodeChallengeMethod: "S256",
+	})
+	resp, err := env.uc.Token(ctx, domain.OAuthTokenRequest{
+		GrantType:    domain.GrantAuthorizationCode,
+		ClientID:     client.ID,
+		ClientSecret: client.Secret,
+		Code:         code,
+		RedirectURI:  testRedirectURI,
+		CodeVerifier: verifier,
+	})
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	session, err := env.uc.issueTokenPair(ctx, user.ID, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		caller *domain.RegisteredOAuthClient
+		token  string
+		active bool
+	}{
+		{name: "own access token", caller: client, token: resp.AccessToken, active: true},
+		{name: "own refresh token", caller: client, token: resp.RefreshToken, active: true},
+		{name: "foreign access token", caller: other, token: resp.AccessToken},
+		{name: "foreign refresh token", caller: other, token: resp.RefreshToken},
+		{name: "first-party session token", caller: client, token: session.AccessToken},
+		{name: "gateway sees client token", caller: gateway, token: resp.AccessToken, active: true},
+		{name: "gateway sees refresh token", caller: gateway, token: resp.RefreshToken, active: true},
+		{name: "gateway sees first-party session token", caller: gateway, token: session.AccessToken, active: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := env.uc.Introspect(ctx, domain.OAuthTokenQuery{
+				Token:        tt.token,
+				ClientID:     tt.caller.ID,
+				ClientSecret: tt.caller.Secret,
+			})
+			if err != nil {
+				t.Fatalf("Introspect: %v", err)
+			}
+			if info.Active != tt.active {
+				t.Fatalf("active = %v, want %v", info.Active, tt.active)
+			}
+			if !tt.active && (info.ClientID != "" || info.Subject != "" || info.Scope != "") {
+				t.Fatalf("inactive response leaks attributes: %+v", info)
+			}
+		})
+	}
+}
+
+func TestRegisterPublicClientWithIntrospectRejected(t *testing.T) {
+	env := newTestEnv(t)
+	_, err := env.uc.RegisterOAuthClient(context.Background(), uuid.New(), domain.RegisterOAuthClientPayload{
+		Name:         "SPA",
+		RedirectURIs: []string{testRedirectURI},
+		GrantTypes:   []string{domain.GrantAuthorizationCode},
+		Scopes:       []string{domain.ScopeOpenID},
+		Public:       true,
+		Introspect:   true,
+	})
+	if !errors.Is(err, ErrInvalidOAuthClient) {
+		t.Fatalf("err = %v, want ErrInvalidOAuthClient", err)
+	}
+}
+
+func TestIntrospectDeletedUserToken(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.addUser(t, "alice@example.com", "correct horse battery")
+	gateway, err := env.uc.RegisterOAuthClient(ctx, uuid.New(), domain.RegisterOAuthClientPayload{
+		Name:         "API gateway",
+		RedirectURIs: []string{testRedirectURI},
+		GrantTypes:   []string{domain.GrantClientCredentials},
+		Scopes:       []string{domain.ScopeOpenID},
+		Introspect:   true,
+	})
+	if err != nil {
+		t.Fatalf("RegisterOAuthClient: %v", err)
+	}
+	session, err := env.uc.issueTokenPair(ctx, user.ID, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.users.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := env.uc.Introspect(ctx, domain.OAuthTokenQuery{
+		Token:        session.AccessToken,
+		ClientID:     gateway.ID,
+		ClientSecret: gateway.Secret,
+	})
+	if err != nil {
+		t.Fatalf("Introspect: %v", err)
+	}
+	if info.Active {
+		t.Fatalf("token of a deleted user is active: %+v", info)
+	}
+}
@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"math/big"
+
+	"jwt_auth_project/internal/config"
+	"jwt_auth_project/internal/domain"
+)
+
+// JWKS возвращает открытые ключи проверки подписи: текущий, предыдущие и заранее опубликованные.
+// Симметричные ключи не публикуются
+func (u *userUseCase) JWKS() domain.JWKSet {
+	set := domain.JWKSet{Keys: []domain.JWK{}}
+	for _, key := range u.keys.All() {
+		if !key.Asymmetric() {
+			continue
+		}
+		jwk, err := publicJWK(key)
+		if err != nil {
+			slog.Error("jwks: convert public key failed", "kid", key.ID, "error", err)
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// publicJWK конвертирует открытую часть ключа подписи в JWK
+func publicJWK(k config.SigningKey) (domain.JWK, error) {
+	jwk := domain.JWK{Kid: k.ID, Use: "sig", Alg: k.Algorithm}
+	enc := base64.RawURLEncoding
+
+	switch pub := k.Private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = enc.EncodeToString(pub.N.Bytes())
+		jwk.E = enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		// Координаты кодируются фиксированной длиной — 32 байта для P-256
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = enc.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = enc.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = enc.EncodeToString(pub)
+	default:
+		return domain.JWK{}, fmt.Errorf("unsupported public key type %T", pub)
+	}
+	return jwk, nil
+}
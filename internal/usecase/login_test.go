@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/throttle"
+)
+
+func TestLoginParallelGuessesAreThrottled(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(t, "alice@example.com", "correct horse battery")
+
+	const burst = 30
+	var (
+		mu        sync.Mutex
+		checked   int
+		throttled int
+		wg        sync.WaitGroup
+	)
+	for i := 0; i < burst; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := env.uc.Login(context.Background(), domain.LoginUserPayload{Email: "alice@example.com", Password: "wrong password"})
+			var throttledErr *throttle.ThrottledError
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case errors.As(err, &throttledErr):
+				throttled++
+			case errors.Is(err, ErrInvalidCredentials):
+				checked++
+			default:
+				t.Errorf("Login err = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Пароль проверяется не больше раз, чем при последовательных попытках
+	if want := loginAccountPolicy.FreeAttempts + 1; checked != want || throttled != burst-want {
+		t.Fatalf("checked %d, throttled %d; want %d checked", checked, throttled, want)
+	}
+
+	_, err := env.uc.Login(context.Background(), domain.LoginUserPayload{Email: "alice@example.com", Password: "correct horse battery"})
+	var throttledErr *throttle.ThrottledError
+	if !errors.As(err, &throttledErr) {
+		t.Fatalf("correct password during lockout err = %v, want ThrottledError", err)
+	}
+}
@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/mailer"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/utils"
+)
+
+// magicLinkTTL — сколько живёт ссылка для входа без пароля
+const magicLinkTTL = 15 * time.Minute
+
+// RequestMagicLink отправляет на email ссылку для входа без пароля. Запросы ограничиваются
+// по адресу независимо от того, есть ли такой аккаунт; письмо отправляется в фоне,
+// поэтому ответ не выдаёт, зарегистрирован ли адрес
+func (u *userUseCase) RequestMagicLink(ctx context.Context, payload domain.MagicLinkPayload) error {
+	payload.Email = strings.TrimSpace(payload.Email)
+	if err := utils.Validate.Struct(payload); err != nil {
+		return err
+	}
+
+	key := magicLinkKey(payload.Email)
+	if _, err := u.limiters.magicLink.Reserve(ctx, key); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := u.sendMagicLink(context.WithoutCancel(ctx), payload.Email); err != nil {
+			slog.Error("magic link: send link failed", "error", err)
+		}
+	}()
+	return nil
+}
+
+func (u *userUseCase) sendMagicLink(ctx context.Context, email string) error {
+	user, err := u.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := u.issuePurposeToken(ctx, user, domain.PurposeMagicLink, magicLinkTTL)
+	if err != nil {
+		return err
+	}
+
+	// Ссылка ведёт прямо на API: callback выставляет cookie с токенами
+	link := fmt.Sprintf("%s/login/magic-link/callback?token=%s", u.issuer, url.QueryEscape(token))
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf("Hi %s,\n\nopen the link below within 15 minutes to sign in:\n%s\n\n"+
+			"The link works once. If you did not request it, just ignore this email.", user.Username, link),
+	})
+}
+
+// LoginMagicLink гасит токен из ссылки и завершает вход так же, как логин по паролю,
+// включая запрос второго фактора. Переход по ссылке доказывает владение почтой,
+// поэтому неподтверждённая почта заодно подтверждается
+func (u *userUseCase) LoginMagicLink(ctx context.Context, token string) (*domain.LoginResult, error) {
+	claims, err := u.parsePurposeToken(token, domain.PurposeMagicLink)
+	if err != nil {
+		return nil, err
+	}
+	jti, err := claims.TokenID()
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	userID, err := u.otpRepo.ConsumeOneTimeToken(ctx, jti, domain.PurposeMagicLink)
+	if err != nil {
+		return nil, err
+	}
+	user, err := u.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	// Почта сменилась после отправки письма — ссылка ушла на старый адрес
+	if !strings.EqualFold(user.Email, claims.Email) {
+		return nil, ErrInvalidToken
+	}
+
+	if user.EmailVerifiedAt == nil {
+		if err := u.repo.MarkEmailVerified(ctx, user.ID, claims.Email); err != nil {
+			return nil, err
+		}
+	}
+	return u.completeLogin(ctx, user)
+}
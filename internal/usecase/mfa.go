@@ -0,0 +1,260 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/totp"
+	"jwt_auth_project/internal/utils"
+)
+
+const (
+	// mfaPendingTTL — сколько есть времени ввести код после ввода пароля
+	mfaPendingTTL = 5 * time.Minute
+	// mfaPendingAttempts — сколько неверных кодов выдерживает один mfa_pending токен,
+	// после этого придётся заново ввести пароль
+	mfaPendingAttempts = 5
+	// recoveryCodesCount — сколько кодов восстановления выдаётся при включении 2FA
+	recoveryCodesCount = 10
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrInvalidMFACode    = errors.New("invalid two-factor code")
+)
+
+// EnrollTOTP выдаёт новый секрет TOTP. 2FA включится только после подтверждения кодом из приложения
+func (u *userUseCase) EnrollTOTP(ctx context.Context, userID uuid.UUID) (*domain.TOTPEnrollment, error) {
+	user, err := u.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled, err := u.mfaEnabled(ctx, userID); err != nil {
+		return nil, err
+	} else if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.mfaRepo.SavePendingTOTP(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+	return &domain.TOTPEnrollment{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(u.totpIssuer(), user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP включает 2FA после проверки первого кода и возвращает коды восстановления.
+// Коды показываются один раз — в базе хранятся только их хеши
+func (u *userUseCase) ConfirmTOTP(ctx context.Context, userID uuid.UUID, payload domain.TOTPCodePayload) ([]string, error) {
+	if err := utils.Validate.Struct(payload); err != nil {
+		return nil, err
+	}
+	mfa, err := u.mfaRepo.GetMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrMFANotFound) {
+			return nil, ErrMFANotEnabled
+		}
+		return nil, err
+	}
+	if mfa.EnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(mfa.TOTPSecret, payload.Code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.mfaRepo.EnableTOTP(ctx, userID, step, hashes); err != nil {
+		if errors.Is(err, repo.ErrTOTPStepUsed) {
+			return nil, ErrInvalidMFACode
+		}
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP выключает 2FA. Требует действующий код, чтобы украденной сессии было недостаточно
+func (u *userUseCase) DisableTOTP(ctx context.Context, userID uuid.UUID, payload domain.DisableMFAPayload) error {
+	if err := utils.Validate.Struct(payload); err != nil {
+		return err
+	}
+	if err := u.verifySecondFactor(ctx, userID, payload.Code, payload.RecoveryCode); err != nil {
+		return err
+	}
+	return u.mfaRepo.DisableMFA(ctx, userID)
+}
+
+// LoginMFA обменивает mfa_pending токен и второй фактор на пару токенов. Токен гасится
+// после успешной проверки кода или после mfaPendingAttempts неверных, так что опечатка
+// не заставляет заново вводить пароль, а подбор кода упирается в повторный ввод пароля
+func (u *userUseCase) LoginMFA(ctx context.Context, payload domain.LoginMFAPayload) (*domain.TokenPair, error) {
+	if err := utils.Validate.Struct(payload); err != nil {
+		return nil, err
+	}
+	claims, err := u.parsePurposeToken(payload.MFAToken, domain.PurposeMFAPending)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	jti, err := claims.TokenID()
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// Погашенный после неудачных попыток токен не должен давать проверять коды дальше
+	if _, err := u.otpRepo.FindOneTimeToken(ctx, jti, domain.PurposeMFAPending); err != nil {
+		return nil, err
+	}
+	if err := u.verifySecondFactor(ctx, userID, payload.Code, payload.RecoveryCode); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			err = errors.Join(err, u.otpRepo.FailOneTimeToken(ctx, jti, domain.PurposeMFAPending, mfaPendingAttempts))
+		}
+		return nil, err
+	}
+	if _, err := u.otpRepo.ConsumeOneTimeToken(ctx, jti, domain.PurposeMFAPending); err != nil {
+		return nil, err
+	}
+	return u.issueTokenPair(ctx, userID, uuid.New())
+}
+
+// completeLogin выдаёт пару токенов либо, если у пользователя включена 2FA, mfa_pending токен
+func (u *userUseCase) completeLogin(ctx context.Context, user *domain.User) (*domain.LoginResult, error) {
+	enabled, err := u.mfaEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		token, err := u.issuePurposeToken(ctx, user, domain.PurposeMFAPending, mfaPendingTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.LoginResult{MFAToken: token}, nil
+	}
+
+	// Каждый логин открывает новое семейство refresh-токенов
+	pair, err := u.issueTokenPair(ctx, user.ID, uuid.New())
+	if err != nil {
+		return nil, err
+	}
+	return &domain.LoginResult{Tokens: pair}, nil
+}
+
+// verifySecondFactor проверяет TOTP-код или код восстановления с учётом блокировки после
+// серии неверных кодов
+func (u *userUseCase) verifySecondFactor(ctx context.Context, userID uuid.UUID, code, recoveryCode string) error {
+	key := secondFactorKey(userID)
+	reservation, err := u.limiters.secondFactor.Reserve(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	err = u.checkSecondFactor(ctx, userID, code, recoveryCode)
+	if errors.Is(err, ErrInvalidMFACode) {
+		return err
+	}
+	if err != nil {
+		return errors.Join(err, u.limiters.secondFactor.Release(ctx, reservation))
+	}
+	return u.limiters.secondFactor.Reset(ctx, key)
+}
+
+// checkSecondFactor проверяет TOTP-код или код восстановления и гасит использованный
+func (u *userUseCase) checkSecondFactor(ctx context.Context, userID uuid.UUID, code, recoveryCode string) error {
+	mfa, err := u.mfaRepo.GetMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrMFANotFound) {
+			return ErrMFANotEnabled
+		}
+		return err
+	}
+	if mfa.EnabledAt == nil {
+		return ErrMFANotEnabled
+	}
+
+	if code != "" {
+		step, ok := totp.Validate(mfa.TOTPSecret, code, time.Now())
+		if !ok || step <= mfa.LastUsedStep {
+			return ErrInvalidMFACode
+		}
+		if err := u.mfaRepo.UseTOTPStep(ctx, userID, step); err != nil {
+			if errors.Is(err, repo.ErrTOTPStepUsed) {
+				return ErrInvalidMFACode
+			}
+			return err
+		}
+		return nil
+	}
+
+	if err := u.mfaRepo.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(recoveryCode))); err != nil {
+		if errors.Is(err, repo.ErrRecoveryCodeNotFound) {
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+	return nil
+}
+
+func (u *userUseCase) mfaEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	mfa, err := u.mfaRepo.GetMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrMFANotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return mfa.EnabledAt != nil, nil
+}
+
+// totpIssuer — имя сервиса в приложении-аутентификаторе, берётся из хоста baseURL
+func (u *userUseCase) totpIssuer() string {
+	if parsed, err := url.Parse(u.baseURL); err == nil && parsed.Hostname() != "" {
+		return parsed.Hostname()
+	}
+	return "jwt_auth_project"
+}
+
+// newRecoveryCodes генерирует коды вида "abcde-fghij" и их хеши
+func newRecoveryCodes() ([]string, []string, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(enc.EncodeToString(buf))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode приводит введённый код к виду, в котором он хешировался
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,550 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/utils"
+)
+
+const (
+	// authorizationRequestTTL — сколько запрос авторизации ждёт решения пользователя
+	authorizationRequestTTL = 10 * time.Minute
+	// authorizationCodeTTL — срок жизни кода авторизации
+	authorizationCodeTTL = time.Minute
+)
+
+// Коды ошибок OAuth 2.0 (RFC 6749, раздел 4.1.2.1 и 5.2; RFC 7009, раздел 2.2.1)
+const (
+	OAuthInvalidRequest          = "invalid_request"
+	OAuthInvalidClient           = "invalid_client"
+	OAuthInvalidGrant            = "invalid_grant"
+	OAuthUnauthorizedClient      = "unauthorized_client"
+	OAuthUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthUnsupportedResponseType = "unsupported_response_type"
+	OAuthInvalidScope            = "invalid_scope"
+	OAuthAccessDenied            = "access_denied"
+	OAuthUnsupportedTokenType    = "unsupported_token_type"
+)
+
+var ErrInvalidOAuthClient = errors.New("invalid oauth client configuration")
+
+// OAuthError — ошибка протокола OAuth. RedirectTo заполнен, когда о ней нужно сообщить
+// клиенту редиректом: client_id и redirect_uri уже проверены, и адресу можно доверять
+type OAuthError struct {
+	Code        string
+	Description string
+	RedirectTo  string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// oauthGrant — OAuth-клиент и scopes, для которых выпускается токен.
+// Пустой grant означает собственный вход пользователя
+type oauthGrant struct {
+	clientID string
+	scopes   []string
+}
+
+// refreshGrant возвращает grant, с которым был выдан refresh-токен
+func refreshGrant(t *domain.RefreshToken) oauthGrant {
+	if t.ClientID == nil {
+		return oauthGrant{}
+	}
+	return oauthGrant{clientID: *t.ClientID, scopes: t.Scopes}
+}
+
+// RegisterOAuthClient регистрирует клиента. Секрет выдаётся конфиденциальным клиентам и показывается один раз
+func (u *userUseCase) RegisterOAuthClient(ctx context.Context, createdBy uuid.UUID, payload domain.RegisterOAuthClientPayload) (*domain.RegisteredOAuthClient, error) {
+	payload.Name = strings.TrimSpace(payload.Name)
+	if err := utils.Validate.Struct(payload); err != nil {
+		return nil, err
+	}
+	grants := slices.Clone(payload.GrantTypes)
+	slices.Sort(grants)
+	grants = slices.Compact(grants)
+	scopes := slices.Clone(payload.Scopes)
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+
+	switch {
+	case slices.Contains(grants, domain.GrantAuthorizationCode) && len(payload.RedirectURIs) == 0:
+		return nil, fmt.Errorf("%w: authorization_code requires redirect_uris", ErrInvalidOAuthClient)
+	case slices.Contains(grants, domain.GrantRefreshToken) && !slices.Contains(grants, domain.GrantAuthorizationCode):
+		return nil, fmt.Errorf("%w: refresh_token requires authorization_code", ErrInvalidOAuthClient)
+	case slices.Contains(grants, domain.GrantClientCredentials) && payload.Public:
+		return nil, fmt.Errorf("%w: client_credentials requires a confidential client", ErrInvalidOAuthClient)
+	case payload.Introspect && payload.Public:
+		return nil, fmt.Errorf("%w: introspection requires a confidential client", ErrInvalidOAuthClient)
+	}
+	for _, uri := range payload.RedirectURIs {
+		if parsed, err := url.Parse(uri); err != nil || parsed.Fragment != "" {
+			return nil, fmt.Errorf("%w: invalid redirect uri %q", ErrInvalidOAuthClient, uri)
+		}
+	}
+
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+	client := domain.OAuthClient{
+		ID:           hex.EncodeToString(idBytes),
+		Name:         payload.Name,
+		RedirectURIs: payload.RedirectURIs,
+		GrantTypes:   grants,
+		Scopes:       scopes,
+		Introspect:   payload.Introspect,
+		CreatedBy:    &createdBy,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if client.RedirectURIs == nil {
+		client.RedirectURIs = []string{}
+	}
+	var secret string
+	if !payload.Public {
+		var err error
+		if secret, err = newOpaqueToken(); err != nil {
+			return nil, err
+		}
+		hash := hashToken(secret)
+		client.SecretHash = &hash
+	}
+
+	if err := u.oauthRepo.CreateClient(ctx, client); err != nil {
+		return nil, err
+	}
+	return &domain.RegisteredOAuthClient{OAuthClient: client, Secret: secret}, nil
+}
+
+func (u *userUseCase) ListOAuthClients(ctx context.Context) ([]domain.OAuthClient, error) {
+	return u.oauthRepo.ListClients(ctx)
+}
+
+func (u *userUseCase) DeleteOAuthClient(ctx context.Context, id string) error {
+	return u.oauthRepo.DeleteClient(ctx, id)
+}
+
+// Authorize обрабатывает запрос /oauth/authorize и возвращает, куда отправить браузер. Анонимного
+// пользователя — на страницу входа фронтенда с возвратом обратно сюда. Если пользователь уже соглашался
+// на эти scopes, код выдаётся сразу, иначе — на экран согласия фронтенда.
+// Поддерживается только response_type=code с обязательным PKCE (S256)
+func (u *userUseCase) Authorize(ctx context.Context, userID uuid.UUID, req domain.AuthorizeRequest) (*domain.OAuthRedirect, error) {
+	client, err := u.oauthRepo.GetClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, repo.ErrOAuthClientNotFound) {
+			return nil, &OAuthError{Code: OAuthInvalidRequest, Description: "unknown client_id"}
+		}
+		return nil, err
+	}
+	redirectURI := req.RedirectURI
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !slices.Contains(client.RedirectURIs, redirectURI) {
+		return nil, &OAuthError{Code: OAuthInvalidRequest, Description: "redirect_uri is not registered for the client"}
+	}
+
+	// Дальше о всех ошибках сообщаем клиенту редиректом
+	fail := func(code, description string) error {
+		return &OAuthError{
+			Code:        code,
+			Description: description,
+			RedirectTo:  oauthRedirect(redirectURI, req.State, url.Values{"error": {code}, "error_description": {description}}),
+		}
+	}
+	if req.ResponseType != "code" {
+		return nil, fail(OAuthUnsupportedResponseType, "only response_type=code is supported")
+	}
+	if !slices.Contains(client.GrantTypes, domain.GrantAuthorizationCode) {
+		return nil, fail(OAuthUnauthorizedClient, "client may not use authorization_code")
+	}
+	if req.CodeChallenge == "" {
+		return nil, fail(OAuthInvalidRequest, "code_challenge is required")
+	}
+	if req.CodeChallengeMethod != "S256" {
+		return nil, fail(OAuthInvalidRequest, "code_challenge_method must be S256")
+	}
+	scopes, ok := requestedScopes(req.Scope, u.clientScopes(client))
+	if !ok {
+		return nil, fail(OAuthInvalidScope, "requested scope is not allowed for the client")
+	}
+
+	if userID == uuid.Nil {
+		returnTo := "/oauth/authorize?" + authorizeQuery(req).Encode()
+		return &domain.OAuthRedirect{RedirectTo: u.baseURL + "/login?" + url.Values{"return_to": {returnTo}}.Encode()}, nil
+	}
+
+	now := time.Now().UTC()
+	auth := domain.OAuthAuthorization{
+		ID:                  uuid.New(),
+		ClientID:            client.ID,
+		UserID:              userID,
+		RedirectURI:         redirectURI,
+		RedirectURIExplicit: req.RedirectURI != "",
+		Scopes:              scopes,
+		State:               req.State,
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		ExpiresAt:           now.Add(authorizationRequestTTL),
+		CreatedAt:           now,
+	}
+
+	granted, err := u.oauthRepo.GetConsent(ctx, userID, client.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !isSubset(scopes, granted) {
+		if err := u.oauthRepo.CreateAuthorization(ctx, auth); err != nil {
+			return nil, err
+		}
+		return &domain.OAuthRedirect{RedirectTo: fmt.Sprintf("%s/oauth/consent?id=%s", u.baseURL, auth.ID)}, nil
+	}
+
+	code, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	codeHash := hashToken(code)
+	auth.CodeHash = &codeHash
+	auth.ApprovedAt = &now
+	auth.ExpiresAt = now.Add(authorizationCodeTTL)
+	if err := u.oauthRepo.CreateAuthorization(ctx, auth); err != nil {
+		return nil, err
+	}
+	return &domain.OAuthRedirect{RedirectTo: oauthRedirect(redirectURI, req.State, url.Values{"code": {code}})}, nil
+}
+
+// GetConsentRequest возвращает данные для экрана согласия
+func (u *userUseCase) GetConsentRequest(ctx context.Context, userID, id uuid.UUID) (*domain.OAuthConsentRequest, error) {
+	auth, err := u.oauthRepo.GetPendingAuthorization(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	client, err := u.oauthRepo.GetClient(ctx, auth.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.OAuthConsentRequest{
+		ID:          auth.ID,
+		ClientID:    client.ID,
+		ClientName:  client.Name,
+		RedirectURI: auth.RedirectURI,
+		Scopes:      auth.Scopes,
+		ExpiresAt:   auth.ExpiresAt,
+	}, nil
+}
+
+// DecideConsent применяет решение пользователя и возвращает адрес возврата к клиенту:
+// с кодом авторизации при согласии или с ошибкой access_denied при отказе
+func (u *userUseCase) DecideConsent(ctx context.Context, userID, id uuid.UUID, payload domain.OAuthConsentPayload) (*domain.OAuthRedirect, error) {
+	if !payload.Approve {
+		auth, err := u.oauthRepo.DeleteAuthorization(ctx, userID, id)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.OAuthRedirect{RedirectTo: oauthRedirect(auth.RedirectURI, auth.State, url.Values{
+			"error":             {OAuthAccessDenied},
+			"error_description": {"the user denied the request"},
+		})}, nil
+	}
+
+	code, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	auth, err := u.oauthRepo.ApproveAuthorization(ctx, userID, id, hashToken(code), time.Now().UTC().Add(authorizationCodeTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	granted, err := u.oauthRepo.GetConsent(ctx, userID, auth.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	granted = append(granted, auth.Scopes...)
+	slices.Sort(granted)
+	if err := u.oauthRepo.SaveConsent(ctx, userID, auth.ClientID, slices.Compact(granted)); err != nil {
+		return nil, err
+	}
+
+	return &domain.OAuthRedirect{RedirectTo: oauthRedirect(auth.RedirectURI, auth.State, url.Values{"code": {code}})}, nil
+}
+
+// Token реализует /oauth/token для grant authorization_code, refresh_token и client_credentials
+func (u *userUseCase) Token(ctx context.Context, req domain.OAuthTokenRequest) (*domain.OAuthTokenResponse, error) {
+	switch req.GrantType {
+	case domain.GrantAuthorizationCode, domain.GrantRefreshToken, domain.GrantClientCredentials:
+	default:
+		return nil, &OAuthError{Code: OAuthUnsupportedGrantType, Description: "unsupported grant_type"}
+	}
+
+	client, err := u.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(client.GrantTypes, req.GrantType) {
+		return nil, &OAuthError{Code: OAuthUnauthorizedClient, Description: "grant_type is not allowed for the client"}
+	}
+
+	switch req.GrantType {
+	case domain.GrantAuthorizationCode:
+		return u.exchangeAuthorizationCode(ctx, client, req)
+	case domain.GrantRefreshToken:
+		return u.refreshClientToken(ctx, client, req)
+	default:
+		return u.issueClientCredentialsToken(client, req)
+	}
+}
+
+// authenticateClient проверяет клиента: конфиденциальный должен предъявить секрет, публичный — не должен
+func (u *userUseCase) authenticateClient(ctx context.Context, clientID, secret string) (*domain.OAuthClient, error) {
+	invalid := &OAuthError{Code: OAuthInvalidClient, Description: "client authentication failed"}
+	if clientID == "" {
+		return nil, invalid
+	}
+	client, err := u.oauthRepo.GetClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, repo.ErrOAuthClientNotFound) {
+			return nil, invalid
+		}
+		return nil, err
+	}
+	if !client.Confidential() {
+		if secret != "" {
+			return nil, invalid
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(*client.SecretHash), []byte(hashToken(secret))) != 1 {
+		return nil, invalid
+	}
+	return client, nil
+}
+
+// exchangeAuthorizationCode обменивает код на токены. Клиент, redirect_uri и PKCE проверяются до того,
+// как код будет погашен: чужой или неполный запрос не сжигает код законного клиента. Повторное
+// предъявление уже обменянного кода означает его перехват — выданные по нему токены отзываются
+func (u *userUseCase) exchangeAuthorizationCode(ctx context.Context, client *domain.OAuthClient, req domain.OAuthTokenRequest) (*domain.OAuthTokenResponse, error) {
+	invalid := &OAuthError{Code: OAuthInvalidGrant, Description: "invalid authorization code"}
+	if req.Code == "" || req.CodeVerifier == "" {
+		return nil, &OAuthError{Code: OAuthInvalidRequest, Description: "code and code_verifier are required"}
+	}
+
+	codeHash := hashToken(req.Code)
+	auth, err := u.oauthRepo.GetAuthorizationByCode(ctx, codeHash)
+	if err != nil {
+		if errors.Is(err, repo.ErrAuthorizationNotFound) {
+			return nil, invalid
+		}
+		return nil, err
+	}
+	if auth.ClientID != client.ID || !verifyCodeChallenge(auth.CodeChallenge, req.CodeVerifier) {
+		return nil, invalid
+	}
+	// Если redirect_uri не передавался в /oauth/authorize, клиент вправе не передавать его и здесь
+	if (auth.RedirectURIExplicit || req.RedirectURI != "") && auth.RedirectURI != req.RedirectURI {
+		return nil, invalid
+	}
+
+	familyID := uuid.New()
+	auth, err = u.oauthRepo.ConsumeAuthorizationCode(ctx, codeHash, familyID)
+	switch {
+	case errors.Is(err, repo.ErrAuthorizationCodeUsed):
+		if auth.FamilyID != nil {
+			if err := u.revokeSession(ctx, *auth.FamilyID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, invalid
+	case errors.Is(err, repo.ErrAuthorizationNotFound):
+		return nil, invalid
+	case err != nil:
+		return nil, err
+	}
+
+	grant := oauthGrant{clientID: client.ID, scopes: auth.Scopes}
+	pair, err := u.issueGrantTokens(ctx, auth.UserID, familyID, grant, slices.Contains(client.GrantTypes, domain.GrantRefreshToken))
+	if err != nil {
+		return nil, err
+	}
+	resp := u.tokenResponse(pair, grant.scopes)
+	if u.wantIDToken(grant.scopes) {
+		if resp.IDToken, err = u.issueIDToken(ctx, auth.UserID, client.ID, grant.scopes, auth.Nonce); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// refreshClientToken обновляет токены клиента. Через scope можно сузить права нового access-токена
+func (u *userUseCase) refreshClientToken(ctx context.Context, client *domain.OAuthClient, req domain.OAuthTokenRequest) (*domain.OAuthTokenResponse, error) {
+	invalid := &OAuthError{Code: OAuthInvalidGrant, Description: "invalid refresh token"}
+	if req.RefreshToken == "" {
+		return nil, &OAuthError{Code: OAuthInvalidRequest, Description: "refresh_token is required"}
+	}
+	stored, err := u.refreshRepo.GetRefreshTokenByHash(ctx, hashToken(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, repo.ErrRefreshTokenNotFound) {
+			return nil, invalid
+		}
+		return nil, err
+	}
+	if stored.ClientID == nil || *stored.ClientID != client.ID {
+		return nil, invalid
+	}
+	scopes, ok := requestedScopes(req.Scope, stored.Scopes)
+	if !ok {
+		return nil, &OAuthError{Code: OAuthInvalidScope, Description: "scope exceeds the original grant"}
+	}
+
+	pair, err := u.rotateRefreshToken(ctx, stored, oauthGrant{clientID: client.ID, scopes: scopes})
+	if err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+			return nil, invalid
+		}
+		return nil, err
+	}
+	resp := u.tokenResponse(pair, scopes)
+	if u.wantIDToken(scopes) {
+		if resp.IDToken, err = u.issueIDToken(ctx, stored.UserID, client.ID, scopes, ""); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// issueClientCredentialsToken выпускает токен, которым клиент действует от своего имени:
+// Subject — client_id, пользователя и сессии нет, refresh-токен не выдаётся
+func (u *userUseCase) issueClientCredentialsToken(client *domain.OAuthClient, req domain.OAuthTokenRequest) (*domain.OAuthTokenResponse, error) {
+	scopes, ok := requestedScopes(req.Scope, u.clientScopes(client))
+	if !ok {
+		return nil, &OAuthError{Code: OAuthInvalidScope, Description: "requested scope is not allowed for the client"}
+	}
+
+	now := time.Now()
+	exp := now.Add(u.ttl)
+	signed, err := u.signClaims(domain.Claims{
+		RegisteredClaims: u.registeredClaims(uuid.NewString(), client.ID, now, u.ttl),
+		ClientID:         client.ID,
+		Scope:            strings.Join(scopes, " "),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u.tokenResponse(&domain.TokenPair{AccessToken: signed, AccessExpiresAt: exp}, scopes), nil
+}
+
+// ClientPermissions возвращает права токена client_credentials: его scopes, которые являются правами
+// и всё ещё разрешены клиенту. Токен удалённого клиента недействителен
+func (u *userUseCase) ClientPermissions(ctx context.Context, claims *domain.Claims) ([]string, error) {
+	client, err := u.oauthRepo.GetClient(ctx, claims.ClientID)
+	if errors.Is(err, repo.ErrOAuthClientNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	var permissions []string
+	for _, scope := range claims.Scopes() {
+		if slices.Contains(domain.Permissions, scope) && slices.Contains(client.Scopes, scope) {
+			permissions = append(permissions, scope)
+		}
+	}
+	return permissions, nil
+}
+
+// tokenResponse переводит пару токенов в ответ /oauth/token
+func (u *userUseCase) tokenResponse(pair *domain.TokenPair, scopes []string) *domain.OAuthTokenResponse {
+	return &domain.OAuthTokenResponse{
+		AccessToken:  pair.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(time.Until(pair.AccessExpiresAt).Seconds()),
+		RefreshToken: pair.RefreshToken,
+		Scope:        strings.Join(scopes, " "),
+	}
+}
+
+// requestedScopes разбирает параметр scope. Пустой scope означает все allowed.
+// ok=false, если запрошено что-то сверх allowed
+func requestedScopes(scope string, allowed []string) ([]string, bool) {
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		return slices.Clone(allowed), true
+	}
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+	return scopes, isSubset(scopes, allowed)
+}
+
+// isSubset сообщает, что все элементы items есть в set
+func isSubset(items, set []string) bool {
+	for _, item := range items {
+		if !slices.Contains(set, item) {
+			return false
+		}
+	}
+	return true
+}
+
+// verifyCodeChallenge проверяет PKCE: BASE64URL(SHA256(verifier)) должен совпасть с challenge
+func verifyCodeChallenge(challenge, verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// authorizeQuery собирает параметры /oauth/authorize обратно в query
+func authorizeQuery(req domain.AuthorizeRequest) url.Values {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"response_type":         req.ResponseType,
+		"client_id":             req.ClientID,
+		"redirect_uri":          req.RedirectURI,
+		"scope":                 req.Scope,
+		"state":                 req.State,
+		"nonce":                 req.Nonce,
+		"code_challenge":        req.CodeChallenge,
+		"code_challenge_method": req.CodeChallengeMethod,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	return query
+}
+
+// oauthRedirect добавляет к redirectURI параметры ответа и state
+func oauthRedirect(redirectURI, state string, params url.Values) string {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	target.RawQuery = query.Encode()
+	return target.String()
+}
@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/domain"
+)
+
+const testRedirectURI = "https://client.example.com/callback"
+
+// pkcePair возвращает code_verifier и S256 code_challenge для него
+func pkcePair(seed string) (verifier, challenge string) {
+	verifier = strings.Repeat(seed, 43/len(seed)+1)[:43]
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// registerTestClient регистрирует конфиденциального клиента с authorization_code и refresh_token
+func registerTestClient(t *testing.T, env *testEnv, redirectURIs ...string) *domain.RegisteredOAuthClient {
+	t.Helper()
+	if len(redirectURIs) == 0 {
+		redirectURIs = []string{testRedirectURI}
+	}
+	client, err := env.uc.RegisterOAuthClient(context.Background(), uuid.New(), domain.RegisterOAuthClientPayload{
+		Name:         "Test client",
+		RedirectURIs: redirectURIs,
+		GrantTypes:   []string{domain.GrantAuthorizationCode, domain.GrantRefreshToken},
+		Scopes:       []string{domain.ScopeOpenID, domain.ScopeProfile, domain.ScopeEmail},
+	})
+	if err != nil {
+		t.Fatalf("RegisterOAuthClient: %v", err)
+	}
+	return client
+}
+
+// authorizeCode проводит /oauth/authorize с согласием пользователя и возвращает код
+func authorizeCode(t *testing.T, env *testEnv, userID uuid.UUID, req domain.AuthorizeRequest) string {
+	t.Helper()
+	ctx := context.Background()
+	redirect, err := env.uc.Authorize(ctx, userID, req)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	target, err := url.Parse(redirect.RedirectTo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := target.Query().Get("id"); strings.HasSuffix(target.Path, "/oauth/consent") {
+		consentID, err := uuid.Parse(id)
+		if err != nil {
+			t.Fatalf("consent redirect %q: %v", redirect.RedirectTo, err)
+		}
+		redirect, err = env.uc.DecideConsent(ctx, userID, consentID, domain.OAuthConsentPayload{Approve: true})
+		if err != nil {
+			t.Fatalf("DecideConsent: %v", err)
+		}
+		if target, err = url.Parse(redirect.RedirectTo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	code := target.Query().Get("code")
+	if code == "" {
+		t.Fatalf("no code in redirect %q", redirect.RedirectTo)
+	}
+	if got := target.Query().Get("state"); got != req.State {
+		t.Fatalf("state = %q, want %q", got, req.State)
+	}
+	return code
+}
+
+func wantOAuthError(t *testing.T, err error, code string) {
+	t.Helper()
+	var oauthErr *OAuthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != code {
+		t.Fatalf("err = %v, want OAuth error %s", err, code)
+	}
+}
+
+func TestAuthorizationCodeRedirectURI(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.addUser(t, "alice@example.com", "correct horse battery")
+	client := registerTestClient(t, env)
+	verifier, challenge := pkcePair("v")
+
+	authorize := func(redirectURI string) string {
+		return authorizeCode(t, env, user.ID, domain.AuthorizeRequest{
+			ResponseType:        "code",
+			ClientID:            client.ID,
+			RedirectURI:         redirectURI,
+			State:               "xyz",
+			CodeChallenge:       challenge,
+			CodeChallengeMethod: "S256",
+		})
+	}
+	exchange := func(code, redirectURI string) error {
+		_, err := env.uc.Token(ctx, domain.OAuthTokenRequest{
+			GrantType:    domain.GrantAuthorizationCode,
+			ClientID:     client.ID,
+			ClientSecret: client.Secret,
+			Code:         code,
+			RedirectURI:  redirectURI,
+			CodeVerifier: verifier,
+		})
+		return err
+	}
+
+	t.Run("omitted at authorize", func(t *testing.T) {
+		if err := exchange(authorize(""), ""); err != nil {
+			t.Fatalf("Token without redirect_uri: %v", err)
+		}
+		if err := exchange(authorize(""), testRedirectURI); err != nil {
+			t.Fatalf("Token with the registered redirect_uri: %v", err)
+		}
+		wantOAuthError(t, exchange(authorize(""), "https://client.example.com/other"), OAuthInvalidGrant)
+	})
+
+	t.Run("sent at authorize", func(t *testing.T) {
+		code := authorize(testRedirectURI)
+		wantOAuthError(t, exchange(code, ""), OAuthInvalidGrant)
+		// Неполный запрос не погасил код
+		if err := exchange(code, testRedirectURI); err != nil {
+			t.Fatalf("Token with redirect_uri: %v", err)
+		}
+	})
+}
+
+func TestAuthorizationCodeChecksBeforeConsume(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.addUser(t, "alice@example.com", "correct horse battery")
+	client := registerTestClient(t, env)
+	other := registerTestClient(t, env)
+	verifier, challenge := pkcePair("v")
+
+	code := authorizeCode(t, env, user.ID, domain.AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            client.ID,
+		RedirectURI:         testRedirectURI,
+		CodeChallenge:       challenge,
+		CodeChallengeMethod: "S256",
+	})
+	req := domain.OAuthTokenRequest{
+		GrantType:    domain.GrantAuthorizationCode,
+		ClientID:     client.ID,
+		ClientSecret: client.Secret,
+		Code:         code,
+		RedirectURI:  testRedirectURI,
+		CodeVerifier: verifier,
+	}
+
+	wrongVerifier := req
+	wrongVerifier.CodeVerifier, _ = pkcePair("w")
+	_, err := env.uc.Token(ctx, wrongVerifier)
+	wantOAuthError(t, err, OAuthInvalidGrant)
+
+	otherClient := req
+	otherClient.ClientID, otherClient.ClientSecret = other.ID, other.Secret
+	_, err = env.uc.Token(ctx, otherClient)
+	wantOAuthError(t, err, OAuthInvalidGrant)
+
+	resp, err := env.uc.Token(ctx, req)
+	if err != nil {
+		t.Fatalf("Token after rejected attempts: %v", err)
+	}
+
+	// Повторный обмен отзывает выданные по коду токены
+	_, err = env.uc.Token(ctx, req)
+	wantOAuthError(t, err, OAuthInvalidGrant)
+	if _, err := env.uc.ValidateToken(ctx, resp.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("access token after code reuse err = %v, want ErrTokenRevoked", err)
+	}
+	_, err = env.uc.Token(ctx, domain.OAuthTokenRequest{
+		GrantType:    domain.GrantRefreshToken,
+		ClientID:     client.ID,
+		ClientSecret: client.Secret,
+		RefreshToken: resp.RefreshToken,
+	})
+	wantOAuthError(t, err, OAuthInvalidGrant)
+}
+
+func TestClientPermissions(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	client, err := env.uc.RegisterOAuthClient(ctx, uuid.New(), domain.RegisterOAuthClientPayload{
+		Name:         "Service",
+		RedirectURIs: []string{testRedirectURI},
+		GrantTypes:   []string{domain.GrantClientCredentials},
+		Scopes:       []string{domain.ScopeEmail, domain.PermAdsWrite, domain.PermUsersManage},
+	})
+	if err != nil {
+		t.Fatalf("RegisterOAuthClient: %v", err)
+	}
+	resp, err := env.uc.Token(ctx, domain.OAuthTokenRequest{
+		GrantType:    domain.GrantClientCredentials,
+		ClientID:     client.ID,
+		ClientSecret: client.Secret,
+		Scope:        "email users:manage ads:write",
+	})
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	claims, err := env.uc.ValidateToken(ctx, resp.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if !claims.ClientToken() {
+		t.Fatalf("claims = %+v, want a client_credentials token", claims)
+	}
+
+	// email — не право; ads:write у клиента отобрали после выдачи токена
+	stored, _ := env.oauth.GetClient(ctx, client.ID)
+	stored.Scopes = []string{domain.ScopeEmail, domain.PermUsersManage}
+	if err := env.oauth.CreateClient(ctx, *stored); err != nil {
+		t.Fatal(err)
+	}
+	permissions, err := env.uc.ClientPermissions(ctx, claims)
+	if err != nil {
+		t.Fatalf("ClientPermissions: %v", err)
+	}
+	if !slices.Equal(permissions, []string{domain.PermUsersManage}) {
+		t.Fatalf("permissions = %v, want [%s]", permissions, domain.PermUsersManage)
+	}
+
+	if err := env.uc.DeleteOAuthClient(ctx, client.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.uc.ClientPermissions(ctx, claims); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("deleted client err = %v, want ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/domain"
+)
+
+// ErrInsufficientScope — токен валиден, но выдан без scope, нужного для запроса
+var ErrInsufficientScope = errors.New("insufficient scope")
+
+// idTokensSupported сообщает, работает ли OpenID Connect. ID-токен должен проверяться по JWKS,
+// а HS256 подписывается нашим общим секретом, которого у клиентов нет (OIDC Core, раздел 10.1).
+// С симметричным ключом scope openid не выдаётся и ID-токены не выпускаются
+func (u *userUseCase) idTokensSupported() bool {
+	return u.keys.Current.Asymmetric()
+}
+
+// clientScopes возвращает scopes, которые клиент может получить сейчас: без openid, если
+// ID-токены не поддерживаются
+func (u *userUseCase) clientScopes(client *domain.OAuthClient) []string {
+	if u.idTokensSupported() {
+		return client.Scopes
+	}
+	return slices.DeleteFunc(slices.Clone(client.Scopes), func(s string) bool { return s == domain.ScopeOpenID })
+}
+
+// OpenIDConfiguration возвращает discovery-документ. Адреса строятся от issuer. Без асимметричного
+// ключа openid и алгоритмы подписи ID-токенов не объявляются
+func (u *userUseCase) OpenIDConfiguration() domain.OpenIDConfiguration {
+	conf := domain.OpenIDConfiguration{
+		Issuer:                            u.issuer,
+		AuthorizationEndpoint:             u.issuer + "/oauth/authorize",
+		TokenEndpoint:                     u.issuer + "/oauth/token",
+		UserInfoEndpoint:                  u.issuer + "/userinfo",
+		IntrospectionEndpoint:             u.issuer + "/oauth/introspect",
+		RevocationEndpoint:                u.issuer + "/oauth/revoke",
+		JWKSURI:                           u.issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   append([]string{domain.ScopeOpenID, domain.ScopeProfile, domain.ScopeEmail}, domain.Permissions...),
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{domain.GrantAuthorizationCode, domain.GrantRefreshToken, domain.GrantClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{u.keys.Current.Algorithm},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "exp", "iat", "azp", "nonce", "email", "email_verified", "preferred_username"},
+	}
+	if !u.idTokensSupported() {
+		conf.ScopesSupported = slices.DeleteFunc(conf.ScopesSupported, func(s string) bool { return s == domain.ScopeOpenID })
+		conf.IDTokenSigningAlgValuesSupported = []string{}
+	}
+	return conf
+}
+
+// UserInfo возвращает сведения о пользователе по access-токену OAuth-клиента со scope openid.
+// Состав полей определяется остальными scopes токена
+func (u *userUseCase) UserInfo(ctx context.Context, accessToken string) (*domain.UserInfo, error) {
+	claims, err := u.ValidateToken(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	// Токен client_credentials выдан самому клиенту, пользователя за ним нет. Проверяем это явно:
+	// client_id из 32 hex-символов разбирается и как UUID
+	if claims.ClientToken() {
+		return nil, ErrInsufficientScope
+	}
+	scopes := claims.Scopes()
+	if claims.ClientID == "" || !slices.Contains(scopes, domain.ScopeOpenID) {
+		return nil, ErrInsufficientScope
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := u.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	info := &domain.UserInfo{Subject: user.ID.String()}
+	if slices.Contains(scopes, domain.ScopeEmail) {
+		verified := user.EmailVerifiedAt != nil
+		info.Email = user.Email
+		info.EmailVerified = &verified
+	}
+	if slices.Contains(scopes, domain.ScopeProfile) {
+		info.PreferredUsername = user.Username
+	}
+	return info, nil
+}
+
+// wantIDToken сообщает, нужен ли в ответе /oauth/token ID-токен
+func (u *userUseCase) wantIDToken(scopes []string) bool {
+	return u.idTokensSupported() && slices.Contains(scopes, domain.ScopeOpenID)
+}
+
+// issueIDToken выпускает ID-токен для клиента clientID. Сведения о пользователе берутся
+// из domain.User в момент выпуска и ограничены scopes
+func (u *userUseCase) issueIDToken(ctx context.Context, userID uuid.UUID, clientID string, scopes []string, nonce string) (string, error) {
+	user, err := u.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := domain.IDTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    u.issuer,
+			Subject:   user.ID.String(),
+			Audience:  jwt.ClaimStrings{clientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(u.ttl)),
+		},
+		AuthorizedParty: clientID,
+		Nonce:           nonce,
+	}
+	if slices.Contains(scopes, domain.ScopeEmail) {
+		verified := user.EmailVerifiedAt != nil
+		claims.Email = user.Email
+		claims.EmailVerified = &verified
+	}
+	if slices.Contains(scopes, domain.ScopeProfile) {
+		claims.PreferredUsername = user.Username
+	}
+	return u.signClaims(claims)
+}
@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/domain"
+)
+
+func TestOIDCDisabledWithSymmetricKey(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	if env.uc.keys.Current.Asymmetric() {
+		t.Fatal("test env is expected to sign with HS256")
+	}
+	user := env.addUser(t, "alice@example.com", "correct horse battery")
+	client := registerTestClient(t, env)
+
+	conf := env.uc.OpenIDConfiguration()
+	if slices.Contains(conf.ScopesSupported, domain.ScopeOpenID) || len(conf.IDTokenSigningAlgValuesSupported) != 0 {
+		t.Fatalf("discovery advertises OIDC with HS256: scopes %v, algs %v", conf.ScopesSupported, conf.IDTokenSigningAlgValuesSupported)
+	}
+
+	verifier, challenge := pkcePair("v")
+	req := domain.AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            client.ID,
+		RedirectURI:         testRedirectURI,
+		Scope:               "openid email",
+		CodeChallenge:       challenge,
+		CodeChallengeMethod: "S256",
+	}
+	_, err := env.uc.Authorize(ctx, user.ID, req)
+	wantOAuthError(t, err, OAuthInvalidScope)
+
+	// Без явного scope клиент получает всё разрешённое, кроме openid
+	req.Scope = ""
+	resp, err := env.uc.Token(ctx, domain.OAuthTokenRequest{
+		GrantType:    domain.GrantAuthorizationCode,
+		ClientID:     client.ID,
+		ClientSecret: client.Secret,
+		Code:         authorizeCode(t, env, user.ID, req),
+		RedirectURI:  testRedirectURI,
+		CodeVerifier: verifier,
+	})
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	if resp.IDToken != "" || slices.Contains(strings.Fields(resp.Scope), domain.ScopeOpenID) {
+		t.Fatalf("token response = %+v, want no id_token and no openid scope", resp)
+	}
+}
+
+func TestUserInfoRejectsClientCredentialsToken(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	// client_id из 32 hex-символов — корректный UUID. Пользователь с таким ID не должен
+	// отдаваться по токену, выданному самому клиенту
+	const clientID = "0123456789abcdef0123456789abcdef"
+	if err := env.users.CreateUser(ctx, domain.User{ID: uuid.MustParse(clientID), Username: "alice", Email: "alice@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	token, err := env.uc.signClaims(domain.Claims{
+		RegisteredClaims: env.uc.registeredClaims(uuid.NewString(), clientID, now, time.Minute),
+		ClientID:         clientID,
+		Scope:            "openid email",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.uc.UserInfo(ctx, token); !errors.Is(err, ErrInsufficientScope) {
+		t.Fatalf("err = %v, want ErrInsufficientScope", err)
+	}
+}
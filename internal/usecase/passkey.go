@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/utils"
+	"jwt_auth_project/internal/webauthn"
+)
+
+var ErrPasskeyRejected = errors.New("passkey verification failed")
+
+// BeginPasskeyRegistration выдаёт параметры для navigator.credentials.create()
+func (u *userUseCase) BeginPasskeyRegistration(ctx context.Context, userID uuid.UUID) (*domain.WebAuthnRegistrationOptions, error) {
+	user, err := u.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := u.webauthnRepo.ListCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	exclude := make([][]byte, 0, len(existing))
+	for _, c := range existing {
+		exclude = append(exclude, c.CredentialID)
+	}
+
+	challenge, err := u.newWebAuthnChallenge(ctx, &userID, domain.WebAuthnRegistration)
+	if err != nil {
+		return nil, err
+	}
+	// user handle — id пользователя: по нему при входе discoverable-ключом находится аккаунт
+	return &domain.WebAuthnRegistrationOptions{
+		ChallengeID: challenge.ID,
+		PublicKey:   u.rp.CreationOptions(challenge.Challenge, userID[:], user.Email, user.Username, exclude),
+	}, nil
+}
+
+// FinishPasskeyRegistration проверяет ответ аутентификатора и сохраняет новый passkey
+func (u *userUseCase) FinishPasskeyRegistration(ctx context.Context, userID uuid.UUID, payload domain.WebAuthnRegisterPayload) (*domain.WebAuthnCredential, error) {
+	payload.Name = strings.TrimSpace(payload.Name)
+	if err := utils.Validate.Struct(payload); err != nil {
+		return nil, err
+	}
+	challenge, err := u.webauthnRepo.ConsumeChallenge(ctx, payload.ChallengeID, domain.WebAuthnRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.UserID == nil || *challenge.UserID != userID {
+		return nil, repo.ErrChallengeInvalid
+	}
+
+	verified, err := u.rp.VerifyRegistration(payload.Credential, challenge.Challenge)
+	if err != nil {
+		return nil, errors.Join(ErrPasskeyRejected, err)
+	}
+
+	if payload.Name == "" {
+		payload.Name = "Passkey"
+	}
+	cred := domain.WebAuthnCredential{
+		ID:           uuid.New(),
+		UserID:       userID,
+		CredentialID: verified.ID,
+		PublicKey:    verified.PublicKey,
+		SignCount:    int64(verified.SignCount),
+		Name:         payload.Name,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if err := u.webauthnRepo.CreateCredential(ctx, cred); err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+// BeginPasskeyLogin выдаёт параметры для navigator.credentials.get(). Аккаунт заранее не указывается:
+// его определит discoverable-ключ, поэтому эндпоинт ничего не говорит о существовании пользователей
+func (u *userUseCase) BeginPasskeyLogin(ctx context.Context) (*domain.WebAuthnLoginOptions, error) {
+	challenge, err := u.newWebAuthnChallenge(ctx, nil, domain.WebAuthnLogin)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.WebAuthnLoginOptions{
+		ChallengeID: challenge.ID,
+		PublicKey:   u.rp.RequestOptions(challenge.Challenge, nil),
+	}, nil
+}
+
+// FinishPasskeyLogin проверяет подпись аутентификатора и выдаёт пару токенов.
+// Passkey с обязательной верификацией пользователя сам по себе двухфакторный, поэтому TOTP не запрашивается
+func (u *userUseCase) FinishPasskeyLogin(ctx context.Context, payload domain.WebAuthnLoginPayload) (*domain.TokenPair, error) {
+	if err := utils.Validate.Struct(payload); err != nil {
+		return nil, err
+	}
+	challenge, err := u.webauthnRepo.ConsumeChallenge(ctx, payload.ChallengeID, domain.WebAuthnLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	cred, err := u.webauthnRepo.GetCredential(ctx, payload.Credential.RawID)
+	if err != nil {
+		if errors.Is(err, repo.ErrCredentialNotFound) {
+			return nil, ErrPasskeyRejected
+		}
+		return nil, err
+	}
+	if handle := payload.Credential.Response.UserHandle; len(handle) > 0 && !bytes.Equal(handle, cred.UserID[:]) {
+		return nil, ErrPasskeyRejected
+	}
+
+	signCount, err := u.rp.VerifyAssertion(payload.Credential, challenge.Challenge, cred.PublicKey, uint32(cred.SignCount))
+	if err != nil {
+		return nil, errors.Join(ErrPasskeyRejected, err)
+	}
+	if err := u.webauthnRepo.UpdateSignCount(ctx, cred.ID, int64(signCount)); err != nil {
+		return nil, err
+	}
+
+	return u.issueTokenPair(ctx, cred.UserID, uuid.New())
+}
+
+func (u *userUseCase) ListPasskeys(ctx context.Context, userID uuid.UUID) ([]domain.WebAuthnCredential, error) {
+	return u.webauthnRepo.ListCredentials(ctx, userID)
+}
+
+func (u *userUseCase) DeletePasskey(ctx context.Context, userID, id uuid.UUID) error {
+	return u.webauthnRepo.DeleteCredential(ctx, userID, id)
+}
+
+func (u *userUseCase) newWebAuthnChallenge(ctx context.Context, userID *uuid.UUID, purpose string) (*domain.WebAuthnChallenge, error) {
+	raw, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	challenge := domain.WebAuthnChallenge{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Purpose:   purpose,
+		Challenge: raw,
+		ExpiresAt: now.Add(webauthn.ChallengeTimeout),
+		CreatedAt: now,
+	}
+	if err := u.webauthnRepo.CreateChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
@@ -0,0 +1,196 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/webauthn"
+	"jwt_auth_project/internal/webauthn/webauthntest"
+)
+
+// registerPasskey проводит регистрацию passkey пользователя программным аутентификатором
+func registerPasskey(t *testing.T, env *testEnv, user *domain.User) *webauthntest.Authenticator {
+	t.Helper()
+	ctx := context.Background()
+	auth, err := webauthntest.New(testRPID, testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.UserHandle = user.ID[:]
+
+	opts, err := env.uc.BeginPasskeyRegistration(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("BeginPasskeyRegistration: %v", err)
+	}
+	_, err = env.uc.FinishPasskeyRegistration(ctx, user.ID, domain.WebAuthnRegisterPayload{
+		ChallengeID: opts.ChallengeID,
+		Credential:  auth.Create(opts.PublicKey.Challenge),
+	})
+	if err != nil {
+		t.Fatalf("FinishPasskeyRegistration: %v", err)
+	}
+	return auth
+}
+
+// passkeyLogin запрашивает challenge входа и подписывает его аутентификатором
+func passkeyLogin(t *testing.T, env *testEnv, auth *webauthntest.Authenticator) domain.WebAuthnLoginPayload {
+	t.Helper()
+	opts, err := env.uc.BeginPasskeyLogin(context.Background())
+	if err != nil {
+		t.Fatalf("BeginPasskeyLogin: %v", err)
+	}
+	assertion, err := auth.Get(opts.PublicKey.Challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return domain.WebAuthnLoginPayload{ChallengeID: opts.ChallengeID, Credential: assertion}
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.addUser(t, "alice@example.com", "correct horse battery")
+	auth := registerPasskey(t, env, user)
+
+	creds, _ := env.uc.ListPasskeys(ctx, user.ID)
+	if len(creds) != 1 || creds[0].Name != "Passkey" {
+		t.Fatalf("passkeys = %+v, want one named Passkey", creds)
+	}
+
+	pair, err := env.uc.FinishPasskeyLogin(ctx, passkeyLogin(t, env, auth))
+	if err != nil {
+		t.Fatalf("FinishPasskeyLogin: %v", err)
+	}
+	claims, err := env.uc.ValidateToken(ctx, pair.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if claims.Subject != user.ID.String() {
+		t.Fatalf("sub = %q, want %s", claims.Subject, user.ID)
+	}
+	if creds, _ := env.uc.ListPasskeys(ctx, user.ID); creds[0].SignCount != int64(auth.SignCount) || creds[0].LastUsedAt == nil {
+		t.Fatalf("credential after login = %+v, want sign count %d", creds[0], auth.SignCount)
+	}
+}
+
+func TestPasskeyRegistrationChallengeBoundToUser(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	alice := env.addUser(t, "alice@example.com", "correct horse battery")
+	mallory := env.addUser(t, "mallory@example.com", "correct horse battery")
+	auth, err := webauthntest.New(testRPID, testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := env.uc.BeginPasskeyRegistration(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = env.uc.FinishPasskeyRegistration(ctx, mallory.ID, domain.WebAuthnRegisterPayload{
+		ChallengeID: opts.ChallengeID,
+		Credential:  auth.Create(opts.PublicKey.Challenge),
+	})
+	if !errors.Is(err, repo.ErrChallengeInvalid) {
+		t.Fatalf("err = %v, want ErrChallengeInvalid", err)
+	}
+}
+
+func TestPasskeyChallengeReplay(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.addUser(t, "alice@example.com", "correct horse battery")
+	auth := registerPasskey(t, env, user)
+
+	payload := passkeyLogin(t, env, auth)
+	if _, err := env.uc.FinishPasskeyLogin(ctx, payload); err != nil {
+		t.Fatalf("first FinishPasskeyLogin: %v", err)
+	}
+	if _, err := env.uc.FinishPasskeyLogin(ctx, payload); !errors.Is(err, repo.ErrChallengeInvalid) {
+		t.Fatalf("replayed login err = %v, want ErrChallengeInvalid", err)
+	}
+
+	// Неудачная попытка тоже тратит challenge
+	payload = passkeyLogin(t, env, auth)
+	payload.Credential.Response.Signature[len(payload.Credential.Response.Signature)-1] ^= 1
+	if _, err := env.uc.FinishPasskeyLogin(ctx, payload); !errors.Is(err, ErrPasskeyRejected) {
+		t.Fatalf("bad signature err = %v, want ErrPasskeyRejected", err)
+	}
+	if _, err := env.uc.FinishPasskeyLogin(ctx, payload); !errors.Is(err, repo.ErrChallengeInvalid) {
+		t.Fatalf("retry err = %v, want ErrChallengeInvalid", err)
+	}
+}
+
+func TestPasskeyChallengeExpired(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.addUser(t, "alice@example.com", "correct horse battery")
+
+	auth, err := webauthntest.New(testRPID, testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := env.uc.BeginPasskeyRegistration(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.webauthn.ExpireChallenge(opts.ChallengeID)
+	_, err = env.uc.FinishPasskeyRegistration(ctx, user.ID, domain.WebAuthnRegisterPayload{
+		ChallengeID: opts.ChallengeID,
+		Credential:  auth.Create(opts.PublicKey.Challenge),
+	})
+	if !errors.Is(err, repo.ErrChallengeInvalid) {
+		t.Fatalf("registration err = %v, want ErrChallengeInvalid", err)
+	}
+
+	auth = registerPasskey(t, env, user)
+	payload := passkeyLogin(t, env, auth)
+	env.webauthn.ExpireChallenge(payload.ChallengeID)
+	if _, err := env.uc.FinishPasskeyLogin(ctx, payload); !errors.Is(err, repo.ErrChallengeInvalid) {
+		t.Fatalf("login err = %v, want ErrChallengeInvalid", err)
+	}
+}
+
+func TestPasskeyLoginRejected(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		mutate func(t *testing.T, env *testEnv, auth *webauthntest.Authenticator)
+	}{
+		{"sign count rollback", func(_ *testing.T, _ *testEnv, auth *webauthntest.Authenticator) {
+			auth.SignCount = 0
+		}},
+		{"user handle of another account", func(t *testing.T, env *testEnv, auth *webauthntest.Authenticator) {
+			other := env.addUser(t, "bob@example.com", "correct horse battery")
+			auth.UserHandle = other.ID[:]
+		}},
+		{"unknown credential", func(_ *testing.T, _ *testEnv, auth *webauthntest.Authenticator) {
+			auth.CredentialID = []byte("unknown credential")
+		}},
+		{"no user verification", func(_ *testing.T, _ *testEnv, auth *webauthntest.Authenticator) {
+			auth.Flags &^= webauthn.FlagUserVerified
+		}},
+		{"wrong origin", func(_ *testing.T, _ *testEnv, auth *webauthntest.Authenticator) {
+			auth.Origin = "https://evil.example.com"
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			user := env.addUser(t, "alice@example.com", "correct horse battery")
+			auth := registerPasskey(t, env, user)
+			// Первый вход поднимает счётчик до 1, чтобы откат к нему был заметен
+			if _, err := env.uc.FinishPasskeyLogin(ctx, passkeyLogin(t, env, auth)); err != nil {
+				t.Fatalf("FinishPasskeyLogin: %v", err)
+			}
+
+			tt.mutate(t, env, auth)
+			if _, err := env.uc.FinishPasskeyLogin(ctx, passkeyLogin(t, env, auth)); !errors.Is(err, ErrPasskeyRejected) {
+				t.Fatalf("err = %v, want ErrPasskeyRejected", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/mailer"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/utils"
+)
+
+// passwordResetTTL — сколько живёт ссылка сброса пароля
+const passwordResetTTL = time.Hour
+
+// ForgotPassword запускает отправку ссылки сброса пароля. Для существующих и несуществующих
+// адресов поведение одинаковое: вся работа идёт в фоне, а ответ не зависит от результата,
+// чтобы эндпоинт нельзя было использовать для перебора аккаунтов. Запросы ограничиваются
+// по адресу почты и по адресу клиента, иначе эндпоинтом можно завалить письмами любой ящик
+func (u *userUseCase) ForgotPassword(ctx context.Context, payload domain.ForgotPasswordPayload) error {
+	payload.Email = strings.TrimSpace(payload.Email)
+	if err := utils.Validate.Struct(payload); err != nil {
+		return err
+	}
+	if err := u.limitPasswordReset(ctx, payload.Email); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := u.sendPasswordReset(context.WithoutCancel(ctx), payload.Email); err != nil {
+			slog.Error("forgot password: send reset link failed", "error", err)
+		}
+	}()
+	return nil
+}
+
+func (u *userUseCase) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := u.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	raw, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+	hash := hashToken(raw)
+	now := time.Now().UTC()
+	if err := u.otpRepo.CreateOneTimeToken(ctx, domain.OneTimeToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Purpose:   domain.PurposePasswordReset,
+		TokenHash: &hash,
+		ExpiresAt: now.Add(passwordResetTTL),
+		CreatedAt: now,
+	}); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/password/reset?token=%s", u.baseURL, url.QueryEscape(raw))
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nsomeone requested a password reset for your account. "+
+			"If it was you, open the link below within an hour:\n%s\n\n"+
+			"If you did not request it, just ignore this email.", user.Username, link),
+	})
+}
+
+// ResetPassword проверяет новый пароль по политике, гасит токен сброса, меняет пароль и завершает все существующие сессии пользователя
+func (u *userUseCase) ResetPassword(ctx context.Context, payload domain.ResetPasswordPayload) error {
+	if err := utils.Validate.Struct(payload); err != nil {
+		return err
+	}
+
+	// Политику проверяем до того, как погасить токен: со слабым паролем ссылка должна остаться рабочей
+	hash := hashToken(payload.Token)
+	userID, err := u.otpRepo.FindOneTimeTokenByHash(ctx, hash, domain.PurposePasswordReset)
+	if err != nil {
+		return err
+	}
+	user, err := u.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := u.policy.Check(payload.Password, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	if _, err := u.otpRepo.ConsumeOneTimeTokenByHash(ctx, hash, domain.PurposePasswordReset); err != nil {
+		return err
+	}
+
+	hashed, err := u.passwords.Hash(payload.Password)
+	if err != nil {
+		return err
+	}
+	if err := u.repo.UpdatePassword(ctx, userID, hashed); err != nil {
+		return err
+	}
+
+	// Остальные выпущенные ссылки сброса больше не нужны
+	if err := u.otpRepo.InvalidateUserTokens(ctx, userID, domain.PurposePasswordReset); err != nil {
+		return err
+	}
+	return u.LogoutAll(ctx, userID)
+}
@@ -0,0 +1,224 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/mailer"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/utils"
+)
+
+var ErrCurrentPasswordRequired = errors.New("current password is required to change email")
+
+// UpdateProfile меняет имя и/или почту. Смена почты требует текущего пароля и сама по себе
+// профиль не меняет: на новый адрес уходит ссылка подтверждения, на прежний — предупреждение,
+// а почта меняется только в ConfirmEmailChange. До этого вход и письма идут на прежний адрес
+func (u *userUseCase) UpdateProfile(ctx context.Context, userID uuid.UUID, payload domain.UpdateProfilePayload) (*domain.User, error) {
+	if payload.Username != nil {
+		*payload.Username = strings.TrimSpace(*payload.Username)
+	}
+	if payload.Email != nil {
+		*payload.Email = strings.TrimSpace(*payload.Email)
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		return nil, err
+	}
+
+	user, err := u.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	newEmail := ""
+	if payload.Email != nil && *payload.Email != user.Email {
+		if payload.CurrentPassword == "" {
+			return nil, ErrCurrentPasswordRequired
+		}
+		if err := u.confirmPassword(ctx, user, payload.CurrentPassword); err != nil {
+			return nil, err
+		}
+		newEmail = *payload.Email
+	}
+
+	if payload.Username != nil && *payload.Username != user.Username {
+		user.Username = *payload.Username
+		if err := u.repo.UpdateUser(ctx, *user); err != nil {
+			return nil, err
+		}
+	}
+	if newEmail != "" {
+		if err := u.requestEmailChange(ctx, user, newEmail); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// requestEmailChange выпускает токен смены почты на newEmail, отправляет ссылку на новый адрес
+// и предупреждает прежний: если смену запросил не владелец, он узнает об этом до подтверждения
+func (u *userUseCase) requestEmailChange(ctx context.Context, user *domain.User, newEmail string) error {
+	existing, err := u.repo.GetUserByEmail(ctx, newEmail)
+	if err == nil && existing.ID != user.ID {
+		return repo.ErrUserExists
+	}
+	if err != nil && !errors.Is(err, repo.ErrUserNotFound) {
+		return err
+	}
+
+	// Действует только последняя запрошенная смена
+	if err := u.otpRepo.InvalidateUserTokens(ctx, user.ID, domain.PurposeEmailChange); err != nil {
+		return err
+	}
+	pending := *user
+	pending.Email = newEmail
+	token, err := u.issuePurposeToken(ctx, &pending, domain.PurposeEmailChange, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/confirm-email-change?token=%s", u.baseURL, url.QueryEscape(token))
+	messages := []mailer.Message{
+		{
+			To:      newEmail,
+			Subject: "Confirm your new email",
+			Body: fmt.Sprintf("Hi %s,\n\nplease confirm your new email by opening the link below:\n%s\n\n"+
+				"The link is valid for 24 hours.", user.Username, link),
+		},
+		{
+			To:      user.Email,
+			Subject: "Email change requested",
+			Body: fmt.Sprintf("Hi %s,\n\nsomeone asked to change the email of your account to %s. "+
+				"The change takes effect only after it is confirmed from the new address.\n\n"+
+				"If it wasn't you, change your password and log out of all sessions.", user.Username, newEmail),
+		},
+	}
+	go func() {
+		for _, msg := range messages {
+			if err := u.mailer.Send(context.WithoutCancel(ctx), msg); err != nil {
+				slog.Error("update profile: send email change message failed", "user_id", user.ID, "error", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// ConfirmEmailChange гасит токен смены почты и переносит на аккаунт адрес из него. Переход по ссылке
+// подтверждает адрес, поэтому почта сразу считается подтверждённой. Выданные access-токены отзываются,
+// чтобы признак email_verified в них не устарел
+func (u *userUseCase) ConfirmEmailChange(ctx context.Context, token string) error {
+	claims, err := u.parsePurposeToken(token, domain.PurposeEmailChange)
+	if err != nil {
+		return err
+	}
+	jti, err := claims.TokenID()
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	userID, err := u.otpRepo.ConsumeOneTimeToken(ctx, jti, domain.PurposeEmailChange)
+	if err != nil {
+		return err
+	}
+	user, err := u.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	now := time.Now().UTC()
+	user.Email = claims.Email
+	user.EmailVerifiedAt = &now
+	if err := u.repo.UpdateUser(ctx, *user); err != nil {
+		return err
+	}
+
+	// Ссылки, отправленные на прежний адрес, больше не действуют
+	for _, purpose := range []string{domain.PurposeEmailVerification, domain.PurposePasswordReset, domain.PurposeMagicLink} {
+		if err := u.otpRepo.InvalidateUserTokens(ctx, userID, purpose); err != nil {
+			return err
+		}
+	}
+	_, err = u.revocations.BumpTokenGeneration(ctx, userID)
+	return err
+}
+
+// ChangePassword меняет пароль после проверки текущего. Все прочие сессии завершаются,
+// а текущему клиенту выдаётся новая пара токенов
+func (u *userUseCase) ChangePassword(ctx context.Context, userID uuid.UUID, payload domain.ChangePasswordPayload) (*domain.TokenPair, error) {
+	if err := utils.Validate.Struct(payload); err != nil {
+		return nil, err
+	}
+	user, err := u.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.confirmPassword(ctx, user, payload.CurrentPassword); err != nil {
+		return nil, err
+	}
+	if err := u.policy.Check(payload.NewPassword, user.Username, user.Email); err != nil {
+		return nil, err
+	}
+
+	hashed, err := u.passwords.Hash(payload.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.repo.UpdatePassword(ctx, userID, hashed); err != nil {
+		return nil, err
+	}
+	if err := u.otpRepo.InvalidateUserTokens(ctx, userID, domain.PurposePasswordReset); err != nil {
+		return nil, err
+	}
+	if err := u.LogoutAll(ctx, userID); err != nil {
+		return nil, err
+	}
+	return u.issueTokenPair(ctx, userID, uuid.New())
+}
+
+// DeleteAccount удаляет аккаунт после проверки пароля
+func (u *userUseCase) DeleteAccount(ctx context.Context, userID uuid.UUID, payload domain.DeleteAccountPayload) error {
+	if err := utils.Validate.Struct(payload); err != nil {
+		return err
+	}
+	user, err := u.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := u.confirmPassword(ctx, user, payload.Password); err != nil {
+		return err
+	}
+
+	// Сначала отзываем токены: кэш поколений иначе ещё какое-то время пропускал бы их
+	if err := u.LogoutAll(ctx, userID); err != nil {
+		return err
+	}
+	return u.repo.DeleteUser(ctx, userID)
+}
+
+// confirmPassword проверяет пароль уже вошедшего пользователя. Попытки учитываются
+// тем же счётчиком, что и логин, — украденная сессия не даёт перебирать пароль
+func (u *userUseCase) confirmPassword(ctx context.Context, user *domain.User, password string) error {
+	attempt, err := u.reserveLogin(ctx, newLoginKeys(ctx, user.Email))
+	if err != nil {
+		return err
+	}
+	ok, _, err := u.passwords.Verify(user.Password, password)
+	if err != nil {
+		return errors.Join(err, u.abortLogin(ctx, attempt))
+	}
+	if !ok {
+		return ErrInvalidCredentials
+	}
+	return u.succeedLogin(ctx, attempt)
+}
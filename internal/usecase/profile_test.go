@@ -0,0 +1,171 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/mailer"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/repo/repotest"
+)
+
+var linkToken = regexp.MustCompile(`token=(\S+)`)
+
+// waitMail ждёт письма на адрес: usecase отправляет их в фоне
+func waitMail(t *testing.T, m *repotest.Mailer, addr string) mailer.Message {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if sent := m.To(addr); len(sent) > 0 {
+			return sent[len(sent)-1]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("no mail sent to %s", addr)
+	return mailer.Message{}
+}
+
+// tokenFromMail достаёт токен из ссылки в письме
+func tokenFromMail(t *testing.T, msg mailer.Message) string {
+	t.Helper()
+	match := linkToken.FindStringSubmatch(msg.Body)
+	if match == nil {
+		t.Fatalf("no link in mail %q", msg.Body)
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func ptr(s string) *string { return &s }
+
+func TestUpdateProfileEmailRequiresPassword(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.addUser(t, "alice@example.com", "correct horse battery")
+
+	_, err := env.uc.UpdateProfile(ctx, user.ID, domain.UpdateProfilePayload{Email: ptr("mallory@example.com")})
+	if !errors.Is(err, ErrCurrentPasswordRequired) {
+		t.Fatalf("without password err = %v, want ErrCurrentPasswordRequired", err)
+	}
+	_, err = env.uc.UpdateProfile(ctx, user.ID, domain.UpdateProfilePayload{
+		Email:           ptr("mallory@example.com"),
+		CurrentPassword: "wrong password",
+	})
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("wrong password err = %v, want ErrInvalidCredentials", err)
+	}
+
+	// Имя меняется без пароля
+	updated, err := env.uc.UpdateProfile(ctx, user.ID, domain.UpdateProfilePayload{Username: ptr("alice2")})
+	if err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if updated.Username != "alice2" || updated.Email != user.Email {
+		t.Fatalf("updated = %+v", updated)
+	}
+	if sent := env.mail.To("mallory@example.com"); len(sent) != 0 {
+		t.Fatalf("mail sent to unconfirmed address: %+v", sent)
+	}
+}
+
+func TestEmailChangeKeepsOldEmailUntilConfirmed(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.addUser(t, "alice@example.com", "correct horse battery")
+	pair, err := env.uc.issueTokenPair(ctx, user.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := env.uc.UpdateProfile(ctx, user.ID, domain.UpdateProfilePayload{
+		Email:           ptr("alice@new.example.com"),
+		CurrentPassword: "correct horse battery",
+	})
+	if err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+	if updated.Email != "alice@example.com" || updated.EmailVerifiedAt == nil {
+		t.Fatalf("email changed before confirmation: %+v", updated)
+	}
+
+	notice := waitMail(t, env.mail, "alice@example.com")
+	if !regexp.MustCompile(`alice@new\.example\.com`).MatchString(notice.Body) {
+		t.Fatalf("notice to old address does not name the new one: %q", notice.Body)
+	}
+	token := tokenFromMail(t, waitMail(t, env.mail, "alice@new.example.com"))
+	if linkToken.MatchString(notice.Body) {
+		t.Fatalf("notice to old address carries the confirmation link")
+	}
+
+	// Токен смены почты не годится для подтверждения почты и наоборот
+	if err := env.uc.VerifyEmail(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("VerifyEmail with email change token err = %v", err)
+	}
+
+	if err := env.uc.ConfirmEmailChange(ctx, token); err != nil {
+		t.Fatalf("ConfirmEmailChange: %v", err)
+	}
+	stored, _ := env.users.GetUserByID(ctx, user.ID)
+	if stored.Email != "alice@new.example.com" || stored.EmailVerifiedAt == nil {
+		t.Fatalf("user after confirmation = %+v", stored)
+	}
+	if err := env.uc.ConfirmEmailChange(ctx, token); !errors.Is(err, repo.ErrOneTimeTokenInvalid) {
+		t.Fatalf("second confirmation err = %v, want ErrOneTimeTokenInvalid", err)
+	}
+	if _, err := env.uc.ValidateToken(ctx, pair.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("access token after email change err = %v, want ErrTokenRevoked", err)
+	}
+}
+
+func TestEmailChangeOnlyLatestRequestCounts(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.addUser(t, "alice@example.com", "correct horse battery")
+
+	request := func(email string) string {
+		t.Helper()
+		_, err := env.uc.UpdateProfile(ctx, user.ID, domain.UpdateProfilePayload{
+			Email:           ptr(email),
+			CurrentPassword: "correct horse battery",
+		})
+		if err != nil {
+			t.Fatalf("UpdateProfile: %v", err)
+		}
+		return tokenFromMail(t, waitMail(t, env.mail, email))
+	}
+	first := request("first@example.com")
+	second := request("second@example.com")
+
+	if err := env.uc.ConfirmEmailChange(ctx, first); !errors.Is(err, repo.ErrOneTimeTokenInvalid) {
+		t.Fatalf("superseded token err = %v, want ErrOneTimeTokenInvalid", err)
+	}
+	if err := env.uc.ConfirmEmailChange(ctx, second); err != nil {
+		t.Fatalf("ConfirmEmailChange: %v", err)
+	}
+	if stored, _ := env.users.GetUserByID(ctx, user.ID); stored.Email != "second@example.com" {
+		t.Fatalf("email = %q, want second@example.com", stored.Email)
+	}
+}
+
+func TestEmailChangeToTakenAddress(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.addUser(t, "alice@example.com", "correct horse battery")
+	env.addUser(t, "bob@example.com", "correct horse battery")
+
+	_, err := env.uc.UpdateProfile(ctx, user.ID, domain.UpdateProfilePayload{
+		Email:           ptr("bob@example.com"),
+		CurrentPassword: "correct horse battery",
+	})
+	if !errors.Is(err, repo.ErrUserExists) {
+		t.Fatalf("err = %v, want ErrUserExists", err)
+	}
+}
@@ -0,0 +1,105 @@
+package usecase
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/utils"
+)
+
+// rolesCacheTTL — как долго держать в памяти справочник ролей и прав
+const rolesCacheTTL = time.Minute
+
+// RoleUseCase описывает управление ролями пользователей и вычисление их прав
+type RoleUseCase interface {
+	ListRoles(ctx context.Context) ([]domain.Role, error)
+	GetUserRoles(ctx context.Context, userID uuid.UUID) ([]string, error)
+	SetUserRoles(ctx context.Context, userID uuid.UUID, payload domain.SetUserRolesPayload) error
+	Permissions(ctx context.Context, roles []string) ([]string, error)
+}
+
+type roleUseCase struct {
+	repo        repo.RoleRepository
+	revocations repo.RevocationRepository
+
+	mu       sync.RWMutex
+	perms    map[string][]string
+	loadedAt time.Time
+}
+
+// NewRoleUsecase конструктор. revocations нужен, чтобы смена ролей отзывала уже выданные токены
+func NewRoleUsecase(r repo.RoleRepository, revocations repo.RevocationRepository) RoleUseCase {
+	return &roleUseCase{repo: r, revocations: revocations}
+}
+
+func (u *roleUseCase) ListRoles(ctx context.Context) ([]domain.Role, error) {
+	return u.repo.ListRoles(ctx)
+}
+
+func (u *roleUseCase) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return u.repo.GetUserRoles(ctx, userID)
+}
+
+// SetUserRoles заменяет роли пользователя и увеличивает поколение его токенов: access-токены
+// со старыми ролями перестают приниматься, и клиент получает новые роли через refresh
+func (u *roleUseCase) SetUserRoles(ctx context.Context, userID uuid.UUID, payload domain.SetUserRolesPayload) error {
+	if err := utils.Validate.Struct(payload); err != nil {
+		return err
+	}
+	roles := slices.Clone(payload.Roles)
+	slices.Sort(roles)
+	if err := u.repo.SetUserRoles(ctx, userID, slices.Compact(roles)); err != nil {
+		return err
+	}
+	_, err := u.revocations.BumpTokenGeneration(ctx, userID)
+	return err
+}
+
+// Permissions возвращает объединение прав перечисленных ролей. Неизвестные роли игнорируются
+func (u *roleUseCase) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	perms, err := u.rolePermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, role := range roles {
+		for _, p := range perms[role] {
+			if !slices.Contains(result, p) {
+				result = append(result, p)
+			}
+		}
+	}
+	return result, nil
+}
+
+// rolePermissions возвращает закешированный справочник "роль -> права", перечитывая его раз в rolesCacheTTL
+func (u *roleUseCase) rolePermissions(ctx context.Context) (map[string][]string, error) {
+	u.mu.RLock()
+	if u.perms != nil && time.Since(u.loadedAt) < rolesCacheTTL {
+		perms := u.perms
+		u.mu.RUnlock()
+		return perms, nil
+	}
+	u.mu.RUnlock()
+
+	roles, err := u.repo.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	perms := make(map[string][]string, len(roles))
+	for _, role := range roles {
+		perms[role.Name] = role.Permissions
+	}
+
+	u.mu.Lock()
+	u.perms = perms
+	u.loadedAt = time.Now()
+	u.mu.Unlock()
+	return perms, nil
+}
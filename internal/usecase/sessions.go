@@ -0,0 +1,30 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/domain"
+)
+
+// ListSessions возвращает действующие сессии пользователя и отмечает текущую
+func (u *userUseCase) ListSessions(ctx context.Context, userID, currentSessionID uuid.UUID) ([]domain.Session, error) {
+	sessions, err := u.sessions.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession завершает сессию пользователя на конкретном устройстве: её access-токены
+// перестают проходить проверку, refresh-токены отзываются
+func (u *userUseCase) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if err := u.sessions.RevokeSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+	return u.refreshRepo.RevokeFamily(ctx, sessionID)
+}
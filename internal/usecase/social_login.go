@@ -0,0 +1,372 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/mailer"
+	"jwt_auth_project/internal/oidc"
+	"jwt_auth_project/internal/repo"
+)
+
+const (
+	// socialStateTTL — сколько пользователь может провести на странице провайдера
+	socialStateTTL = 10 * time.Minute
+	// socialLinkTTL — сколько живёт ссылка подтверждения привязки аккаунта провайдера
+	socialLinkTTL = time.Hour
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown social login provider")
+	// ErrSocialEmailRequired — провайдер не сообщил email, а без него аккаунт не завести
+	ErrSocialEmailRequired = errors.New("provider did not share an email address")
+	// ErrSocialEmailConflict — email провайдера занят существующим аккаунтом, но провайдер
+	// его не подтвердил, поэтому доверять ему для привязки нельзя
+	ErrSocialEmailConflict = errors.New("an account with this email already exists; sign in and link the provider from your profile")
+)
+
+// BeginSocialLogin начинает вход через провайдера. Если linkUserID задан, по возвращении
+// аккаунт провайдера привязывается к этому пользователю вместо входа
+func (u *userUseCase) BeginSocialLogin(ctx context.Context, providerName string, linkUserID *uuid.UUID) (*domain.SocialLoginStart, error) {
+	provider, ok := u.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	state, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if err := u.identityRepo.CreateSocialLoginState(ctx, domain.SocialLoginState{
+		StateHash:    hashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		UserID:       linkUserID,
+		ExpiresAt:    now.Add(socialStateTTL),
+		CreatedAt:    now,
+	}); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	redirectTo, err := provider.AuthCodeURL(ctx, state, nonce, base64.RawURLEncoding.EncodeToString(sum[:]))
+	if err != nil {
+		return nil, err
+	}
+	return &domain.SocialLoginStart{RedirectTo: redirectTo, State: state}, nil
+}
+
+// SocialCallback завершает вход через провайдера: обменивает код, проверяет ID-токен и находит
+// или заводит пользователя. Аккаунт провайдера, чей подтверждённый email совпал с существующим
+// пользователем, привязывается только после подтверждения по ссылке из письма: иначе
+// провайдер с небрежной проверкой почты открыл бы доступ к чужому аккаунту
+func (u *userUseCase) SocialCallback(ctx context.Context, providerName, code, state string) (*domain.SocialLoginResult, error) {
+	provider, ok := u.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	stored, err := u.identityRepo.ConsumeSocialLoginState(ctx, hashToken(state), providerName)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := provider.Exchange(ctx, code, stored.CodeVerifier, stored.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if stored.UserID != nil {
+		return u.linkIdentity(ctx, *stored.UserID, providerName, identity)
+	}
+
+	linked, err := u.identityRepo.GetLinkedIdentity(ctx, providerName, identity.Subject)
+	if err == nil {
+		user, err := u.repo.GetUserByID(ctx, linked.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if err := u.identityRepo.TouchIdentity(ctx, linked.ID); err != nil {
+			slog.Error("social login: touch identity failed", "identity_id", linked.ID, "error", err)
+		}
+		return u.completeSocialLogin(ctx, user)
+	}
+	if !errors.Is(err, repo.ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	if identity.Email == "" {
+		return nil, ErrSocialEmailRequired
+	}
+	user, err := u.repo.GetUserByEmail(ctx, identity.Email)
+	switch {
+	case err == nil:
+		if !identity.EmailVerified {
+			return nil, ErrSocialEmailConflict
+		}
+		if err := u.requestIdentityLink(ctx, user, providerName, identity); err != nil {
+			return nil, err
+		}
+		return &domain.SocialLoginResult{LinkConfirmationRequired: true}, nil
+	case errors.Is(err, repo.ErrUserNotFound):
+		user, err = u.registerSocialUser(ctx, providerName, identity)
+		if err != nil {
+			return nil, err
+		}
+		return u.completeSocialLogin(ctx, user)
+	default:
+		return nil, err
+	}
+}
+
+// ConfirmSocialLink гасит токен из письма, подтверждает привязку и сразу выполняет вход
+func (u *userUseCase) ConfirmSocialLink(ctx context.Context, token string) (*domain.LoginResult, error) {
+	claims, err := u.parsePurposeToken(token, domain.PurposeSocialLink)
+	if err != nil {
+		return nil, err
+	}
+	jti, err := claims.TokenID()
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	userID, err := u.otpRepo.ConsumeOneTimeToken(ctx, jti, domain.PurposeSocialLink)
+	if err != nil {
+		return nil, err
+	}
+	user, err := u.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if !strings.EqualFold(user.Email, claims.Email) {
+		return nil, ErrInvalidToken
+	}
+
+	// jti токена совпадает с ID ожидающей привязки
+	identity, err := u.identityRepo.ConfirmIdentity(ctx, jti)
+	if err != nil {
+		if errors.Is(err, repo.ErrIdentityNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if identity.UserID != user.ID {
+		return nil, ErrInvalidToken
+	}
+	// Переход по ссылке доказывает владение почтой
+	if user.EmailVerifiedAt == nil {
+		if err := u.repo.MarkEmailVerified(ctx, user.ID, claims.Email); err != nil {
+			return nil, err
+		}
+	}
+	if err := u.identityRepo.TouchIdentity(ctx, identity.ID); err != nil {
+		slog.Error("social link: touch identity failed", "identity_id", identity.ID, "error", err)
+	}
+
+	slog.Info("social identity linked", "user_id", user.ID, "provider", identity.Provider)
+	return u.completeLogin(ctx, user)
+}
+
+func (u *userUseCase) ListIdentities(ctx context.Context, userID uuid.UUID) ([]domain.UserIdentity, error) {
+	return u.identityRepo.ListIdentities(ctx, userID)
+}
+
+// UnlinkIdentity отвязывает аккаунт провайдера. Пароль у пользователя есть всегда
+// (у заведённых через провайдера — случайный, его можно сбросить по почте), поэтому
+// отвязка последнего провайдера не лишает доступа к аккаунту
+func (u *userUseCase) UnlinkIdentity(ctx context.Context, userID, id uuid.UUID) error {
+	return u.identityRepo.DeleteIdentity(ctx, userID, id)
+}
+
+// linkIdentity привязывает аккаунт провайдера к уже вошедшему пользователю
+func (u *userUseCase) linkIdentity(ctx context.Context, userID uuid.UUID, providerName string, identity *oidc.Identity) (*domain.SocialLoginResult, error) {
+	linked, err := u.identityRepo.GetLinkedIdentity(ctx, providerName, identity.Subject)
+	if err == nil {
+		if linked.UserID != userID {
+			return nil, repo.ErrIdentityExists
+		}
+		return &domain.SocialLoginResult{Linked: true}, nil
+	}
+	if !errors.Is(err, repo.ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if err := u.identityRepo.CreateIdentity(ctx, domain.UserIdentity{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Provider:  providerName,
+		Subject:   identity.Subject,
+		Email:     identity.Email,
+		CreatedAt: now,
+		LinkedAt:  &now,
+	}); err != nil {
+		return nil, err
+	}
+
+	slog.Info("social identity linked", "user_id", userID, "provider", providerName)
+	return &domain.SocialLoginResult{Linked: true}, nil
+}
+
+// requestIdentityLink сохраняет ожидающую привязку и отправляет владельцу аккаунта ссылку
+// для её подтверждения
+func (u *userUseCase) requestIdentityLink(ctx context.Context, user *domain.User, providerName string, identity *oidc.Identity) error {
+	id := uuid.New()
+	if err := u.identityRepo.CreateIdentity(ctx, domain.UserIdentity{
+		ID:        id,
+		UserID:    user.ID,
+		Provider:  providerName,
+		Subject:   identity.Subject,
+		Email:     identity.Email,
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		return err
+	}
+
+	token, err := u.issuePurposeTokenWithID(ctx, user, domain.PurposeSocialLink, socialLinkTTL, id)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/login/social/link/confirm?token=%s", u.issuer, url.QueryEscape(token))
+	go func() {
+		if err := u.mailer.Send(context.WithoutCancel(ctx), mailer.Message{
+			To:      user.Email,
+			Subject: "Confirm sign-in with " + providerName,
+			Body: fmt.Sprintf("Hi %s,\n\nsomeone tried to sign in with a %s account that uses your email address.\n"+
+				"If it was you, open the link below within an hour to link it to your account:\n%s\n\n"+
+				"If it was not you, ignore this email: nothing will be linked.", user.Username, providerName, link),
+		}); err != nil {
+			slog.Error("social login: send link confirmation failed", "user_id", user.ID, "error", err)
+		}
+	}()
+	return nil
+}
+
+// registerSocialUser заводит пользователя по данным провайдера. Пароль случайный: войти по нему
+// нельзя, пока пользователь не задаст свой через сброс пароля
+func (u *userUseCase) registerSocialUser(ctx context.Context, providerName string, identity *oidc.Identity) (*domain.User, error) {
+	secret, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	hashed, err := u.passwords.Hash(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	base := socialUsername(identity)
+	user := domain.User{
+		ID:        uuid.New(),
+		Username:  base,
+		Email:     identity.Email,
+		Password:  hashed,
+		CreatedAt: time.Now(),
+	}
+	// Имя могут уже занять: пробуем несколько раз со случайным суффиксом
+	for attempt := 0; ; attempt++ {
+		err = u.repo.CreateUser(ctx, user)
+		if !errors.Is(err, repo.ErrUserExists) || attempt == 3 {
+			break
+		}
+		suffix := make([]byte, 3)
+		if _, err := rand.Read(suffix); err != nil {
+			return nil, err
+		}
+		user.Username = base + "_" + hex.EncodeToString(suffix)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := u.roleRepo.SetUserRoles(ctx, user.ID, []string{domain.RoleUser}); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if err := u.identityRepo.CreateIdentity(ctx, domain.UserIdentity{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Provider:  providerName,
+		Subject:   identity.Subject,
+		Email:     identity.Email,
+		CreatedAt: now,
+		LinkedAt:  &now,
+	}); err != nil {
+		return nil, err
+	}
+
+	if identity.EmailVerified {
+		if err := u.repo.MarkEmailVerified(ctx, user.ID, user.Email); err != nil {
+			return nil, err
+		}
+		user.EmailVerifiedAt = &now
+	} else {
+		go func() {
+			if err := u.SendVerificationEmail(context.WithoutCancel(ctx), user.ID); err != nil {
+				slog.Error("social login: send verification email failed", "user_id", user.ID, "error", err)
+			}
+		}()
+	}
+
+	slog.Info("user registered via social login", "user_id", user.ID, "provider", providerName)
+	return &user, nil
+}
+
+func (u *userUseCase) completeSocialLogin(ctx context.Context, user *domain.User) (*domain.SocialLoginResult, error) {
+	result, err := u.completeLogin(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.SocialLoginResult{LoginResult: *result}, nil
+}
+
+// socialUsername подбирает имя пользователя из preferred_username, name или локальной части email,
+// оставляя только допустимые символы и укладываясь в ограничения длины
+func socialUsername(identity *oidc.Identity) string {
+	local, _, _ := strings.Cut(identity.Email, "@")
+	for _, candidate := range []string{identity.PreferredUsername, identity.Name, local} {
+		var b strings.Builder
+		for _, r := range candidate {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-', r == '.':
+				b.WriteRune(r)
+			case r == ' ':
+				b.WriteRune('_')
+			}
+		}
+		// Оставляем место под суффикс "_xxxxxx"
+		name := b.String()
+		if len(name) > 23 {
+			name = name[:23]
+		}
+		if len(name) >= 3 {
+			return name
+		}
+	}
+	return "user"
+}
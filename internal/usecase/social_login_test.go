@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/oidc/oidctest"
+	"jwt_auth_project/internal/repo"
+)
+
+// socialLogin проводит вход через провайдера от начала до callback
+func socialLogin(t *testing.T, env *testEnv, idp *oidctest.IdP, provider string) (*domain.SocialLoginResult, error) {
+	t.Helper()
+	start, err := env.uc.BeginSocialLogin(context.Background(), provider, nil)
+	if err != nil {
+		t.Fatalf("BeginSocialLogin: %v", err)
+	}
+	code, state := idp.Login(t, start.RedirectTo)
+	if state != start.State {
+		t.Fatalf("state = %q, want %q", state, start.State)
+	}
+	return env.uc.SocialCallback(context.Background(), provider, code, state)
+}
+
+func TestSocialLoginLinksByEmailOnlyAfterConfirmation(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.addUser(t, "alice@example.com", "correct horse battery")
+	idp := env.addProvider(t, "mock")
+
+	result, err := socialLogin(t, env, idp, "mock")
+	if err != nil {
+		t.Fatalf("SocialCallback: %v", err)
+	}
+	if !result.LinkConfirmationRequired || result.Tokens != nil {
+		t.Fatalf("result = %+v, want link confirmation without tokens", result)
+	}
+	if identities, _ := env.uc.ListIdentities(ctx, user.ID); len(identities) != 0 {
+		t.Fatalf("identity linked before confirmation: %+v", identities)
+	}
+
+	token := tokenFromMail(t, waitMail(t, env.mail, "alice@example.com"))
+	login, err := env.uc.ConfirmSocialLink(ctx, token)
+	if err != nil {
+		t.Fatalf("ConfirmSocialLink: %v", err)
+	}
+	claims, err := env.uc.ValidateToken(ctx, login.Tokens.AccessToken)
+	if err != nil || claims.Subject != user.ID.String() {
+		t.Fatalf("token after confirmation: sub = %v, err = %v", claims, err)
+	}
+	if _, err := env.uc.ConfirmSocialLink(ctx, token); !errors.Is(err, repo.ErrOneTimeTokenInvalid) {
+		t.Fatalf("reused link err = %v, want ErrOneTimeTokenInvalid", err)
+	}
+
+	identities, _ := env.uc.ListIdentities(ctx, user.ID)
+	if len(identities) != 1 || identities[0].Provider != "mock" {
+		t.Fatalf("identities = %+v", identities)
+	}
+	result, err = socialLogin(t, env, idp, "mock")
+	if err != nil || result.Tokens == nil {
+		t.Fatalf("login with linked identity = %+v, %v", result, err)
+	}
+}
+
+func TestSocialLinkOnlyLatestRequestCounts(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	env.addUser(t, "alice@example.com", "correct horse battery")
+	idp := env.addProvider(t, "mock")
+
+	request := func() string {
+		t.Helper()
+		before := len(env.mail.To("alice@example.com"))
+		result, err := socialLogin(t, env, idp, "mock")
+		if err != nil || !result.LinkConfirmationRequired {
+			t.Fatalf("SocialCallback = %+v, %v", result, err)
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for len(env.mail.To("alice@example.com")) == before && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		return tokenFromMail(t, waitMail(t, env.mail, "alice@example.com"))
+	}
+	first := request()
+	second := request()
+
+	if _, err := env.uc.ConfirmSocialLink(ctx, first); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("superseded link err = %v, want ErrInvalidToken", err)
+	}
+	if _, err := env.uc.ConfirmSocialLink(ctx, second); err != nil {
+		t.Fatalf("ConfirmSocialLink: %v", err)
+	}
+}
+
+func TestSocialLinkRejectedAfterEmailChange(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.addUser(t, "alice@example.com", "correct horse battery")
+	idp := env.addProvider(t, "mock")
+
+	if _, err := socialLogin(t, env, idp, "mock"); err != nil {
+		t.Fatalf("SocialCallback: %v", err)
+	}
+	token := tokenFromMail(t, waitMail(t, env.mail, "alice@example.com"))
+
+	user.Email = "alice@new.example.com"
+	if err := env.users.UpdateUser(ctx, *user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.uc.ConfirmSocialLink(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("err = %v, want ErrInvalidToken", err)
+	}
+	if identities, _ := env.uc.ListIdentities(ctx, user.ID); len(identities) != 0 {
+		t.Fatalf("identity linked: %+v", identities)
+	}
+}
+
+func TestSocialLoginUnverifiedEmailNotLinked(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(t, "alice@example.com", "correct horse battery")
+	idp := env.addProvider(t, "mock")
+	idp.SetClaims(func(c jwt.MapClaims) { c["email_verified"] = false })
+
+	if _, err := socialLogin(t, env, idp, "mock"); !errors.Is(err, ErrSocialEmailConflict) {
+		t.Fatalf("err = %v, want ErrSocialEmailConflict", err)
+	}
+	if sent := env.mail.To("alice@example.com"); len(sent) != 0 {
+		t.Fatalf("link mail sent for an unverified provider email: %+v", sent)
+	}
+}
@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jwt_auth_project/internal/throttle"
+	"jwt_auth_project/internal/utils"
+)
+
+var (
+	// loginAccountPolicy — защита конкретного аккаунта от подбора пароля
+	loginAccountPolicy = throttle.Policy{
+		FreeAttempts: 5,
+		BaseDelay:    time.Second,
+		MaxDelay:     15 * time.Minute,
+		Window:       time.Hour,
+	}
+	// loginIPPolicy — защита от перебора множества аккаунтов с одного адреса.
+	// Порог выше: за одним NAT может сидеть много честных пользователей
+	loginIPPolicy = throttle.Policy{
+		FreeAttempts: 20,
+		BaseDelay:    time.Second,
+		MaxDelay:     15 * time.Minute,
+		Window:       time.Hour,
+	}
+	// secondFactorPolicy — подбор шестизначного кода должен упираться в блокировку быстро
+	secondFactorPolicy = throttle.Policy{
+		FreeAttempts: 5,
+		BaseDelay:    30 * time.Second,
+		MaxDelay:     time.Hour,
+		Window:       time.Hour,
+	}
+	// magicLinkPolicy — сколько писем со ссылкой для входа можно запросить на один адрес,
+	// чтобы эндпоинт нельзя было использовать для рассылки спама
+	magicLinkPolicy = throttle.Policy{
+		FreeAttempts: 3,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Hour,
+		Window:       time.Hour,
+	}
+	// passwordResetPolicy — сколько писем сброса пароля можно запросить на один адрес
+	passwordResetPolicy = throttle.Policy{
+		FreeAttempts: 3,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Hour,
+		Window:       time.Hour,
+	}
+	// passwordResetIPPolicy — то же с одного адреса клиента, чтобы нельзя было перебирать
+	// множество почтовых адресов и занимать SMTP-релей
+	passwordResetIPPolicy = throttle.Policy{
+		FreeAttempts: 10,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Hour,
+		Window:       time.Hour,
+	}
+)
+
+// limiters — ограничители неудачных попыток поверх общего хранилища
+type limiters struct {
+	account      *throttle.Limiter
+	ip           *throttle.Limiter
+	secondFactor *throttle.Limiter
+	magicLink    *throttle.Limiter
+	reset        *throttle.Limiter
+	resetIP      *throttle.Limiter
+}
+
+func newLimiters(store throttle.Store) limiters {
+	return limiters{
+		account:      throttle.NewLimiter(store, loginAccountPolicy),
+		ip:           throttle.NewLimiter(store, loginIPPolicy),
+		secondFactor: throttle.NewLimiter(store, secondFactorPolicy),
+		magicLink:    throttle.NewLimiter(store, magicLinkPolicy),
+		reset:        throttle.NewLimiter(store, passwordResetPolicy),
+		resetIP:      throttle.NewLimiter(store, passwordResetIPPolicy),
+	}
+}
+
+// loginKeys — ключи счётчиков для попытки логина: по email и по адресу клиента
+type loginKeys struct {
+	account string
+	ip      string
+}
+
+func newLoginKeys(ctx context.Context, email string) loginKeys {
+	keys := loginKeys{account: "login:email:" + strings.ToLower(strings.TrimSpace(email))}
+	if ip := utils.ClientIPFromContext(ctx); ip != "" {
+		keys.ip = "login:ip:" + ip
+	}
+	return keys
+}
+
+// loginAttempt — попытка проверки пароля, учтённая по аккаунту и по адресу
+type loginAttempt struct {
+	keys    loginKeys
+	account throttle.Reservation
+	ip      throttle.Reservation
+}
+
+// reserveLogin учитывает попытку до проверки пароля. Возвращает *throttle.ThrottledError,
+// если заблокирован аккаунт или адрес. Неверный пароль дальше учитывать не нужно
+func (u *userUseCase) reserveLogin(ctx context.Context, keys loginKeys) (loginAttempt, error) {
+	attempt := loginAttempt{keys: keys}
+	var err error
+	if attempt.account, err = u.limiters.account.Reserve(ctx, keys.account); err != nil {
+		return attempt, err
+	}
+	if keys.ip == "" {
+		return attempt, nil
+	}
+	if attempt.ip, err = u.limiters.ip.Reserve(ctx, keys.ip); err != nil {
+		return attempt, errors.Join(err, u.limiters.account.Release(ctx, attempt.account))
+	}
+	return attempt, nil
+}
+
+// succeedLogin сбрасывает счётчик аккаунта, а попытку по адресу только отменяет: иначе атакующий
+// со своим аккаунтом мог бы обнулять счётчик адреса между попытками подбора чужих паролей
+func (u *userUseCase) succeedLogin(ctx context.Context, attempt loginAttempt) error {
+	if err := u.limiters.account.Reset(ctx, attempt.keys.account); err != nil {
+		return err
+	}
+	return u.limiters.ip.Release(ctx, attempt.ip)
+}
+
+// abortLogin отменяет попытку, пароль в которой так и не проверили из-за сбоя
+func (u *userUseCase) abortLogin(ctx context.Context, attempt loginAttempt) error {
+	return errors.Join(u.limiters.account.Release(ctx, attempt.account), u.limiters.ip.Release(ctx, attempt.ip))
+}
+
+// limitPasswordReset учитывает запрос письма сброса пароля по адресу почты и по адресу клиента.
+// Возвращает *throttle.ThrottledError, если один из лимитов уже исчерпан
+func (u *userUseCase) limitPasswordReset(ctx context.Context, email string) error {
+	emailKey := "reset:email:" + strings.ToLower(strings.TrimSpace(email))
+	reservation, err := u.limiters.reset.Reserve(ctx, emailKey)
+	if err != nil {
+		return err
+	}
+	ip := utils.ClientIPFromContext(ctx)
+	if ip == "" {
+		return nil
+	}
+	if _, err := u.limiters.resetIP.Reserve(ctx, "reset:ip:"+ip); err != nil {
+		return errors.Join(err, u.limiters.reset.Release(ctx, reservation))
+	}
+	return nil
+}
+
+func magicLinkKey(email string) string {
+	return "magic:email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func secondFactorKey(userID uuid.UUID) string {
+	return "mfa:user:" + userID.String()
+}
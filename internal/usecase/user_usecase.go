@@ -2,59 +2,190 @@ package usecase
 
 import (
 	"context"
-	"crypto/rand"
-	"crypto/subtle"
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/argon2"
+	"jwt_auth_project/internal/config"
 	"jwt_auth_project/internal/domain"
+	"jwt_auth_project/internal/mailer"
+	"jwt_auth_project/internal/oidc"
+	"jwt_auth_project/internal/password"
 	"jwt_auth_project/internal/repo"
+	"jwt_auth_project/internal/throttle"
 	"jwt_auth_project/internal/utils"
+	"jwt_auth_project/internal/webauthn"
+	"log/slog"
 	"strings"
 	"time"
 )
 
-// UserUseCase описывает операции регистрации, логина, валидации токена и получение юзера
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrTokenRevoked        = errors.New("token revoked")
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+)
+
+// Причины, по которым JWT не прошёл проверку. Все они — частные случаи ErrInvalidToken
+var (
+	ErrTokenMalformed     = fmt.Errorf("%w: malformed", ErrInvalidToken)
+	ErrTokenBadSignature  = fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	ErrTokenExpired       = fmt.Errorf("%w: expired", ErrInvalidToken)
+	ErrTokenNotYetValid   = fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	ErrTokenWrongIssuer   = fmt.Errorf("%w: wrong issuer", ErrInvalidToken)
+	ErrTokenWrongAudience = fmt.Errorf("%w: wrong audience", ErrInvalidToken)
+)
+
+// UserUseCase описывает операции регистрации, логина, обновления и валидации токена и получение юзера
 type UserUseCase interface {
-	Register(ctx context.Context, payload domain.RegisterUserPayload) (*domain.User, string, error)
-	Login(ctx context.Context, payload domain.LoginUserPayload) (string, error)
-	ValidateToken(tokenStr string) (uuid.UUID, error)
-	GetUserByID(ctx context.Context, id int64) (*domain.User, error)
+	Register(ctx context.Context, payload domain.RegisterUserPayload) (*domain.User, *domain.TokenPair, error)
+	Login(ctx context.Context, payload domain.LoginUserPayload) (*domain.LoginResult, error)
+	LoginMFA(ctx context.Context, payload domain.LoginMFAPayload) (*domain.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*domain.TokenPair, error)
+	Logout(ctx context.Context, accessToken, refreshToken string) error
+	LogoutAll(ctx context.Context, userID uuid.UUID) error
+	ValidateToken(ctx context.Context, tokenStr string) (*domain.Claims, error)
+	ClientPermissions(ctx context.Context, claims *domain.Claims) ([]string, error)
+	JWKS() domain.JWKSet
+	GetUserByID(ctx context.Context, id uuid.UUID) (*domain.User, error)
+	UpdateProfile(ctx context.Context, userID uuid.UUID, payload domain.UpdateProfilePayload) (*domain.User, error)
+	ChangePassword(ctx context.Context, userID uuid.UUID, payload domain.ChangePasswordPayload) (*domain.TokenPair, error)
+	DeleteAccount(ctx context.Context, userID uuid.UUID, payload domain.DeleteAccountPayload) error
+	SendVerificationEmail(ctx context.Context, userID uuid.UUID) error
+	VerifyEmail(ctx context.Context, token string) error
+	ConfirmEmailChange(ctx context.Context, token string) error
+	RequestMagicLink(ctx context.Context, payload domain.MagicLinkPayload) error
+	LoginMagicLink(ctx context.Context, token string) (*domain.LoginResult, error)
+	BeginSocialLogin(ctx context.Context, provider string, linkUserID *uuid.UUID) (*domain.SocialLoginStart, error)
+	SocialCallback(ctx context.Context, provider, code, state string) (*domain.SocialLoginResult, error)
+	ConfirmSocialLink(ctx context.Context, token string) (*domain.LoginResult, error)
+	ListIdentities(ctx context.Context, userID uuid.UUID) ([]domain.UserIdentity, error)
+	UnlinkIdentity(ctx context.Context, userID, id uuid.UUID) error
+	ForgotPassword(ctx context.Context, payload domain.ForgotPasswordPayload) error
+	ResetPassword(ctx context.Context, payload domain.ResetPasswordPayload) error
+	EnrollTOTP(ctx context.Context, userID uuid.UUID) (*domain.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID uuid.UUID, payload domain.TOTPCodePayload) ([]string, error)
+	DisableTOTP(ctx context.Context, userID uuid.UUID, payload domain.DisableMFAPayload) error
+	BeginPasskeyRegistration(ctx context.Context, userID uuid.UUID) (*domain.WebAuthnRegistrationOptions, error)
+	FinishPasskeyRegistration(ctx context.Context, userID uuid.UUID, payload domain.WebAuthnRegisterPayload) (*domain.WebAuthnCredential, error)
+	BeginPasskeyLogin(ctx context.Context) (*domain.WebAuthnLoginOptions, error)
+	FinishPasskeyLogin(ctx context.Context, payload domain.WebAuthnLoginPayload) (*domain.TokenPair, error)
+	ListPasskeys(ctx context.Context, userID uuid.UUID) ([]domain.WebAuthnCredential, error)
+	DeletePasskey(ctx context.Context, userID, id uuid.UUID) error
+	ListSessions(ctx context.Context, userID, currentSessionID uuid.UUID) ([]domain.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	Authorize(ctx context.Context, userID uuid.UUID, req domain.AuthorizeRequest) (*domain.OAuthRedirect, error)
+	GetConsentRequest(ctx context.Context, userID, id uuid.UUID) (*domain.OAuthConsentRequest, error)
+	DecideConsent(ctx context.Context, userID, id uuid.UUID, payload domain.OAuthConsentPayload) (*domain.OAuthRedirect, error)
+	Token(ctx context.Context, req domain.OAuthTokenRequest) (*domain.OAuthTokenResponse, error)
+	Introspect(ctx context.Context, req domain.OAuthTokenQuery) (*domain.OAuthIntrospection, error)
+	Revoke(ctx context.Context, req domain.OAuthTokenQuery) error
+	OpenIDConfiguration() domain.OpenIDConfiguration
+	UserInfo(ctx context.Context, accessToken string) (*domain.UserInfo, error)
+	RegisterOAuthClient(ctx context.Context, createdBy uuid.UUID, payload domain.RegisterOAuthClientPayload) (*domain.RegisteredOAuthClient, error)
+	ListOAuthClients(ctx context.Context) ([]domain.OAuthClient, error)
+	DeleteOAuthClient(ctx context.Context, id string) error
 }
 
 type userUseCase struct {
-	repo      repo.UserRepository
-	jwtSecret []byte
-	ttl       time.Duration
+	repo         repo.UserRepository
+	refreshRepo  repo.RefreshTokenRepository
+	sessions     repo.SessionRepository
+	revocations  repo.RevocationRepository
+	roleRepo     repo.RoleRepository
+	otpRepo      repo.OneTimeTokenRepository
+	mfaRepo      repo.MFARepository
+	webauthnRepo repo.WebAuthnRepository
+	oauthRepo    repo.OAuthRepository
+	identityRepo repo.IdentityRepository
+	providers    map[string]*oidc.Provider
+	rp           *webauthn.RelyingParty
+	limiters     limiters
+	passwords    *password.Hasher
+	policy       *password.Policy
+	mailer       mailer.Mailer
+	keys         config.KeyRing
+	ttl          time.Duration
+	refreshTTL   time.Duration
+	tokenIssuer  string
+	audience     string
+	leeway       time.Duration
+	baseURL      string
+	issuer       string
 }
 
-// NewUserUsecase конструктор. jwtSecret — из конфига, ttl — время жизни токена.
-func NewUserUsecase(r repo.UserRepository, jwtSecret string, ttl time.Duration) UserUseCase {
+// NewUserUsecase конструктор. jwtCfg — ключи, время жизни, iss и aud токенов из конфига,
+// baseURL — адрес фронтенда, на который ведут ссылки из писем, issuer — публичный адрес сервиса
+// для OpenID Connect.
+func NewUserUsecase(
+	r repo.UserRepository,
+	refreshRepo repo.RefreshTokenRepository,
+	sessions repo.SessionRepository,
+	revocations repo.RevocationRepository,
+	roleRepo repo.RoleRepository,
+	otpRepo repo.OneTimeTokenRepository,
+	mfaRepo repo.MFARepository,
+	webauthnRepo repo.WebAuthnRepository,
+	oauthRepo repo.OAuthRepository,
+	identityRepo repo.IdentityRepository,
+	providers map[string]*oidc.Provider,
+	rp *webauthn.RelyingParty,
+	attempts throttle.Store,
+	passwords *password.Hasher,
+	policy *password.Policy,
+	m mailer.Mailer,
+	jwtCfg config.JWTConfig,
+	baseURL string,
+	issuer string,
+) UserUseCase {
 	return &userUseCase{
-		repo:      r,
-		jwtSecret: []byte(jwtSecret),
-		ttl:       ttl,
+		repo:         r,
+		refreshRepo:  refreshRepo,
+		sessions:     sessions,
+		revocations:  revocations,
+		roleRepo:     roleRepo,
+		otpRepo:      otpRepo,
+		mfaRepo:      mfaRepo,
+		webauthnRepo: webauthnRepo,
+		oauthRepo:    oauthRepo,
+		identityRepo: identityRepo,
+		providers:    providers,
+		rp:           rp,
+		limiters:     newLimiters(attempts),
+		passwords:    passwords,
+		policy:       policy,
+		mailer:       m,
+		keys:         jwtCfg.Keys,
+		ttl:          jwtCfg.Lifetime,
+		refreshTTL:   jwtCfg.RefreshLifetime,
+		tokenIssuer:  jwtCfg.Issuer,
+		audience:     jwtCfg.Audience,
+		leeway:       jwtCfg.Leeway,
+		baseURL:      baseURL,
+		issuer:       issuer,
 	}
 }
 
-func (u *userUseCase) GetUserByID(ctx context.Context, id int64) (*domain.User, error) {
+func (u *userUseCase) GetUserByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
 	return u.repo.GetUserByID(ctx, id)
 }
 
-// Register валидация, хеширование, сохранение и генерация JWT
-func (u *userUseCase) Register(ctx context.Context, payload domain.RegisterUserPayload) (*domain.User, string, error) {
+// Register валидация, проверка политики паролей, хеширование, сохранение и выдача пары токенов
+func (u *userUseCase) Register(ctx context.Context, payload domain.RegisterUserPayload) (*domain.User, *domain.TokenPair, error) {
 	payload.Username = strings.TrimSpace(payload.Username)
 	payload.Email = strings.TrimSpace(payload.Email)
 	if err := utils.Validate.Struct(payload); err != nil {
-		return nil, "", err
+		return nil, nil, err
+	}
+	if err := u.policy.Check(payload.Password, payload.Username, payload.Email); err != nil {
+		return nil, nil, err
 	}
 	newID := uuid.New()
-	hashed, err := hashPassword(payload.Password)
+	hashed, err := u.passwords.Hash(payload.Password)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
 	user := domain.User{
@@ -66,99 +197,440 @@ func (u *userUseCase) Register(ctx context.Context, payload domain.RegisterUserP
 	}
 
 	if err := u.repo.CreateUser(ctx, user); err != nil {
-		return nil, "", err
+		return nil, nil, err
+	}
+	if err := u.roleRepo.SetUserRoles(ctx, user.ID, []string{domain.RoleUser}); err != nil {
+		return nil, nil, err
 	}
 
-	tokenStr, err := u.generateToken(user.ID)
+	// Письмо отправляется в фоне: сбой почты не должен ломать регистрацию
+	go func() {
+		if err := u.SendVerificationEmail(context.WithoutCancel(ctx), user.ID); err != nil {
+			slog.Error("register: send verification email failed", "user_id", user.ID, "error", err)
+		}
+	}()
+
+	pair, err := u.issueTokenPair(ctx, user.ID, uuid.New())
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	return &user, tokenStr, nil
+	return &user, pair, nil
 }
 
-// Login валидация, проверка пароля, генерация JWT
-func (u *userUseCase) Login(ctx context.Context, payload domain.LoginUserPayload) (string, error) {
+// Login валидация, проверка блокировки, проверка пароля, выдача пары токенов. При включённой 2FA вместо пары
+// возвращается mfa_pending токен, который обменивается на пару через LoginMFA
+func (u *userUseCase) Login(ctx context.Context, payload domain.LoginUserPayload) (*domain.LoginResult, error) {
 	if err := utils.Validate.Struct(payload); err != nil {
-		return "", err
+		return nil, err
+	}
+
+	// Попытка учитывается до Argon2: заблокированные попытки не должны нагружать CPU
+	attempt, err := u.reserveLogin(ctx, newLoginKeys(ctx, payload.Email))
+	if err != nil {
+		return nil, err
 	}
 
 	user, err := u.repo.GetUserByEmail(ctx, payload.Email)
 	if err != nil {
-		return "", err
+		if errors.Is(err, repo.ErrUserNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, errors.Join(err, u.abortLogin(ctx, attempt))
 	}
 
-	ok, err := verifyPassword(user.Password, payload.Password)
+	ok, needsRehash, err := u.passwords.Verify(user.Password, payload.Password)
 	if err != nil {
-		return "", err
+		return nil, errors.Join(err, u.abortLogin(ctx, attempt))
 	}
 	if !ok {
-		return "", errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
+	}
+	if err := u.succeedLogin(ctx, attempt); err != nil {
+		return nil, err
+	}
+	if needsRehash {
+		u.rehashPassword(ctx, user.ID, payload.Password)
+	}
+
+	return u.completeLogin(ctx, user)
+}
+
+// Refresh обменивает refresh-токен на новую пару токенов. Старый токен помечается использованным.
+// Повторное предъявление уже использованного токена означает его кражу — отзываем всё семейство.
+func (u *userUseCase) Refresh(ctx context.Context, refreshToken string) (*domain.TokenPair, error) {
+	stored, err := u.refreshRepo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repo.ErrRefreshTokenNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	// Токены OAuth-клиентов обновляются только через /oauth/token
+	if stored.ClientID != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	return u.rotateRefreshToken(ctx, stored, oauthGrant{})
+}
+
+// rotateRefreshToken проверяет refresh-токен и обменивает его на новую пару в том же семействе.
+// access задаёт клиента и scopes нового access-токена, новый refresh-токен наследует их от старого
+func (u *userUseCase) rotateRefreshToken(ctx context.Context, stored *domain.RefreshToken, access oauthGrant) (*domain.TokenPair, error) {
+	if stored.RevokedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if stored.UsedAt != nil {
+		return nil, u.revokeReusedFamily(ctx, stored)
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	accessToken, accessExp, err := u.generateToken(ctx, stored.UserID, stored.FamilyID, access)
+	if err != nil {
+		return nil, err
+	}
+	next, raw, err := u.newRefreshToken(stored.UserID, stored.FamilyID, refreshGrant(stored))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.refreshRepo.Rotate(ctx, stored.ID, next); err != nil {
+		if errors.Is(err, repo.ErrRefreshTokenUsed) {
+			// Токен успели использовать параллельно — это тоже повторное использование
+			return nil, u.revokeReusedFamily(ctx, stored)
+		}
+		return nil, err
 	}
 
-	return u.generateToken(user.ID)
+	return &domain.TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExp,
+		RefreshToken:     raw,
+		RefreshExpiresAt: next.ExpiresAt,
+	}, nil
+}
+
+// Logout отзывает access-токен по jti, его сессию и семейство, к которому относится refresh-токен.
+// Невалидные и неизвестные токены не считаются ошибкой — отзывать в них нечего
+func (u *userUseCase) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	if accessToken != "" {
+		if claims, err := u.parseToken(accessToken); err == nil && claims.Purpose == "" {
+			if err := u.revokeAccessToken(ctx, claims); err != nil {
+				return err
+			}
+			if sid, ok := claims.Session(); ok {
+				if err := u.revokeSession(ctx, sid); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+	stored, err := u.refreshRepo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repo.ErrRefreshTokenNotFound) {
+			return nil
+		}
+		return err
+	}
+	return u.revokeSession(ctx, stored.FamilyID)
+}
+
+// LogoutAll завершает все сессии пользователя: увеличивает поколение токенов,
+// из-за чего все ранее выпущенные access-токены перестают проходить проверку, и отзывает refresh-токены
+func (u *userUseCase) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	if _, err := u.revocations.BumpTokenGeneration(ctx, userID); err != nil {
+		return err
+	}
+	if err := u.refreshRepo.RevokeUserTokens(ctx, userID); err != nil {
+		return err
+	}
+	return u.sessions.RevokeUserSessions(ctx, userID)
 }
 
-// ValidateToken парсит и проверяет JWT, возвращает userID из claims.Subject
-func (u *userUseCase) ValidateToken(tokenStr string) (uuid.UUID, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &jwt.RegisteredClaims{}, func(t *jwt.Token) (interface{}, error) {
-		if t.Method != jwt.SigningMethodHS256 {
-			return nil, errors.New("unexpected signing method")
+// ValidateToken парсит и проверяет JWT, затем проверяет, что токен не отозван
+// ни по jti, ни сменой поколения токенов пользователя, ни отзывом его сессии
+func (u *userUseCase) ValidateToken(ctx context.Context, tokenStr string) (*domain.Claims, error) {
+	claims, err := u.parseToken(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	// Одноразовые токены (подтверждение почты и т.п.) подписаны тем же ключом, но доступа не дают
+	if claims.Purpose != "" {
+		return nil, ErrInvalidToken
+	}
+
+	jti, err := claims.TokenID()
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	revoked, err := u.revocations.IsTokenRevoked(ctx, jti)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	// У токена client_credentials нет пользователя, а значит, и поколения с сессией
+	if claims.ClientToken() {
+		return claims, nil
+	}
+
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	gen, err := u.revocations.GetTokenGeneration(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Generation < gen {
+		return nil, ErrTokenRevoked
+	}
+
+	if sid, ok := claims.Session(); ok {
+		revoked, err := u.sessions.IsSessionRevoked(ctx, sid)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
 		}
-		return u.jwtSecret, nil
-	})
+	}
+
+	return claims, nil
+}
+
+// parseToken проверяет подпись, iss, aud и сроки действия JWT (exp, nbf, iat с запасом leeway)
+// и возвращает claims. Ошибка — одна из ErrToken*, по ней видно, что именно не так с токеном
+func (u *userUseCase) parseToken(tokenStr string) (*domain.Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &domain.Claims{}, u.verificationKey,
+		jwt.WithIssuer(u.tokenIssuer),
+		jwt.WithAudience(u.audience),
+		jwt.WithLeeway(u.leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
 	if err != nil {
-		return uuid.Nil, err
+		return nil, tokenError(err)
 	}
 
-	claims, ok := token.Claims.(*jwt.RegisteredClaims)
+	claims, ok := token.Claims.(*domain.Claims)
 	if !ok || !token.Valid {
-		return uuid.Nil, errors.New("invalid token")
+		return nil, ErrInvalidToken
 	}
+	return claims, nil
+}
 
-	return uuid.Parse(claims.Subject)
+// tokenError переводит ошибку разбора JWT в одну из ErrToken*
+func tokenError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return ErrTokenNotYetValid
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return ErrTokenWrongIssuer
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return ErrTokenWrongAudience
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return ErrTokenBadSignature
+	default:
+		// Сюда же попадают токены без exp: такие мы не выпускаем
+		return ErrTokenMalformed
+	}
 }
 
-// generateToken соберет JWT с полем Subject=userID и сроком ttl
-func (u *userUseCase) generateToken(userID uuid.UUID) (string, error) {
-	now := time.Now()
-	claims := jwt.RegisteredClaims{
-		Subject:   userID.String(),
+// registeredClaims собирает стандартные claims токена, выпускаемого сервисом: iss и aud из конфига,
+// iat и nbf — now, exp — now+ttl
+func (u *userUseCase) registeredClaims(id, subject string, now time.Time, ttl time.Duration) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		ID:        id,
+		Issuer:    u.tokenIssuer,
+		Subject:   subject,
+		Audience:  jwt.ClaimStrings{u.audience},
 		IssuedAt:  jwt.NewNumericDate(now),
-		ExpiresAt: jwt.NewNumericDate(now.Add(u.ttl)),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+}
+
+// verificationKey выбирает ключ проверки подписи по kid из заголовка токена.
+// Токены без kid проверяются текущим ключом
+func (u *userUseCase) verificationKey(t *jwt.Token) (interface{}, error) {
+	key := u.keys.Current
+	if kid, ok := t.Header["kid"].(string); ok {
+		var found bool
+		key, found = u.keys.Lookup(kid)
+		if !found {
+			return nil, errors.New("unknown key id")
+		}
+	}
+	if t.Method.Alg() != key.Algorithm {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.VerifyKey(), nil
+}
+
+// revokeAccessToken заносит jti токена в список отозванных до момента его истечения
+func (u *userUseCase) revokeAccessToken(ctx context.Context, claims *domain.Claims) error {
+	jti, err := claims.TokenID()
+	if err != nil {
+		return nil
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil
+	}
+	expiresAt := time.Now().Add(u.ttl)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	return u.revocations.RevokeToken(ctx, jti, userID, expiresAt)
+}
+
+// generateToken соберет JWT с полями Subject=userID, jti, iss, aud, текущим поколением токенов, сессией,
+// именем и ролями пользователя, признаком подтверждённой почты и сроком ttl, отметит активность сессии
+// и вернёт токен и момент его истечения. Токен для OAuth-клиента дополнительно несёт
+// client_id и выданные scopes; aud у него тот же — токен предъявляется нашему API, а не клиенту
+func (u *userUseCase) generateToken(ctx context.Context, userID, sessionID uuid.UUID, grant oauthGrant) (string, time.Time, error) {
+	gen, err := u.revocations.GetTokenGeneration(ctx, userID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	roles, err := u.roleRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	user, err := u.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	exp := now.Add(u.ttl)
+	jti := uuid.New()
+	claims := domain.Claims{
+		RegisteredClaims: u.registeredClaims(jti.String(), userID.String(), now, u.ttl),
+		Generation:       gen,
+		SessionID:        sessionID.String(),
+		Username:         user.Username,
+		Roles:            roles,
+		EmailVerified:    user.EmailVerifiedAt != nil,
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(u.jwtSecret)
+	if grant.clientID != "" {
+		claims.ClientID = grant.clientID
+		claims.Scope = strings.Join(grant.scopes, " ")
+	}
+	signed, err := u.signClaims(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	if err := u.sessions.TouchSession(ctx, domain.Session{
+		ID:         sessionID,
+		UserID:     userID,
+		UserAgent:  utils.UserAgentFromContext(ctx),
+		IP:         utils.ClientIPFromContext(ctx),
+		LastJTI:    &jti,
+		LastSeenAt: now.UTC(),
+	}); err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, exp, nil
 }
 
-// hashPassword хеширует пароль Argon2id и возвращает строку в формате "salt$hash"
-func hashPassword(password string) (string, error) {
-	salt := make([]byte, 16)
-	if _, err := rand.Read(salt); err != nil {
-		return "", err
+// signClaims подписывает claims текущим ключом кольца и проставляет его kid в заголовок
+func (u *userUseCase) signClaims(claims jwt.Claims) (string, error) {
+	key := u.keys.Current
+	token := jwt.NewWithClaims(key.Method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.SignKey())
+}
+
+// revokeSession отзывает сессию вместе с семейством её refresh-токенов
+func (u *userUseCase) revokeSession(ctx context.Context, sessionID uuid.UUID) error {
+	if err := u.refreshRepo.RevokeFamily(ctx, sessionID); err != nil {
+		return err
 	}
-	hash := argon2.IDKey([]byte(password), salt, 1, 64*1024, 4, 32)
+	return u.sessions.RevokeSessionByID(ctx, sessionID)
+}
 
-	bSalt := base64.RawStdEncoding.EncodeToString(salt)
-	bHash := base64.RawStdEncoding.EncodeToString(hash)
-	return fmt.Sprintf("%s$%s", bSalt, bHash), nil
+// issueTokenPair выпускает access JWT и новый refresh-токен в семействе familyID.
+// Семейство и есть сессия: для нового семейства здесь же появляется запись о сессии
+func (u *userUseCase) issueTokenPair(ctx context.Context, userID, familyID uuid.UUID) (*domain.TokenPair, error) {
+	return u.issueGrantTokens(ctx, userID, familyID, oauthGrant{}, true)
 }
 
-// verifyPassword сравнивает raw-пароль с закешированным hash в формате "salt$hash"
-func verifyPassword(encoded, password string) (bool, error) {
-	parts := strings.Split(encoded, "$")
-	if len(parts) != 2 {
-		return false, errors.New("invalid hash format")
+// issueGrantTokens выпускает токены для grant. Без withRefresh выдаётся только access-токен
+func (u *userUseCase) issueGrantTokens(ctx context.Context, userID, familyID uuid.UUID, grant oauthGrant, withRefresh bool) (*domain.TokenPair, error) {
+	accessToken, accessExp, err := u.generateToken(ctx, userID, familyID, grant)
+	if err != nil {
+		return nil, err
+	}
+	if !withRefresh {
+		return &domain.TokenPair{AccessToken: accessToken, AccessExpiresAt: accessExp}, nil
+	}
+
+	rt, raw, err := u.newRefreshToken(userID, familyID, grant)
+	if err != nil {
+		return nil, err
 	}
-	salt, err := base64.RawStdEncoding.DecodeString(parts[0])
+	if err := u.refreshRepo.CreateRefreshToken(ctx, rt); err != nil {
+		return nil, err
+	}
+
+	return &domain.TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExp,
+		RefreshToken:     raw,
+		RefreshExpiresAt: rt.ExpiresAt,
+	}, nil
+}
+
+// newRefreshToken генерирует refresh-токен и запись для БД. Сырой токен возвращается отдельно
+func (u *userUseCase) newRefreshToken(userID, familyID uuid.UUID, grant oauthGrant) (domain.RefreshToken, string, error) {
+	raw, err := newOpaqueToken()
 	if err != nil {
-		return false, err
+		return domain.RefreshToken{}, "", err
+	}
+	now := time.Now().UTC()
+	rt := domain.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: now.Add(u.refreshTTL),
+		CreatedAt: now,
+	}
+	if grant.clientID != "" {
+		rt.ClientID = &grant.clientID
+		rt.Scopes = grant.scopes
+	}
+	return rt, raw, nil
+}
+
+// rehashPassword пересчитывает хеш пароля текущими параметрами. Сбой не мешает логину:
+// пароль уже проверен, а пересчёт повторится при следующем входе
+func (u *userUseCase) rehashPassword(ctx context.Context, userID uuid.UUID, password string) {
+	hashed, err := u.passwords.Hash(password)
+	if err == nil {
+		err = u.repo.UpdatePassword(ctx, userID, hashed)
 	}
-	hash, err := base64.RawStdEncoding.DecodeString(parts[1])
 	if err != nil {
-		return false, err
+		slog.Error("login: rehash password failed", "user_id", userID, "error", err)
 	}
+}
 
-	newHash := argon2.IDKey([]byte(password), salt, 1, 64*1024, 4, 32)
-	return subtle.ConstantTimeCompare(hash, newHash) == 1, nil
+// revokeReusedFamily отзывает семейство после обнаружения повторного использования токена
+func (u *userUseCase) revokeReusedFamily(ctx context.Context, stored *domain.RefreshToken) error {
+	if err := u.revokeSession(ctx, stored.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
 }
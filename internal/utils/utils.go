@@ -1,16 +1,26 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"net/http"
 	"strconv"
+	"strings"
+
+	"jwt_auth_project/internal/domain"
 )
 
 type contextKey string
 
-const ContextKeyUserID = contextKey("userID")
+const (
+	ContextKeyUserID    = contextKey("userID")
+	ContextKeyClaims    = contextKey("claims")
+	ContextKeyPrincipal = contextKey("principal")
+	ContextKeyClientIP  = contextKey("clientIP")
+	ContextKeyUserAgent = contextKey("userAgent")
+)
 
 var Validate = validator.New()
 
@@ -41,3 +51,30 @@ func ParseInt(s string, def int) int {
 	}
 	return def
 }
+
+// BearerToken извлекает токен из заголовка "Authorization: Bearer <token>"
+func BearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(h[7:])
+}
+
+// PrincipalFromContext возвращает пользователя, которого AuthMiddleware положил в контекст
+func PrincipalFromContext(ctx context.Context) (domain.Principal, bool) {
+	p, ok := ctx.Value(ContextKeyPrincipal).(domain.Principal)
+	return p, ok
+}
+
+// ClientIPFromContext возвращает адрес клиента, который положил middleware.ClientInfo
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(ContextKeyClientIP).(string)
+	return ip
+}
+
+// UserAgentFromContext возвращает User-Agent клиента, который положил middleware.ClientInfo
+func UserAgentFromContext(ctx context.Context) string {
+	ua, _ := ctx.Value(ContextKeyUserAgent).(string)
+	return ua
+}
@@ -0,0 +1,80 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Флаги authenticatorData (WebAuthn §6.1)
+const (
+	FlagUserPresent     byte = 0x01
+	FlagUserVerified    byte = 0x04
+	FlagBackupEligible  byte = 0x08
+	FlagBackupState     byte = 0x10
+	FlagAttestedData    byte = 0x40
+	FlagExtensionData   byte = 0x80
+	authDataMinLength        = 37
+	attestedDataMinimum      = 18
+)
+
+// AuthenticatorData — разобранные данные аутентификатора
+type AuthenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+	// Заполняются только при регистрации (флаг AT)
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+func (a *AuthenticatorData) Has(flag byte) bool {
+	return a.Flags&flag != 0
+}
+
+// ParseAuthenticatorData разбирает бинарный authenticatorData
+func ParseAuthenticatorData(raw []byte) (*AuthenticatorData, error) {
+	if len(raw) < authDataMinLength {
+		return nil, errors.New("webauthn: authenticator data too short")
+	}
+	ad := &AuthenticatorData{
+		RPIDHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	rest := raw[authDataMinLength:]
+
+	if ad.Has(FlagAttestedData) {
+		if len(rest) < attestedDataMinimum {
+			return nil, errors.New("webauthn: attested credential data too short")
+		}
+		ad.AAGUID = rest[:16]
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLen == 0 || idLen > 1023 || len(rest) < idLen {
+			return nil, errors.New("webauthn: invalid credential id length")
+		}
+		ad.CredentialID = rest[:idLen]
+		rest = rest[idLen:]
+
+		// Длина ключа заранее неизвестна — узнаём её, разобрав CBOR
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+		ad.PublicKey = rest[:len(rest)-len(after)]
+		rest = after
+	}
+
+	if ad.Has(FlagExtensionData) {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+		rest = after
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("webauthn: trailing data in authenticator data")
+	}
+	return ad, nil
+}
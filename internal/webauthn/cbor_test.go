@@ -0,0 +1,73 @@
+package webauthn
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	// {1: 2, "k": [-1, h'0102', true]}
+	data := []byte{0xa2, 0x01, 0x02, 0x61, 'k', 0x83, 0x20, 0x42, 0x01, 0x02, 0xf5, 0xff}
+	v, rest, err := decodeCBOR(data)
+	if err != nil {
+		t.Fatalf("decodeCBOR: %v", err)
+	}
+	if !bytes.Equal(rest, []byte{0xff}) {
+		t.Fatalf("rest = %x, want ff", rest)
+	}
+	m, ok := v.(map[any]any)
+	if !ok || m[int64(1)] != int64(2) {
+		t.Fatalf("decoded %#v", v)
+	}
+	arr, ok := m["k"].([]any)
+	if !ok || len(arr) != 3 || arr[0] != int64(-1) || !bytes.Equal(arr[1].([]byte), []byte{1, 2}) || arr[2] != true {
+		t.Fatalf("decoded array %#v", m["k"])
+	}
+}
+
+func TestDecodeCBORMalformed(t *testing.T) {
+	nested := func(prefix []byte) []byte {
+		return append(bytes.Repeat(prefix, 1000), 0x00)
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated uint16", []byte{0x19, 0x01}},
+		{"truncated uint64", []byte{0x1b, 0, 0, 0, 0}},
+		{"byte string longer than data", []byte{0x45, 1, 2}},
+		{"huge byte string length", []byte{0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"huge array length", []byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"huge map length", []byte{0xbb, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"array missing items", []byte{0x83, 0x01, 0x02}},
+		{"map missing value", []byte{0xa1, 0x01}},
+		{"indefinite length", []byte{0x9f, 0x01, 0xff}},
+		{"negative integer overflow", []byte{0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"unsupported map key", []byte{0xa1, 0x40, 0x00}},
+		{"duplicate map key", []byte{0xa2, 0x01, 0x00, 0x01, 0x00}},
+		{"float", []byte{0xf9, 0x3c, 0x00}},
+		{"deeply nested arrays", nested([]byte{0x81})},
+		{"deeply nested maps", nested([]byte{0xa1, 0x00})},
+		{"deeply nested tags", nested([]byte{0xc0})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if v, _, err := decodeCBOR(tt.data); err == nil {
+				t.Fatalf("decoded %#v, want error", v)
+			}
+		})
+	}
+}
+
+// FuzzDecodeCBOR проверяет, что разбор ответов аутентификатора не паникует на произвольном входе
+func FuzzDecodeCBOR(f *testing.F) {
+	f.Add([]byte{0xa2, 0x01, 0x02, 0x61, 'k', 0x83, 0x20, 0x42, 0x01, 0x02, 0xf5})
+	f.Add([]byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	f.Add(bytes.Repeat([]byte{0x81}, 64))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _, _ = decodeCBOR(data)
+		_, _ = ParseAuthenticatorData(data)
+		_, _ = ParsePublicKey(data)
+	})
+}
@@ -0,0 +1,117 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Алгоритмы COSE (RFC 9053), которые мы принимаем от аутентификаторов
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// SupportedAlgorithms — в порядке предпочтения, попадает в pubKeyCredParams
+var SupportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+const (
+	coseKeyType   = 1
+	coseAlgorithm = 3
+
+	coseKtyOKP = 1
+	coseKtyEC2 = 2
+	coseKtyRSA = 3
+
+	coseCrvP256    = 1
+	coseCrvEd25519 = 6
+)
+
+var ErrUnsupportedKey = errors.New("webauthn: unsupported credential public key")
+
+// PublicKey — открытый ключ учётных данных вместе с его COSE-алгоритмом
+type PublicKey struct {
+	Algorithm int64
+	Key       crypto.PublicKey
+}
+
+// ParsePublicKey разбирает COSE_Key в том виде, в каком он хранится в базе
+func ParsePublicKey(cose []byte) (*PublicKey, error) {
+	v, rest, err := decodeCBOR(cose)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("webauthn: trailing data after public key")
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+
+	kty, _ := m[int64(coseKeyType)].(int64)
+	alg, _ := m[int64(coseAlgorithm)].(int64)
+	crv, _ := m[int64(-1)].(int64)
+
+	switch {
+	case kty == coseKtyEC2 && alg == AlgES256 && crv == coseCrvP256:
+		x, _ := m[int64(-2)].([]byte)
+		y, _ := m[int64(-3)].([]byte)
+		if len(x) != 32 || len(y) != 32 {
+			return nil, ErrUnsupportedKey
+		}
+		// ecdh проверяет, что точка лежит на кривой
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, fmt.Errorf("webauthn: invalid P-256 point: %w", err)
+		}
+		return &PublicKey{Algorithm: alg, Key: &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}}, nil
+
+	case kty == coseKtyOKP && alg == AlgEdDSA && crv == coseCrvEd25519:
+		x, _ := m[int64(-2)].([]byte)
+		if len(x) != ed25519.PublicKeySize {
+			return nil, ErrUnsupportedKey
+		}
+		return &PublicKey{Algorithm: alg, Key: ed25519.PublicKey(x)}, nil
+
+	case kty == coseKtyRSA && alg == AlgRS256:
+		n, _ := m[int64(-1)].([]byte)
+		e, _ := m[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, ErrUnsupportedKey
+		}
+		exp := 0
+		for _, b := range e {
+			exp = exp<<8 | int(b)
+		}
+		return &PublicKey{Algorithm: alg, Key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exp}}, nil
+	}
+	return nil, ErrUnsupportedKey
+}
+
+// Verify проверяет подпись data ключом учётных данных
+func (k *PublicKey) Verify(data, sig []byte) bool {
+	switch key := k.Key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(key, digest[:], sig)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, data, sig)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+	}
+	return false
+}
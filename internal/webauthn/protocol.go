@@ -0,0 +1,112 @@
+package webauthn
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// URLEncoded — байты, которые в JSON WebAuthn передаются как base64url без паддинга
+type URLEncoded []byte
+
+func (b URLEncoded) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *URLEncoded) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// Параметры для navigator.credentials.create()
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          URLEncoded `json:"id"`
+	Name        string     `json:"name"`
+	DisplayName string     `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string     `json:"type"`
+	ID   URLEncoded `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+type CreationOptions struct {
+	Challenge              URLEncoded             `json:"challenge"`
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// Параметры для navigator.credentials.get()
+
+type RequestOptions struct {
+	Challenge        URLEncoded             `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// Ответы аутентификатора в JSON-сериализации PublicKeyCredential
+
+type AttestationResponse struct {
+	ClientDataJSON    URLEncoded `json:"clientDataJSON"    validate:"required"`
+	AttestationObject URLEncoded `json:"attestationObject" validate:"required"`
+}
+
+type RegistrationCredential struct {
+	ID       string              `json:"id"`
+	RawID    URLEncoded          `json:"rawId"    validate:"required"`
+	Type     string              `json:"type"     validate:"eq=public-key"`
+	Response AttestationResponse `json:"response"`
+}
+
+type AssertionResponse struct {
+	ClientDataJSON    URLEncoded `json:"clientDataJSON"    validate:"required"`
+	AuthenticatorData URLEncoded `json:"authenticatorData" validate:"required"`
+	Signature         URLEncoded `json:"signature"         validate:"required"`
+	UserHandle        URLEncoded `json:"userHandle"`
+}
+
+type AssertionCredential struct {
+	ID       string            `json:"id"`
+	RawID    URLEncoded        `json:"rawId"    validate:"required"`
+	Type     string            `json:"type"     validate:"eq=public-key"`
+	Response AssertionResponse `json:"response"`
+}
+
+// clientData — CollectedClientData (WebAuthn §5.8.1)
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
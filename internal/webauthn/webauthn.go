@@ -0,0 +1,208 @@
+// Package webauthn реализует серверную часть церемоний WebAuthn (регистрация и вход по passkey).
+// Аттестация не проверяется: мы запрашиваем attestation "none" и доверяем ключу,
+// а не модели аутентификатора. Верификация пользователя (PIN, биометрия) обязательна
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	// ChallengeTimeout — сколько действует выданный challenge
+	ChallengeTimeout = 5 * time.Minute
+
+	typeCreate = "webauthn.create"
+	typeGet    = "webauthn.get"
+
+	credentialType = "public-key"
+)
+
+var (
+	ErrVerification      = errors.New("webauthn: verification failed")
+	ErrSignCountRollback = errors.New("webauthn: signature counter went backwards, authenticator may be cloned")
+)
+
+// RelyingParty — наша сторона церемоний: идентификатор (домен), имя и разрешённые origin'ы
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+func NewRelyingParty(id, name string, origins []string) *RelyingParty {
+	return &RelyingParty{ID: id, Name: name, Origins: origins}
+}
+
+// Credential — результат успешной регистрации
+type Credential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+}
+
+// NewChallenge генерирует случайный challenge
+func NewChallenge() ([]byte, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// CreationOptions собирает параметры регистрации нового passkey. exclude — уже
+// зарегистрированные учётные данные пользователя, чтобы не завести второй ключ на том же устройстве
+func (rp *RelyingParty) CreationOptions(challenge, userHandle []byte, name, displayName string, exclude [][]byte) CreationOptions {
+	params := make([]CredentialParameter, 0, len(SupportedAlgorithms))
+	for _, alg := range SupportedAlgorithms {
+		params = append(params, CredentialParameter{Type: credentialType, Alg: alg})
+	}
+	return CreationOptions{
+		Challenge:          challenge,
+		RP:                 RelyingPartyEntity{ID: rp.ID, Name: rp.Name},
+		User:               UserEntity{ID: userHandle, Name: name, DisplayName: displayName},
+		PubKeyCredParams:   params,
+		Timeout:            ChallengeTimeout.Milliseconds(),
+		ExcludeCredentials: descriptors(exclude),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: "required",
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions собирает параметры входа. Пустой allow означает вход по discoverable-ключу
+// без указания пользователя
+func (rp *RelyingParty) RequestOptions(challenge []byte, allow [][]byte) RequestOptions {
+	return RequestOptions{
+		Challenge:        challenge,
+		Timeout:          ChallengeTimeout.Milliseconds(),
+		RPID:             rp.ID,
+		AllowCredentials: descriptors(allow),
+		UserVerification: "required",
+	}
+}
+
+// VerifyRegistration проверяет ответ navigator.credentials.create() (WebAuthn §7.1)
+func (rp *RelyingParty) VerifyRegistration(cred RegistrationCredential, challenge []byte) (*Credential, error) {
+	if err := rp.verifyClientData(cred.Response.ClientDataJSON, typeCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	v, rest, err := decodeCBOR(cred.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := v.(map[any]any)
+	if !ok || len(rest) != 0 {
+		return nil, fmt.Errorf("%w: malformed attestation object", ErrVerification)
+	}
+	format, _ := obj["fmt"].(string)
+	stmt, _ := obj["attStmt"].(map[any]any)
+	rawAuthData, _ := obj["authData"].([]byte)
+	if format != "none" || len(stmt) != 0 {
+		return nil, fmt.Errorf("%w: unsupported attestation format %q", ErrVerification, format)
+	}
+
+	authData, err := ParseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.verifyAuthData(authData); err != nil {
+		return nil, err
+	}
+	if !authData.Has(FlagAttestedData) {
+		return nil, fmt.Errorf("%w: no attested credential data", ErrVerification)
+	}
+	if !bytes.Equal(authData.CredentialID, cred.RawID) {
+		return nil, fmt.Errorf("%w: credential id mismatch", ErrVerification)
+	}
+	if _, err := ParsePublicKey(authData.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:        authData.CredentialID,
+		PublicKey: authData.PublicKey,
+		SignCount: authData.SignCount,
+	}, nil
+}
+
+// VerifyAssertion проверяет ответ navigator.credentials.get() ключом publicKey (WebAuthn §7.2)
+// и возвращает новое значение счётчика подписей
+func (rp *RelyingParty) VerifyAssertion(cred AssertionCredential, challenge, publicKey []byte, storedSignCount uint32) (uint32, error) {
+	if err := rp.verifyClientData(cred.Response.ClientDataJSON, typeGet, challenge); err != nil {
+		return 0, err
+	}
+
+	authData, err := ParseAuthenticatorData(cred.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	if err := rp.verifyAuthData(authData); err != nil {
+		return 0, err
+	}
+
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientHash := sha256.Sum256(cred.Response.ClientDataJSON)
+	signed := append(append([]byte(nil), cred.Response.AuthenticatorData...), clientHash[:]...)
+	if !key.Verify(signed, cred.Response.Signature) {
+		return 0, fmt.Errorf("%w: bad signature", ErrVerification)
+	}
+
+	// Синхронизируемые passkey обычно всегда присылают 0 — тогда счётчик не проверяем
+	if (authData.SignCount != 0 || storedSignCount != 0) && authData.SignCount <= storedSignCount {
+		return 0, ErrSignCountRollback
+	}
+	return authData.SignCount, nil
+}
+
+func (rp *RelyingParty) verifyClientData(raw []byte, wantType string, challenge []byte) error {
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return fmt.Errorf("%w: malformed client data", ErrVerification)
+	}
+	if cd.Type != wantType {
+		return fmt.Errorf("%w: unexpected client data type %q", ErrVerification, cd.Type)
+	}
+	if cd.Challenge != base64.RawURLEncoding.EncodeToString(challenge) {
+		return fmt.Errorf("%w: challenge mismatch", ErrVerification)
+	}
+	if !slices.Contains(rp.Origins, cd.Origin) {
+		return fmt.Errorf("%w: unexpected origin %q", ErrVerification, cd.Origin)
+	}
+	if cd.CrossOrigin {
+		return fmt.Errorf("%w: cross-origin ceremonies are not allowed", ErrVerification)
+	}
+	return nil
+}
+
+func (rp *RelyingParty) verifyAuthData(ad *AuthenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(ad.RPIDHash, rpIDHash[:]) {
+		return fmt.Errorf("%w: rp id mismatch", ErrVerification)
+	}
+	if !ad.Has(FlagUserPresent) || !ad.Has(FlagUserVerified) {
+		return fmt.Errorf("%w: user verification required", ErrVerification)
+	}
+	return nil
+}
+
+func descriptors(ids [][]byte) []CredentialDescriptor {
+	out := make([]CredentialDescriptor, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, CredentialDescriptor{Type: credentialType, ID: id})
+	}
+	return out
+}
@@ -0,0 +1,352 @@
+package webauthn_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"jwt_auth_project/internal/webauthn"
+	"jwt_auth_project/internal/webauthn/webauthntest"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+func newTestRP() *webauthn.RelyingParty {
+	return webauthn.NewRelyingParty(testRPID, "Example", []string{testOrigin})
+}
+
+func newAuthenticator(t *testing.T) *webauthntest.Authenticator {
+	t.Helper()
+	a, err := webauthntest.New(testRPID, testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func newChallenge(t *testing.T) []byte {
+	t.Helper()
+	c, err := webauthn.NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func get(t *testing.T, a *webauthntest.Authenticator, challenge []byte) webauthn.AssertionCredential {
+	t.Helper()
+	cred, err := a.Get(challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cred
+}
+
+func register(t *testing.T, rp *webauthn.RelyingParty, a *webauthntest.Authenticator) *webauthn.Credential {
+	t.Helper()
+	challenge := newChallenge(t)
+	cred, err := rp.VerifyRegistration(a.Create(challenge), challenge)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	return cred
+}
+
+// mutateClientData меняет поле clientDataJSON, не трогая остальные
+func mutateClientData(t *testing.T, raw []byte, field string, value any) []byte {
+	t.Helper()
+	var cd map[string]any
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		t.Fatal(err)
+	}
+	cd[field] = value
+	out, err := json.Marshal(cd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestRegistrationAndLogin(t *testing.T) {
+	rp := newTestRP()
+	a := newAuthenticator(t)
+
+	cred := register(t, rp, a)
+	if !bytes.Equal(cred.ID, a.CredentialID) {
+		t.Fatalf("credential id = %x, want %x", cred.ID, a.CredentialID)
+	}
+	if _, err := webauthn.ParsePublicKey(cred.PublicKey); err != nil {
+		t.Fatalf("stored public key does not parse: %v", err)
+	}
+
+	stored := cred.SignCount
+	for i := 0; i < 2; i++ {
+		challenge := newChallenge(t)
+		count, err := rp.VerifyAssertion(get(t, a, challenge), challenge, cred.PublicKey, stored)
+		if err != nil {
+			t.Fatalf("login %d: %v", i, err)
+		}
+		if count != a.SignCount {
+			t.Fatalf("login %d: sign count = %d, want %d", i, count, a.SignCount)
+		}
+		stored = count
+	}
+}
+
+func TestClientDataChecks(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		value any
+	}{
+		{"wrong origin", "origin", "https://evil.example.com"},
+		{"wrong challenge", "challenge", base64.RawURLEncoding.EncodeToString([]byte("other"))},
+		{"wrong type", "type", "payment.get"},
+		{"cross origin", "crossOrigin", true},
+	}
+	rp := newTestRP()
+
+	for _, tt := range tests {
+		t.Run("registration/"+tt.name, func(t *testing.T) {
+			a := newAuthenticator(t)
+			challenge := newChallenge(t)
+			cred := a.Create(challenge)
+			cred.Response.ClientDataJSON = mutateClientData(t, cred.Response.ClientDataJSON, tt.field, tt.value)
+			if _, err := rp.VerifyRegistration(cred, challenge); !errors.Is(err, webauthn.ErrVerification) {
+				t.Fatalf("err = %v, want ErrVerification", err)
+			}
+		})
+		t.Run("assertion/"+tt.name, func(t *testing.T) {
+			a := newAuthenticator(t)
+			stored := register(t, rp, a)
+			challenge := newChallenge(t)
+			cred := get(t, a, challenge)
+			cred.Response.ClientDataJSON = mutateClientData(t, cred.Response.ClientDataJSON, tt.field, tt.value)
+			if _, err := rp.VerifyAssertion(cred, challenge, stored.PublicKey, stored.SignCount); !errors.Is(err, webauthn.ErrVerification) {
+				t.Fatalf("err = %v, want ErrVerification", err)
+			}
+		})
+	}
+}
+
+func TestCeremonyTypesAreNotInterchangeable(t *testing.T) {
+	rp := newTestRP()
+	a := newAuthenticator(t)
+	stored := register(t, rp, a)
+
+	// Подписанный get-ответ с clientData от create не должен сойти за вход
+	challenge := newChallenge(t)
+	cred := get(t, a, challenge)
+	cred.Response.ClientDataJSON = a.ClientData("webauthn.create", challenge)
+	if _, err := rp.VerifyAssertion(cred, challenge, stored.PublicKey, stored.SignCount); !errors.Is(err, webauthn.ErrVerification) {
+		t.Fatalf("err = %v, want ErrVerification", err)
+	}
+}
+
+func TestRPIDHashMismatch(t *testing.T) {
+	rp := newTestRP()
+
+	t.Run("registration", func(t *testing.T) {
+		a := newAuthenticator(t)
+		a.RPID = "evil.example.com"
+		challenge := newChallenge(t)
+		if _, err := rp.VerifyRegistration(a.Create(challenge), challenge); !errors.Is(err, webauthn.ErrVerification) {
+			t.Fatalf("err = %v, want ErrVerification", err)
+		}
+	})
+	t.Run("assertion", func(t *testing.T) {
+		a := newAuthenticator(t)
+		stored := register(t, rp, a)
+		a.RPID = "evil.example.com"
+		challenge := newChallenge(t)
+		if _, err := rp.VerifyAssertion(get(t, a, challenge), challenge, stored.PublicKey, stored.SignCount); !errors.Is(err, webauthn.ErrVerification) {
+			t.Fatalf("err = %v, want ErrVerification", err)
+		}
+	})
+}
+
+func TestUserPresenceAndVerificationRequired(t *testing.T) {
+	rp := newTestRP()
+	tests := []struct {
+		name  string
+		flags byte
+	}{
+		{"no UP", webauthn.FlagUserVerified},
+		{"no UV", webauthn.FlagUserPresent},
+		{"neither", 0},
+	}
+	for _, tt := range tests {
+		t.Run("registration/"+tt.name, func(t *testing.T) {
+			a := newAuthenticator(t)
+			a.Flags = tt.flags
+			challenge := newChallenge(t)
+			if _, err := rp.VerifyRegistration(a.Create(challenge), challenge); !errors.Is(err, webauthn.ErrVerification) {
+				t.Fatalf("err = %v, want ErrVerification", err)
+			}
+		})
+		t.Run("assertion/"+tt.name, func(t *testing.T) {
+			a := newAuthenticator(t)
+			stored := register(t, rp, a)
+			a.Flags = tt.flags
+			challenge := newChallenge(t)
+			if _, err := rp.VerifyAssertion(get(t, a, challenge), challenge, stored.PublicKey, stored.SignCount); !errors.Is(err, webauthn.ErrVerification) {
+				t.Fatalf("err = %v, want ErrVerification", err)
+			}
+		})
+	}
+}
+
+func TestAssertionSignature(t *testing.T) {
+	rp := newTestRP()
+	a := newAuthenticator(t)
+	stored := register(t, rp, a)
+
+	t.Run("tampered authenticator data", func(t *testing.T) {
+		challenge := newChallenge(t)
+		cred := get(t, a, challenge)
+		cred.Response.AuthenticatorData[36] ^= 0xff // младший байт счётчика подписей
+		if _, err := rp.VerifyAssertion(cred, challenge, stored.PublicKey, stored.SignCount); !errors.Is(err, webauthn.ErrVerification) {
+			t.Fatalf("err = %v, want ErrVerification", err)
+		}
+	})
+	t.Run("other key", func(t *testing.T) {
+		other := newAuthenticator(t)
+		other.CredentialID = a.CredentialID
+		challenge := newChallenge(t)
+		if _, err := rp.VerifyAssertion(get(t, other, challenge), challenge, stored.PublicKey, stored.SignCount); !errors.Is(err, webauthn.ErrVerification) {
+			t.Fatalf("err = %v, want ErrVerification", err)
+		}
+	})
+}
+
+func TestSignCount(t *testing.T) {
+	rp := newTestRP()
+	a := newAuthenticator(t)
+	stored := register(t, rp, a)
+
+	tests := []struct {
+		name   string
+		before uint32 // счётчик аутентификатора перед Get, который его увеличит
+		stored uint32
+	}{
+		{"rollback", 4, 10},
+		{"repeated", 9, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a.SignCount = tt.before
+			challenge := newChallenge(t)
+			if _, err := rp.VerifyAssertion(get(t, a, challenge), challenge, stored.PublicKey, tt.stored); !errors.Is(err, webauthn.ErrSignCountRollback) {
+				t.Fatalf("err = %v, want ErrSignCountRollback", err)
+			}
+		})
+	}
+
+	t.Run("counterless authenticator", func(t *testing.T) {
+		// Синхронизируемые passkey всегда присылают 0: Get переполнит счётчик в 0
+		a.SignCount = ^uint32(0)
+		challenge := newChallenge(t)
+		if count, err := rp.VerifyAssertion(get(t, a, challenge), challenge, stored.PublicKey, 0); err != nil || count != 0 {
+			t.Fatalf("count = %d, err = %v; want 0, nil", count, err)
+		}
+	})
+}
+
+func TestRegistrationRejectsMalformedAttestation(t *testing.T) {
+	rp := newTestRP()
+	a := newAuthenticator(t)
+	challenge := newChallenge(t)
+
+	t.Run("credential id mismatch", func(t *testing.T) {
+		cred := a.Create(challenge)
+		cred.RawID = []byte("another-credential")
+		if _, err := rp.VerifyRegistration(cred, challenge); !errors.Is(err, webauthn.ErrVerification) {
+			t.Fatalf("err = %v, want ErrVerification", err)
+		}
+	})
+	t.Run("attestation format", func(t *testing.T) {
+		cred := a.Create(challenge)
+		cred.Response.AttestationObject = webauthntest.EncodeCBOR(webauthntest.Map{
+			{"fmt", "packed"},
+			{"attStmt", webauthntest.Map{{"alg", int64(-7)}}},
+			{"authData", a.AuthData(true)},
+		})
+		if _, err := rp.VerifyRegistration(cred, challenge); !errors.Is(err, webauthn.ErrVerification) {
+			t.Fatalf("err = %v, want ErrVerification", err)
+		}
+	})
+	t.Run("truncated attestation object", func(t *testing.T) {
+		full := a.Create(challenge).Response.AttestationObject
+		for n := 0; n < len(full); n++ {
+			cred := a.Create(challenge)
+			cred.Response.AttestationObject = full[:n]
+			if _, err := rp.VerifyRegistration(cred, challenge); err == nil {
+				t.Fatalf("prefix of %d bytes accepted", n)
+			}
+		}
+	})
+}
+
+func TestParseAuthenticatorDataTruncated(t *testing.T) {
+	a := newAuthenticator(t)
+	full := a.AuthData(true)
+	if _, err := webauthn.ParseAuthenticatorData(full); err != nil {
+		t.Fatalf("full authenticator data: %v", err)
+	}
+	for n := 0; n < len(full); n++ {
+		if _, err := webauthn.ParseAuthenticatorData(full[:n]); err == nil {
+			t.Fatalf("prefix of %d bytes accepted", n)
+		}
+	}
+	if _, err := webauthn.ParseAuthenticatorData(append(full, 0)); err == nil {
+		t.Fatal("trailing byte accepted")
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	a := newAuthenticator(t)
+	key, err := webauthn.ParsePublicKey(a.COSEKey())
+	if err != nil {
+		t.Fatalf("ParsePublicKey: %v", err)
+	}
+	if key.Algorithm != webauthn.AlgES256 {
+		t.Fatalf("algorithm = %d, want %d", key.Algorithm, webauthn.AlgES256)
+	}
+
+	x := a.Key.X.FillBytes(make([]byte, 32))
+	ec2 := func(x, y []byte) []byte {
+		return webauthntest.EncodeCBOR(webauthntest.Map{
+			{int64(1), int64(2)},
+			{int64(3), webauthn.AlgES256},
+			{int64(-1), int64(1)},
+			{int64(-2), x},
+			{int64(-3), y},
+		})
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"point not on curve", ec2(x, x)},
+		{"short coordinate", ec2(x[:31], x)},
+		{"unsupported algorithm", webauthntest.EncodeCBOR(webauthntest.Map{
+			{int64(1), int64(2)},
+			{int64(3), int64(-35)},
+			{int64(-1), int64(2)},
+		})},
+		{"not a map", webauthntest.EncodeCBOR([]any{int64(1)})},
+		{"trailing data", append(a.COSEKey(), 0x00)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := webauthn.ParsePublicKey(tt.data); err == nil {
+				t.Fatal("want error")
+			}
+		})
+	}
+}
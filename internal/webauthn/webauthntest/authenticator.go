@@ -0,0 +1,176 @@
+// Package webauthntest — программный аутентификатор для тестов церемоний WebAuthn.
+// Собирает ответы navigator.credentials.create() и get() так же, как браузер с passkey на P-256
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+
+	"jwt_auth_project/internal/webauthn"
+)
+
+// Authenticator — passkey с ключом P-256. Поля можно менять между церемониями,
+// чтобы получить некорректный ответ: чужой RP ID, origin, флаги, откат счётчика
+type Authenticator struct {
+	Key          *ecdsa.PrivateKey
+	CredentialID []byte
+	UserHandle   []byte
+	RPID         string
+	Origin       string
+	Flags        byte
+	SignCount    uint32
+}
+
+// New создаёт аутентификатор для rpID и origin с флагами UP и UV
+func New(rpID, origin string) (*Authenticator, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	credID := make([]byte, 16)
+	if _, err := rand.Read(credID); err != nil {
+		return nil, err
+	}
+	return &Authenticator{
+		Key:          key,
+		CredentialID: credID,
+		RPID:         rpID,
+		Origin:       origin,
+		Flags:        webauthn.FlagUserPresent | webauthn.FlagUserVerified,
+	}, nil
+}
+
+// COSEKey возвращает открытый ключ в формате COSE_Key
+func (a *Authenticator) COSEKey() []byte {
+	return EncodeCBOR(Map{
+		{int64(1), int64(2)},  // kty: EC2
+		{int64(3), int64(-7)}, // alg: ES256
+		{int64(-1), int64(1)}, // crv: P-256
+		{int64(-2), a.Key.X.FillBytes(make([]byte, 32))},
+		{int64(-3), a.Key.Y.FillBytes(make([]byte, 32))},
+	})
+}
+
+// AuthData собирает authenticatorData. С attested в него входят id учётных данных и ключ
+func (a *Authenticator) AuthData(attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	flags := a.Flags
+	if attested {
+		flags |= webauthn.FlagAttestedData
+	}
+	out := append([]byte(nil), rpIDHash[:]...)
+	out = append(out, flags)
+	out = binary.BigEndian.AppendUint32(out, a.SignCount)
+	if attested {
+		out = append(out, make([]byte, 16)...) // AAGUID
+		out = binary.BigEndian.AppendUint16(out, uint16(len(a.CredentialID)))
+		out = append(out, a.CredentialID...)
+		out = append(out, a.COSEKey()...)
+	}
+	return out
+}
+
+// ClientData собирает clientDataJSON церемонии typ ("webauthn.create" или "webauthn.get")
+func (a *Authenticator) ClientData(typ string, challenge []byte) []byte {
+	data, _ := json.Marshal(map[string]any{
+		"type":      typ,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    a.Origin,
+	})
+	return data
+}
+
+// Create отвечает на navigator.credentials.create() с аттестацией "none"
+func (a *Authenticator) Create(challenge []byte) webauthn.RegistrationCredential {
+	obj := EncodeCBOR(Map{
+		{"fmt", "none"},
+		{"attStmt", Map{}},
+		{"authData", a.AuthData(true)},
+	})
+	return webauthn.RegistrationCredential{
+		ID:    base64.RawURLEncoding.EncodeToString(a.CredentialID),
+		RawID: a.CredentialID,
+		Type:  "public-key",
+		Response: webauthn.AttestationResponse{
+			ClientDataJSON:    a.ClientData("webauthn.create", challenge),
+			AttestationObject: obj,
+		},
+	}
+}
+
+// Get отвечает на navigator.credentials.get(), предварительно увеличив счётчик подписей
+func (a *Authenticator) Get(challenge []byte) (webauthn.AssertionCredential, error) {
+	a.SignCount++
+	authData := a.AuthData(false)
+	clientData := a.ClientData("webauthn.get", challenge)
+	clientHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.Key, digest[:])
+	if err != nil {
+		return webauthn.AssertionCredential{}, err
+	}
+	return webauthn.AssertionCredential{
+		ID:    base64.RawURLEncoding.EncodeToString(a.CredentialID),
+		RawID: a.CredentialID,
+		Type:  "public-key",
+		Response: webauthn.AssertionResponse{
+			ClientDataJSON:    clientData,
+			AuthenticatorData: authData,
+			Signature:         sig,
+			UserHandle:        a.UserHandle,
+		},
+	}, nil
+}
+
+// Map — словарь CBOR с заданным порядком ключей
+type Map []struct {
+	Key, Value any
+}
+
+// EncodeCBOR кодирует int64, []byte, string, []any и Map. Другие типы — ошибка в тесте, поэтому паника
+func EncodeCBOR(v any) []byte {
+	switch v := v.(type) {
+	case int64:
+		if v >= 0 {
+			return header(0, uint64(v))
+		}
+		return header(1, uint64(-1-v))
+	case []byte:
+		return append(header(2, uint64(len(v))), v...)
+	case string:
+		return append(header(3, uint64(len(v))), v...)
+	case []any:
+		out := header(4, uint64(len(v)))
+		for _, item := range v {
+			out = append(out, EncodeCBOR(item)...)
+		}
+		return out
+	case Map:
+		out := header(5, uint64(len(v)))
+		for _, kv := range v {
+			out = append(out, EncodeCBOR(kv.Key)...)
+			out = append(out, EncodeCBOR(kv.Value)...)
+		}
+		return out
+	}
+	panic("webauthntest: unsupported CBOR type")
+}
+
+func header(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(arg))
+	case arg <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(arg))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, arg)
+}